@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"main/pkg/downloader"
+)
+
+// 艺术家页面的发行类型
+const (
+	releaseAlbum       = "albums"
+	releaseSingle      = "singles"
+	releaseEP          = "eps"
+	releaseCompilation = "compilations"
+	releaseLive        = "live"
+	releaseMV          = "music-videos"
+)
+
+var releaseKinds = []string{releaseAlbum, releaseSingle, releaseEP, releaseCompilation, releaseLive, releaseMV}
+
+// 艺术家下载过滤条件（命令行 --include 等），任一条件生效时自动选择，不再交互
+var (
+	artistInclude          []string
+	artistExcludeAppearsOn bool
+	artistReleasedAfter    string
+	artistReleasedBefore   string
+	artistDedupeEditions   bool
+
+	releasedAfter  time.Time
+	releasedBefore time.Time
+)
+
+var (
+	liveNamePattern  = regexp.MustCompile(`(?i)[(\[]\s*live\b|\blive (at|from|in|on)\b| - live\b`)
+	editionBrackets  = regexp.MustCompile(`(?i)\s*[(\[][^)\]]*\b(edition|remaster(ed)?|deluxe|expanded|anniversary|bonus)\b[^)\]]*[)\]]`)
+	editionSuffix    = regexp.MustCompile(`(?i)\s+-\s+(single|ep|.*\b(edition|remaster(ed)?|deluxe|expanded|anniversary|bonus)\b.*)$`)
+	whitespaceRepeat = regexp.MustCompile(`\s+`)
+)
+
+// parseArtistFilter 校验并解析过滤参数（在 PersistentPreRunE 中调用）
+func parseArtistFilter() error {
+	var include []string
+	for _, k := range artistInclude {
+		k = strings.ToLower(strings.TrimSpace(k))
+		switch k {
+		case "":
+			continue
+		case "album":
+			k = releaseAlbum
+		case "single":
+			k = releaseSingle
+		case "ep":
+			k = releaseEP
+		case "compilation":
+			k = releaseCompilation
+		case "mv", "mvs", "music-video":
+			k = releaseMV
+		}
+		if !contains(releaseKinds, k) {
+			return fmt.Errorf("invalid --include value %q, available: %s", k, strings.Join(releaseKinds, ","))
+		}
+		include = append(include, k)
+	}
+	artistInclude = include
+
+	var err error
+	releasedAfter, releasedBefore = time.Time{}, time.Time{}
+	if artistReleasedAfter != "" {
+		if releasedAfter, err = parseReleaseDate(artistReleasedAfter); err != nil {
+			return fmt.Errorf("invalid --released-after: %w", err)
+		}
+	}
+	if artistReleasedBefore != "" {
+		if releasedBefore, err = parseReleaseDate(artistReleasedBefore); err != nil {
+			return fmt.Errorf("invalid --released-before: %w", err)
+		}
+	}
+	return nil
+}
+
+func artistFilterActive() bool {
+	return len(artistInclude) > 0 || artistExcludeAppearsOn || !releasedAfter.IsZero() || !releasedBefore.IsZero() || artistDedupeEditions
+}
+
+// parseReleaseDate 支持 2006-01-02 / 2006-01 / 2006，Apple 部分老专辑只有年份
+func parseReleaseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// releaseKind 按 IsCompilation / 名称 / IsSingle 判断发行类型
+func releaseKind(it downloader.ArtistItem, relationship string) string {
+	if relationship == "music-videos" {
+		return releaseMV
+	}
+	switch {
+	case it.IsCompilation:
+		return releaseCompilation
+	case liveNamePattern.MatchString(it.Name):
+		return releaseLive
+	case it.IsSingle || strings.HasSuffix(it.Name, " - Single"):
+		return releaseSingle
+	case strings.HasSuffix(it.Name, " - EP"):
+		return releaseEP
+	default:
+		return releaseAlbum
+	}
+}
+
+// inReleaseWindow 发行日期在 --released-after / --released-before 范围内；未设置时总为 true
+func inReleaseWindow(releaseDate string) bool {
+	if releasedAfter.IsZero() && releasedBefore.IsZero() {
+		return true
+	}
+	date, err := parseReleaseDate(releaseDate)
+	if err != nil {
+		return false
+	}
+	if !releasedAfter.IsZero() && date.Before(releasedAfter) {
+		return false
+	}
+	return releasedBefore.IsZero() || !date.After(releasedBefore)
+}
+
+// editionKey 去掉版本/再版等修饰，得到用于合并同一作品不同版本的名称
+func editionKey(name string) string {
+	name = editionBrackets.ReplaceAllString(name, "")
+	name = editionSuffix.ReplaceAllString(name, "")
+	name = whitespaceRepeat.ReplaceAllString(strings.TrimSpace(name), " ")
+	return strings.ToLower(name)
+}
+
+// filterArtistItems 按 --include / --exclude-appears-on / 发行日期 / 版本去重过滤艺术家条目
+func filterArtistItems(items []downloader.ArtistItem, relationship string, artistName string) []downloader.ArtistItem {
+	// 歌单没有发行类型与发行日期，不过滤
+	if relationship == "playlists" {
+		return items
+	}
+	var out []downloader.ArtistItem
+	for _, it := range items {
+		if len(artistInclude) > 0 && !contains(artistInclude, releaseKind(it, relationship)) {
+			continue
+		}
+		if artistExcludeAppearsOn && artistName != "" &&
+			!strings.Contains(strings.ToLower(it.ArtistName), strings.ToLower(artistName)) {
+			continue
+		}
+		if !inReleaseWindow(it.ReleaseDate) {
+			continue
+		}
+		out = append(out, it)
+	}
+	if !artistDedupeEditions || relationship != "albums" {
+		return out
+	}
+
+	// 同名不同版本（Deluxe、Remastered 等）只保留曲目最多的一个，曲目数相同时保留较新的
+	best := make(map[string]int)
+	for i, it := range out {
+		key := releaseKind(it, relationship) + "|" + editionKey(it.Name)
+		j, ok := best[key]
+		if !ok || it.TrackCount > out[j].TrackCount ||
+			(it.TrackCount == out[j].TrackCount && it.ReleaseDate > out[j].ReleaseDate) {
+			best[key] = i
+		}
+	}
+	var deduped []downloader.ArtistItem
+	for i, it := range out {
+		if best[releaseKind(it, relationship)+"|"+editionKey(it.Name)] == i {
+			deduped = append(deduped, it)
+		}
+	}
+	return deduped
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"main/pkg/downloader"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// batchItem 记录批量模式中单个 URL 的处理结果
+type batchItem struct {
+	URL     string
+	Kind    string
+	ID      string
+	Status  string
+	Success int
+	Total   int
+	Errors  int
+}
+
+func init() {
+	var from string
+	var retries int
+	var retryFailed bool
+	batchCmd := &cobra.Command{
+		Use:   "batch --from <file|->",
+		Short: "从文件或标准输入批量下载 URL",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from == "" && !retryFailed {
+				return fmt.Errorf("--from or --retry-failed is required")
+			}
+			var urls []string
+			if from != "" {
+				list, err := readBatchUrls(from)
+				if err != nil {
+					return fmt.Errorf("failed to read urls: %w", err)
+				}
+				urls = append(urls, list...)
+			}
+			if retryFailed {
+				// 从下载数据库中找出仍有失败曲目的专辑/歌单/电台
+				list, err := failedEntityUrls()
+				if err != nil {
+					return fmt.Errorf("failed to read download database: %w", err)
+				}
+				fmt.Printf("Found %d item(s) with failed tracks in the download database.\n", len(list))
+				urls = append(urls, list...)
+			}
+			if len(urls) == 0 {
+				fmt.Println("No URLs to process.")
+				return nil
+			}
+			// 批量模式不可交互：艺术家链接直接选择全部
+			artist_select = true
+			if dl_select {
+				fmt.Println("--select is ignored in batch mode.")
+				dl_select = false
+			}
+			if dryRunFormat != "" {
+				retries = 0
+			}
+			d := newDownloader()
+			items := runBatch(cmd.Context(), d, urls, retries, retryFailed)
+			printBatchTable(items)
+			printIssuesSummary(d)
+			st := d.Stats()
+			fmt.Printf("=======  [✔ ] Completed: %d/%d  |  [⚠ ] Warnings: %d  |  [✖ ] Errors: %d  =======\n", st.Success, st.Total, st.Unavailable, st.Error)
+			return nil
+		},
+	}
+	batchCmd.Flags().StringVar(&from, "from", "", "File with one URL per line, or - for stdin")
+	batchCmd.Flags().IntVar(&retries, "retry", 1, "Retry rounds for failed URLs")
+	batchCmd.Flags().BoolVar(&retryFailed, "retry-failed", false, "Re-queue only the failed tracks recorded in the download database")
+	rootCmd.AddCommand(batchCmd)
+}
+
+// readBatchUrls 读取 URL 列表，忽略空行与 # 注释
+func readBatchUrls(from string) ([]string, error) {
+	var r io.Reader
+	if from == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(from)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// runBatch 按顺序处理 URL，按实体 ID 去重，失败项由同一个下载器记录并重试；
+// retryFirst 为 true 时首轮即只处理失败曲目；ctx 取消后剩余 URL 记为 interrupted
+func runBatch(ctx context.Context, d *downloader.Downloader, urls []string, retries int, retryFirst bool) []*batchItem {
+	d.ClearIssues()
+	seen := make(map[string]struct{})
+	var items []*batchItem
+	for _, u := range urls {
+		item := &batchItem{URL: u}
+		items = append(items, item)
+		if ctx.Err() != nil {
+			item.Status = "interrupted"
+			continue
+		}
+		kind, _, id := downloader.ParseURL(u)
+		if kind == "" {
+			item.Status = "invalid"
+			d.AddError(fmt.Sprintf("Invalid URL: %s", u))
+			continue
+		}
+		item.Kind, item.ID = kind, id
+		key := kind + ":" + id
+		// --song 时同一专辑中 ?i= 不同的链接是不同的歌曲
+		if kind == "album" && dl_song {
+			if parsed, err := url.Parse(u); err == nil && parsed.Query().Get("i") != "" {
+				key += "?i=" + parsed.Query().Get("i")
+			}
+		}
+		if _, ok := seen[key]; ok {
+			item.Status = "duplicate"
+			continue
+		}
+		seen[key] = struct{}{}
+		runBatchItem(ctx, d, item, downloader.RipOptions{Retry: retryFirst})
+	}
+	for round := 0; round < retries && ctx.Err() == nil; round++ {
+		var failed []*batchItem
+		for _, item := range items {
+			if item.Status == "failed" {
+				failed = append(failed, item)
+			}
+		}
+		if len(failed) == 0 {
+			break
+		}
+		fmt.Printf("Retrying %d failed URL(s), round %d/%d\n", len(failed), round+1, retries)
+		for _, item := range failed {
+			if ctx.Err() != nil {
+				break
+			}
+			runBatchItem(ctx, d, item, downloader.RipOptions{Retry: true})
+		}
+	}
+	return items
+}
+
+// runBatchItem 处理单个 URL，按下载结果判断状态
+func runBatchItem(ctx context.Context, d *downloader.Downloader, item *batchItem, opts downloader.RipOptions) {
+	res, err := d.RipURL(ctx, item.URL, opts)
+	if dryRunFormat != "" {
+		printPlan(res)
+	}
+	// 重试时已完成的曲目同样计入，结果即该实体的最新状态
+	item.Success = res.Stats.Success
+	item.Total = res.Stats.Total
+	item.Errors = res.Stats.Error
+	if ctx.Err() != nil {
+		item.Status = "interrupted"
+	} else if err != nil || res.Failed() {
+		item.Status = "failed"
+	} else if res.Skipped != "" {
+		item.Status = "skipped"
+	} else {
+		item.Status = "ok"
+	}
+}
+
+func printBatchTable(items []*batchItem) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Type", "ID", "Completed", "Errors", "Status"})
+	table.SetRowLine(false)
+	for i, item := range items {
+		completed := ""
+		if item.Total > 0 {
+			completed = fmt.Sprintf("%d/%d", item.Success, item.Total)
+		}
+		id := item.ID
+		if id == "" {
+			id = item.URL
+		}
+		table.Append([]string{fmt.Sprint(i + 1), item.Kind, id, completed, fmt.Sprint(item.Errors), item.Status})
+	}
+	table.Render()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"main/pkg/downloader"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var chartTypes = []string{"albums", "songs", "playlists", "music-videos"}
+
+func init() {
+	var storefront, kind, genre string
+	var limit, retries int
+	var dated bool
+
+	chartsCmd := &cobra.Command{
+		Use:   "charts",
+		Short: "下载 storefront 的排行榜（专辑、歌曲、歌单或 MV），可按流派筛选",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !contains(chartTypes, kind) {
+				return fmt.Errorf("invalid --type %q, available: %s", kind, strings.Join(chartTypes, ","))
+			}
+			if limit <= 0 {
+				return fmt.Errorf("--limit must be greater than 0")
+			}
+			if storefront == "" {
+				storefront = Config.Storefront
+			}
+			storefront = strings.ToLower(storefront)
+			name, items, err := downloader.New(downloaderOptions()).Charts(cmd.Context(), storefront, kind, genre, limit)
+			if err != nil {
+				return fmt.Errorf("failed to get %s chart: %w", kind, err)
+			}
+			total := len(items)
+			switch kind {
+			case "albums", "music-videos":
+				items = filterArtistItems(items, kind, "")
+			case "songs":
+				var out []downloader.ArtistItem
+				for _, it := range items {
+					if inReleaseWindow(it.ReleaseDate) {
+						out = append(out, it)
+					}
+				}
+				items = out
+			default:
+				if artistFilterActive() {
+					fmt.Println("Release filters are ignored for playlist charts.")
+				}
+			}
+			fmt.Printf("%s (%s): %d of %d item(s) selected\n", name, storefront, len(items), total)
+			printChartTable(items)
+			if len(items) == 0 {
+				return nil
+			}
+
+			// 榜单下载不可交互：按过滤条件直接下载全部条目
+			artist_select = true
+			if dl_select {
+				fmt.Println("--select is ignored in charts mode.")
+				dl_select = false
+			}
+			if dryRunFormat != "" {
+				retries = 0
+			}
+			opts := downloaderOptions()
+			if dated {
+				// 每次快照保存在独立的日期目录中，不按下载数据库跳过之前快照已下载的条目
+				folder := fmt.Sprintf("%s %s", storefront, kind)
+				if genre != "" {
+					folder += " " + genre
+				}
+				opts.OutputFolder = filepath.Join(OutputFolder, "Charts", folder, time.Now().Format("2006-01-02"))
+				opts.DB = nil
+				fmt.Println("Saving chart snapshot to", opts.OutputFolder)
+			} else {
+				sweepPartFiles()
+				opts.DB = openDownloadDB()
+			}
+			d := downloader.New(opts)
+			urls := make([]string, 0, len(items))
+			for _, it := range items {
+				urls = append(urls, it.URL)
+			}
+			batch := runBatch(cmd.Context(), d, urls, retries, false)
+			printBatchTable(batch)
+			printIssuesSummary(d)
+			st := d.Stats()
+			fmt.Printf("=======  [✔ ] Completed: %d/%d  |  [⚠ ] Warnings: %d  |  [✖ ] Errors: %d  =======\n", st.Success, st.Total, st.Unavailable, st.Error)
+			return nil
+		},
+	}
+	chartsCmd.Flags().StringVar(&storefront, "storefront", "", "Chart storefront, e.g. jp (default: storefront from config)")
+	chartsCmd.Flags().StringVar(&kind, "type", "albums", "Chart type: albums, songs, playlists, music-videos")
+	chartsCmd.Flags().StringVar(&genre, "genre", "", "Genre ID to filter the chart, e.g. 34 for Music (default: all genres)")
+	chartsCmd.Flags().IntVar(&limit, "limit", 100, "Number of chart positions to fetch")
+	chartsCmd.Flags().IntVar(&retries, "retry", 1, "Retry rounds for failed items")
+	chartsCmd.Flags().BoolVar(&dated, "dated", false, "Save into Charts/<storefront> <type>[ <genre>]/<YYYY-MM-DD> under the output folder so snapshots don't overwrite each other")
+	rootCmd.AddCommand(chartsCmd)
+}
+
+// printChartTable 按名次列出将要下载的榜单条目
+func printChartTable(items []downloader.ArtistItem) {
+	if len(items) == 0 {
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Name", "Artist", "Release Date", "URL"})
+	table.SetRowLine(false)
+	for i, it := range items {
+		table.Append([]string{fmt.Sprint(i + 1), it.Name, it.ArtistName, it.ReleaseDate, it.URL})
+	}
+	table.Render()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"main/utils/configcheck"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "配置文件管理",
+		// 覆盖 rootCmd 的 PersistentPreRunE：不检查配置、不获取 token
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "检查配置文件、AMD_* 环境变量与 --set 中的未知键、类型错误与非法取值",
+		Args:  cobra.NoArgs,
+		// 检查失败是结果而非用法错误，不打印帮助
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// 启动时 loadConfig 已完成检查
+			for _, issue := range configIssues {
+				fmt.Println(issue)
+			}
+			n := configcheck.Errors(configIssues)
+			fmt.Printf("Checked %s: %d error(s), %d warning(s)\n", configName(), n, len(configIssues)-n)
+			if n > 0 {
+				return fmt.Errorf("config has %d error(s)", n)
+			}
+			return nil
+		},
+	}
+	configCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// offlinePreRun 供不访问 Apple Music 的本地命令覆盖 rootCmd 的 PersistentPreRunE：
+// 只做配置检查，不获取 token，离线也能运行
+func offlinePreRun(cmd *cobra.Command, args []string) error {
+	for _, issue := range configIssues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+	if n := configcheck.Errors(configIssues); n > 0 {
+		return fmt.Errorf("config has %d error(s), fix them or run `amd config validate`", n)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"main/pkg/downloader"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	libraryCmd := &cobra.Command{
+		Use:   "library",
+		Short: "本地曲库索引管理",
+		// 只读写本地文件与下载数据库，不获取 token
+		PersistentPreRunE: offlinePreRun,
+	}
+	reindexCmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "扫描输出目录，按内嵌歌曲 ID/ISRC 重建曲库索引",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Println("Scanning", OutputFolder)
+			indexed, skipped, err := reindexLibrary(OutputFolder)
+			if err != nil {
+				return fmt.Errorf("reindex failed: %w", err)
+			}
+			fmt.Printf("Indexed %d file(s), skipped %d unreadable or without song ID/ISRC tags.\n", indexed, skipped)
+			return nil
+		},
+	}
+	libraryCmd.AddCommand(reindexCmd)
+	rootCmd.AddCommand(libraryCmd)
+
+	var urlsOnly bool
+	libraryListCmd := &cobra.Command{
+		Use:       "library-list [playlists|albums|songs]",
+		Short:     "列出 Apple Music 资料库中的歌单、专辑或歌曲及其下载链接（需要 media-user-token）",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"playlists", "albums", "songs"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := "playlists"
+			if len(args) > 0 {
+				kind = args[0]
+			}
+			items, err := downloader.New(downloaderOptions()).LibraryItems(cmd.Context(), kind)
+			if err != nil {
+				return fmt.Errorf("failed to list library %s: %w", kind, err)
+			}
+			// --urls 每行一个链接，可直接交给 batch --from -
+			if urlsOnly {
+				for _, it := range items {
+					if it.URL != "" {
+						fmt.Println(it.URL)
+					}
+				}
+				return nil
+			}
+			if len(items) == 0 {
+				fmt.Printf("No %s in library.\n", kind)
+				return nil
+			}
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"", "Name", "Artist", "Library ID", "Catalog ID", "URL"})
+			table.SetRowLine(false)
+			for i, it := range items {
+				u := it.URL
+				if u == "" {
+					u = "(not in catalog)"
+				}
+				table.Append([]string{fmt.Sprint(i + 1), it.Name, it.ArtistName, it.ID, it.CatalogID, u})
+			}
+			table.Render()
+			return nil
+		},
+	}
+	libraryListCmd.Flags().BoolVar(&urlsOnly, "urls", false, "Print only the download URLs, one per line")
+	rootCmd.AddCommand(libraryListCmd)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"main/pkg/downloader"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var deleteRemoved bool
+	var archiveDir string
+
+	playlistCmd := &cobra.Command{
+		Use:   "playlist",
+		Short: "歌单管理",
+	}
+
+	syncCmd := &cobra.Command{
+		Use:   "sync <playlist-url>...",
+		Short: "将歌单同步到本地：下载新曲目，按新位置改名并更新标签，归档已移出的曲目",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if openDownloadDB() == nil {
+				return fmt.Errorf("download database is not available")
+			}
+			if dl_select {
+				fmt.Println("--select is ignored in playlist sync.")
+				dl_select = false
+			}
+			d := newDownloader()
+			failed := 0
+			for _, u := range args {
+				if cmd.Context().Err() != nil {
+					break
+				}
+				kind, storefront, id := downloader.ParseURL(u)
+				if kind != "playlist" {
+					return fmt.Errorf("%s: invalid playlist url", u)
+				}
+				res, err := d.SyncPlaylist(cmd.Context(), id, downloader.SyncOptions{
+					Storefront: storefront,
+					Delete:     deleteRemoved,
+					ArchiveDir: archiveDir,
+				})
+				if dryRunFormat != "" {
+					printPlan(res)
+					continue
+				}
+				if err != nil || res.Failed() {
+					failed++
+					if err != nil {
+						fmt.Printf("Failed to sync %s: %v\n", u, err)
+					}
+				}
+				printSyncSummary(res)
+			}
+			printIssuesSummary(d)
+			if failed > 0 {
+				return fmt.Errorf("%d playlist(s) failed to sync, failed tracks will be retried on the next sync", failed)
+			}
+			return nil
+		},
+	}
+	syncCmd.Flags().BoolVar(&deleteRemoved, "delete", false, "Delete tracks removed from the playlist instead of archiving them")
+	syncCmd.Flags().StringVar(&archiveDir, "archive-dir", "", "Folder for removed tracks (default: _archive/<playlist folder> next to the playlist folder)")
+
+	playlistCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(playlistCmd)
+}
+
+// printSyncSummary 按状态统计一次歌单同步的结果
+func printSyncSummary(res *downloader.Result) {
+	if res == nil {
+		return
+	}
+	var downloaded, moved, kept, removed, failed int
+	for _, t := range res.Tracks {
+		switch {
+		case t.Status == downloader.TrackRemoved:
+			removed++
+		case t.Status == downloader.TrackOK && (t.Reason == "moved" || t.Reason == "renumbered"):
+			moved++
+		case t.Status == downloader.TrackOK:
+			downloaded++
+		case t.Status == downloader.TrackSkipped:
+			kept++
+		default:
+			failed++
+		}
+	}
+	fmt.Printf("%s: %d downloaded, %d moved/retagged, %d unchanged, %d removed, %d failed\n", res.Name, downloaded, moved, kept, removed, failed)
+}
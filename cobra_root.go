@@ -9,6 +9,7 @@ import (
 
     "github.com/spf13/cobra"
     "main/utils/ampapi"
+    "main/utils/configcheck"
 )
 
 var (
@@ -17,6 +18,13 @@ var (
         Use:   "amd",
         Short: "Apple Music Downloader CLI",
         PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+            // 配置检查：警告照常运行，有错误时不开始下载
+            for _, issue := range configIssues {
+                fmt.Fprintln(os.Stderr, issue)
+            }
+            if n := configcheck.Errors(configIssues); n > 0 {
+                return fmt.Errorf("config has %d error(s), fix them or run `amd config validate`", n)
+            }
             // 将 CLI 标志应用到运行时配置
             if cmd.Flags().Changed("atmos") {
                 v, _ := cmd.Flags().GetBool("atmos")
@@ -43,6 +51,17 @@ var (
                 debug_mode = v
             }
 
+            if err := parseArtistFilter(); err != nil {
+                return err
+            }
+            // ndjson / 预演 json 模式需在初始化日志前切换 stdout
+            if err := setupDryRun(); err != nil {
+                return err
+            }
+            if err := setupProgressOutput(); err != nil {
+                return err
+            }
+
             // 初始化日志
             var logLevel, logFormat, logFile string
             var noColor bool
@@ -146,6 +165,10 @@ var (
 )
 
 func init() {
+    // 配置文件与配置档在 main 中预先解析，这里注册仅用于帮助信息和参数校验
+    rootCmd.PersistentFlags().String("config", "", "Config file path, or $AMD_CONFIG (default: ./config.yaml, then $XDG_CONFIG_HOME/amd/config.yaml, ~/.config/amd/config.yaml)")
+    rootCmd.PersistentFlags().String("profile", "", "Named profile from the config's profiles section to overlay on the base settings, or $AMD_PROFILE")
+    rootCmd.PersistentFlags().StringArray("set", nil, "Override a config key, e.g. --set cover-format=png (repeatable; wins over AMD_* environment variables)")
     // 持久化标志（全局）
     rootCmd.PersistentFlags().BoolVar(&dl_atmos, "atmos", false, "Enable atmos download mode")
     rootCmd.PersistentFlags().BoolVar(&dl_aac, "aac", false, "Enable adm-aac download mode")
@@ -153,11 +176,14 @@ func init() {
     rootCmd.PersistentFlags().BoolVar(&dl_song, "song", false, "Enable single song download mode")
     rootCmd.PersistentFlags().BoolVar(&artist_select, "all-album", false, "Download all artist albums")
     rootCmd.PersistentFlags().BoolVar(&debug_mode, "debug", false, "Enable debug mode to show audio quality information")
+    rootCmd.PersistentFlags().StringVar(&dryRunFormat, "dry-run", "", "Resolve tracks, variants and target paths without downloading or writing anything: text, json (--dry-run alone means text)")
+    rootCmd.PersistentFlags().Lookup("dry-run").NoOptDefVal = "text"
     // 日志控制
     rootCmd.PersistentFlags().String("log-level", "info", "Log level: debug, info, warn, error")
     rootCmd.PersistentFlags().String("log-format", "text", "Log format: text, json, auto")
     rootCmd.PersistentFlags().String("log-file", "", "Log file path (enable file logging)")
     rootCmd.PersistentFlags().Bool("no-color", false, "Disable color in console output")
+    rootCmd.PersistentFlags().StringVar(&progressFormat, "progress-format", "text", "Progress output: text, ndjson (machine-readable events on stdout, console text on stderr)")
     rootCmd.PersistentFlags().IntVar(&Config.AlacMax, "alac-max", Config.AlacMax, "Specify the max quality for download alac")
     rootCmd.PersistentFlags().IntVar(&Config.AtmosMax, "atmos-max", Config.AtmosMax, "Specify the max quality for download atmos")
     rootCmd.PersistentFlags().StringVar(&Config.AacType, "aac-type", Config.AacType, "Select AAC type, aac aac-binaural aac-downmix aac-lc")
@@ -165,6 +191,12 @@ func init() {
     rootCmd.PersistentFlags().IntVar(&Config.MVMax, "mv-max", Config.MVMax, "Specify the max quality for download MV")
     rootCmd.PersistentFlags().String("codec-priority", strings.Join(Config.CodecPriority, ","), "Specify codec priority, comma separated")
     rootCmd.PersistentFlags().StringVar(&cpuProfilePath, "profile-cpu", "", "生成 CPU Profile（pprof），用于 PGO，例如 default.pgo")
+    // 艺术家发行过滤（任一条件生效时自动选择）
+    rootCmd.PersistentFlags().StringSliceVar(&artistInclude, "include", nil, "Artist release types to download: singles,eps,albums,compilations,live,music-videos")
+    rootCmd.PersistentFlags().BoolVar(&artistExcludeAppearsOn, "exclude-appears-on", false, "Skip artist releases credited to other artists")
+    rootCmd.PersistentFlags().StringVar(&artistReleasedAfter, "released-after", "", "Only artist releases on or after this date (YYYY-MM-DD)")
+    rootCmd.PersistentFlags().StringVar(&artistReleasedBefore, "released-before", "", "Only artist releases on or before this date (YYYY-MM-DD)")
+    rootCmd.PersistentFlags().BoolVar(&artistDedupeEditions, "dedupe-editions", false, "Keep only the edition with the most tracks among deluxe/remastered variants")
 
     // 绑定 aac_type 指针到配置，避免 setDlFlags() 写入空指针
     aac_type = &Config.AacType
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"main/pkg/serve"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var listen string
+	var workers int
+	var authToken string
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "启动 HTTP/JSON API 服务（任务队列）",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRunFormat != "" {
+				return fmt.Errorf("--dry-run is not supported by serve")
+			}
+			if workers < 1 {
+				workers = 1
+			}
+			// 服务模式不可交互
+			artist_select = true
+			dl_select = false
+			ctx := cmd.Context()
+			s := serve.New(ctx, newDownloader(), workers)
+			srv := &http.Server{Addr: listen, Handler: s.Handler(authToken)}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+			fmt.Printf("Listening on http://%s (workers: %d)\n", listen, workers)
+			err := srv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			// 等待运行中的任务清理完毕
+			s.Wait()
+			return nil
+		},
+	}
+	serveCmd.Flags().StringVar(&listen, "listen", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().IntVar(&workers, "workers", 1, "Number of jobs processed concurrently (tracks still share download-concurrency)")
+	serveCmd.Flags().StringVar(&authToken, "auth-token", "", "Require 'Authorization: Bearer <token>' on every request")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"main/utils/atomicfile"
+	"main/utils/verify"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var remove bool
+	verifyCmd := &cobra.Command{
+		Use:   "verify [folder]",
+		Short: "检查目录中已下载音频的完整性（默认输出目录）",
+		Args:  cobra.MaximumNArgs(1),
+		// 校验失败是结果而非用法错误，不打印帮助
+		SilenceUsage:      true,
+		PersistentPreRunE: offlinePreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := OutputFolder
+			if len(args) == 1 {
+				root = args[0]
+			}
+			ctx := cmd.Context()
+			fmt.Println("Verifying", root)
+			var checked int
+			var bad [][]string
+			err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".m4a") || atomicfile.IsPart(d.Name()) {
+					return nil
+				}
+				checked++
+				if _, err := verify.File(path, verify.Expect{}); err != nil {
+					status := "broken"
+					if remove {
+						if rmErr := os.Remove(path); rmErr == nil {
+							status = "removed"
+							if db := openDownloadDB(); db != nil {
+								_ = db.RemoveLibraryItem(path)
+							}
+						}
+					}
+					bad = append(bad, []string{fmt.Sprint(len(bad) + 1), path, err.Error(), status})
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("verify failed: %w", err)
+			}
+			if len(bad) > 0 {
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"", "File", "Problem", "Status"})
+				table.SetRowLine(false)
+				table.AppendBulk(bad)
+				table.Render()
+			}
+			fmt.Printf("Checked %d file(s), %d problem(s).\n", checked, len(bad))
+			if len(bad) > 0 {
+				return fmt.Errorf("%d file(s) failed verification", len(bad))
+			}
+			return nil
+		},
+	}
+	verifyCmd.Flags().BoolVar(&remove, "remove", false, "Delete files that fail verification so the next run downloads them again")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"main/pkg/downloader"
+	"main/utils/store"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "关注艺术家并下载新发行",
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <artist-url>...",
+		Short: "关注艺术家（当前已有的专辑记为已见过）",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if openDownloadDB() == nil {
+				return fmt.Errorf("download database is not available")
+			}
+			d := downloader.New(downloaderOptions())
+			for _, u := range args {
+				if err := watchAdd(cmd.Context(), d, u); err != nil {
+					return fmt.Errorf("%s: %w", u, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:               "remove <artist-url|artist-id>...",
+		Short:             "取消关注艺术家",
+		Args:              cobra.MinimumNArgs(1),
+		PersistentPreRunE: offlinePreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if openDownloadDB() == nil {
+				return fmt.Errorf("download database is not available")
+			}
+			for _, a := range args {
+				id := a
+				if strings.Contains(a, "://") {
+					kind, _, artistId := downloader.ParseURL(a)
+					id = ""
+					if kind == "artist" {
+						id = artistId
+					}
+				}
+				if id == "" {
+					return fmt.Errorf("%s: not an artist URL or ID", a)
+				}
+				if err := downloadDB.RemoveWatch(id); err != nil {
+					return err
+				}
+				fmt.Println("Removed", id)
+			}
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:               "list",
+		Short:             "列出关注的艺术家",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: offlinePreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if openDownloadDB() == nil {
+				return fmt.Errorf("download database is not available")
+			}
+			watches, err := downloadDB.Watches()
+			if err != nil {
+				return err
+			}
+			if len(watches) == 0 {
+				fmt.Println("No artists watched.")
+				return nil
+			}
+			sort.Slice(watches, func(i, j int) bool { return strings.ToLower(watches[i].Name) < strings.ToLower(watches[j].Name) })
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"", "Artist", "ID", "Storefront", "Releases", "Last Sync"})
+			table.SetRowLine(false)
+			for i, w := range watches {
+				lastSync := "never"
+				if !w.LastSync.IsZero() {
+					lastSync = w.LastSync.Local().Format("2006-01-02 15:04")
+				}
+				table.Append([]string{fmt.Sprint(i + 1), w.Name, w.ArtistID, w.Storefront, fmt.Sprint(len(w.Seen)), lastSync})
+			}
+			table.Render()
+			return nil
+		},
+	}
+
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "下载关注艺术家的新发行（非交互，适合 cron）",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if openDownloadDB() == nil {
+				return fmt.Errorf("download database is not available")
+			}
+			watches, err := downloadDB.Watches()
+			if err != nil {
+				return err
+			}
+			if dl_select {
+				fmt.Println("--select is ignored in watch sync.")
+				dl_select = false
+			}
+			d := newDownloader()
+			var items []*batchItem
+			for i := range watches {
+				if cmd.Context().Err() != nil {
+					break
+				}
+				synced, err := watchSync(cmd.Context(), d, &watches[i])
+				if err != nil {
+					fmt.Printf("Failed to sync %s: %v\n", watches[i].Name, err)
+					d.AddError(fmt.Sprintf("Watch sync failed for %s: %v", watches[i].Name, err))
+				}
+				items = append(items, synced...)
+			}
+			if len(items) == 0 {
+				fmt.Println("No new releases.")
+			} else {
+				printBatchTable(items)
+			}
+			printIssuesSummary(d)
+			failed := 0
+			for _, item := range items {
+				if item.Status != "ok" {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d new release(s) failed, they will be retried on the next sync", failed)
+			}
+			return nil
+		},
+	}
+
+	watchCmd.AddCommand(addCmd, removeCmd, listCmd, syncCmd)
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchAdd 记录艺术家及其当前全部专辑，之后的 sync 只下载新出现的专辑
+func watchAdd(ctx context.Context, d *downloader.Downloader, artistUrl string) error {
+	kind, storefront, artistId := downloader.ParseURL(artistUrl)
+	if kind != "artist" {
+		return fmt.Errorf("invalid artist url")
+	}
+	existing, err := downloadDB.GetWatch(artistId)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		fmt.Printf("Already watching %s (%s)\n", existing.Name, existing.ArtistID)
+		return nil
+	}
+	name, err := d.ArtistName(ctx, storefront, artistId)
+	if err != nil {
+		return fmt.Errorf("failed to get artist name: %w", err)
+	}
+	albums, err := d.ArtistItems(ctx, storefront, artistId, "albums")
+	if err != nil {
+		return fmt.Errorf("failed to get artist albums: %w", err)
+	}
+	w := store.WatchedArtist{
+		ArtistID:   artistId,
+		Storefront: storefront,
+		Name:       name,
+		URL:        artistUrl,
+		Seen:       make(map[string]bool, len(albums)),
+		AddedAt:    time.Now(),
+	}
+	for _, a := range albums {
+		w.Seen[a.ID] = true
+	}
+	if err := downloadDB.PutWatch(w); err != nil {
+		return err
+	}
+	fmt.Printf("Watching %s (%s), %d existing release(s) marked as seen\n", name, artistId, len(albums))
+	return nil
+}
+
+// watchSync 下载某个艺术家新出现的专辑，成功的专辑记为已见过，失败的留待下次重试
+func watchSync(ctx context.Context, d *downloader.Downloader, w *store.WatchedArtist) ([]*batchItem, error) {
+	albums, err := d.ArtistItems(ctx, w.Storefront, w.ArtistID, "albums")
+	if err != nil {
+		return nil, err
+	}
+	if w.Seen == nil {
+		w.Seen = make(map[string]bool)
+	}
+	var fresh []downloader.ArtistItem
+	for _, a := range albums {
+		if !w.Seen[a.ID] {
+			fresh = append(fresh, a)
+		}
+	}
+	fmt.Printf("%s: %d new release(s)\n", w.Name, len(fresh))
+
+	// 与下载艺术家链接时一致，填充艺术家目录名
+	opts := downloader.RipOptions{ArtistName: w.Name, ArtistID: w.ArtistID}
+	var items []*batchItem
+	for _, a := range fresh {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("%s - %s (%s)\n", w.Name, a.Name, a.ReleaseDate)
+		item := &batchItem{URL: a.URL, Kind: "album", ID: a.ID}
+		runBatchItem(ctx, d, item, opts)
+		items = append(items, item)
+		if item.Status == "ok" {
+			w.Seen[a.ID] = true
+		}
+	}
+	// 预演不更新关注记录
+	if dryRunFormat != "" {
+		return items, nil
+	}
+	w.LastSync = time.Now()
+	return items, downloadDB.PutWatch(*w)
+}
@@ -7,8 +7,10 @@ import (
 
 	survey "github.com/AlecAivazis/survey/v2"
 	"github.com/manifoldco/promptui"
+	"main/utils/atomicfile"
+	"main/utils/yamledit"
+
 	"github.com/spf13/cobra"
-	yaml "gopkg.in/yaml.v2"
 )
 
 func init() {
@@ -16,8 +18,9 @@ func init() {
 		Use:   "wizard",
 		Short: "交互式向导（PromptUI）",
 		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
 			token := cliToken
-			for {
+			for ctx.Err() == nil {
 				actions := []string{"rip 单 URL", "search 搜索下载", "设置", "帮助", "退出"}
 				var action string
 				if err := survey.AskOne(&survey.Select{Message: "选择操作", Options: actions}, &action); err != nil {
@@ -34,23 +37,7 @@ func init() {
 					}
 					url = strings.TrimSpace(url)
 
-					clearIssues()
-					handleSingleURL(url, token)
-					printIssuesSummary()
-
-					for hasAnyFail() {
-						if !askYesNo("是否重试失败项? (y/N) ") {
-							break
-						}
-						clearIssues()
-						retryOnly = true
-						handleSingleURL(url, token)
-						retryOnly = false
-						printIssuesSummary()
-					}
-					clearFail()
-					clearEntityFail()
-					fmt.Printf("=======  [✔ ] Completed: %d/%d  |  [⚠ ] Warnings: %d  |  [✘ ] Errors: %d  =======\n", counter.Success, counter.Total, counter.Unavailable+counter.NotSong, counter.Error)
+					ripInteractive(ctx, url)
 
 				case "search 搜索下载":
 					types := []string{"album", "song", "artist"}
@@ -66,7 +53,7 @@ func init() {
 						continue
 					}
 
-					selectedUrl, err := handleSearch(st, strings.Fields(kwStr), token)
+					selectedUrl, err := handleSearch(ctx, st, strings.Fields(kwStr), token)
 					if err != nil {
 						fmt.Println("Search error:", err)
 						continue
@@ -76,23 +63,7 @@ func init() {
 						continue
 					}
 
-					clearIssues()
-					handleSingleURL(selectedUrl, token)
-					printIssuesSummary()
-
-					for hasAnyFail() {
-						if !askYesNo("是否重试失败项? (y/N) ") {
-							break
-						}
-						clearIssues()
-						retryOnly = true
-						handleSingleURL(selectedUrl, token)
-						retryOnly = false
-						printIssuesSummary()
-					}
-					clearFail()
-					clearEntityFail()
-					fmt.Printf("=======  [✔ ] Completed: %d/%d  |  [⚠ ] Warnings: %d  |  [✘ ] Errors: %d  =======\n", counter.Success, counter.Total, counter.Unavailable+counter.NotSong, counter.Error)
+					ripInteractive(ctx, selectedUrl)
 
 				case "设置":
 					runSettingsMenu()
@@ -116,6 +87,7 @@ func runSettingsMenu() {
 			"并发下载线程",
 			"编码优先级 codec-priority",
 			"输出目录 output-folder",
+			"其他配置项",
 			"返回",
 		}
 		var choose string
@@ -145,15 +117,17 @@ func runSettingsMenu() {
 			if err == nil && strings.TrimSpace(outStr) != "" {
 				OutputFolder = strings.TrimSpace(outStr)
 				fmt.Println("输出目录(运行时)已更新为:", OutputFolder)
-				if askYesNo("是否写入 config.yaml 的 output-folder? (y/N) ") {
+				if askYesNo("是否写入配置文件的 output-folder? (y/N) ") {
 					if err := writeConfigKey("output-folder", OutputFolder); err != nil {
 						fmt.Println("写入配置失败:", err)
 					} else {
-						fmt.Println("config.yaml 已更新 output-folder")
+						fmt.Println(configName(), "已更新 output-folder")
 					}
 				}
 			}
 		case items[3]:
+			runConfigGroupsMenu()
+		case items[4]:
 			return
 		}
 	}
@@ -207,7 +181,7 @@ func runCodecPriorityMenu() {
 				fmt.Println("写入配置失败:", err)
 			} else {
 				Config.CodecPriority = src
-				fmt.Println("config.yaml 已更新 codec-priority")
+				fmt.Println(configName(), "已更新 codec-priority")
 			}
 		case items[4]:
 			return
@@ -224,28 +198,29 @@ func printWizardHelp() {
 	fmt.Println("  - 并发下载线程: 设置下载并发数")
 	fmt.Println("  - 编码优先级: 查看/设置运行时优先级，写入配置")
 	fmt.Println("  - 输出目录: 设置运行时输出目录，支持写入配置")
+	fmt.Println("  - 其他配置项: 按分组修改歌词、封面、格式、转换、超时等全部配置，支持写入配置")
 	fmt.Println("- 退出: 结束向导")
 }
 
-// 写入配置文件指定键
+// 写入配置文件指定键：只改写该键所在的行，保留注释与键的顺序
 func writeConfigKey(key string, value interface{}) error {
-	data, err := os.ReadFile("config.yaml")
+	if configPath == "" {
+		return errNoConfig
+	}
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return err
 	}
-
-	var m map[string]interface{}
-	err = yaml.Unmarshal(data, &m) // 先赋值
+	out, err := yamledit.Set(data, key, value)
 	if err != nil {
 		return err
 	}
-
-	m[key] = value
-
-	out, err := yaml.Marshal(m) // 此时外层函数的 err 已经被 Unmarshal 重新赋值，这里再次赋值是清晰的
+	if configProfile != "" {
+		fmt.Printf("注意：写入的是基础配置，当前配置档 %s 中的同名键仍会覆盖它\n", configProfile)
+	}
+	st, err := os.Stat(configPath)
 	if err != nil {
 		return err
 	}
-
-	return os.WriteFile("config.yaml", out, 0644)
+	return atomicfile.WriteFile(configPath, out, st.Mode().Perm())
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"main/utils/configcheck"
+
+	survey "github.com/AlecAivazis/survey/v2"
+	"github.com/manifoldco/promptui"
+)
+
+// 向导设置菜单中的配置分组；ConfigSet 中未列出的键归入“其他”
+var configGroups = []struct {
+	Name string
+	Keys []string
+}{
+	{"歌词", []string{"embed-lrc", "save-lrc-file", "lrc-type", "lrc-format", "language"}},
+	{"封面", []string{"embed-cover", "cover-size", "cover-format", "save-artist-cover", "save-animated-artwork", "emby-animated-artwork", "dl-albumcover-for-playlist"}},
+	{"目录与文件名", []string{"album-folder-format", "playlist-folder-format", "artist-folder-format", "song-file-format", "mv-file-format", "disc-folder-format", "explicit-choice", "clean-choice", "apple-master-choice", "limit-max", "use-songinfo-for-playlist", "filename-profile", "filename-normalization", "max-path-length", "playlist-files", "playlist-files-for-albums", "playlist-layout", "playlist-links"}},
+	{"音质与格式", []string{"aac-type", "alac-max", "atmos-max", "mv-audio-type", "mv-max", "get-m3u8-mode", "get-m3u8-from-device"}},
+	{"下载后转换", []string{"convert-after-download", "convert-format", "convert-keep-original", "convert-skip-if-source-matches", "convert-warn-lossy-to-lossless", "ffmpeg-path", "convert-extra-args"}},
+	{"网络与超时", []string{"request-timeout-sec", "download-timeout-sec", "mv-segment-concurrency", "tagging-concurrency", "max-memory-limit", "decrypt-m3u8-port", "get-m3u8-port"}},
+	{"曲库", []string{"library-skip-existing", "library-match-codec", "library-match-isrc"}},
+	{"账户与地区", []string{"storefront", "media-user-token", "authorization-token"}},
+}
+
+// 启动时初始化（HTTP 客户端、信号量），修改后需重新运行才生效
+var restartConfigKeys = map[string]bool{"request-timeout-sec": true, "tagging-concurrency": true}
+
+// 有专门菜单的键不在分组中重复出现
+var dedicatedConfigKeys = map[string]bool{"codec-priority": true, "output-folder": true, "download-concurrency": true}
+
+// configGroupKeys 返回分组的键；“其他”包含所有未分组的 ConfigSet 键
+func configGroupKeys(name string) []string {
+	for _, g := range configGroups {
+		if g.Name == name {
+			return g.Keys
+		}
+	}
+	grouped := make(map[string]bool)
+	for _, g := range configGroups {
+		for _, k := range g.Keys {
+			grouped[k] = true
+		}
+	}
+	var rest []string
+	for key := range configcheck.Fields() {
+		if !grouped[key] && !dedicatedConfigKeys[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return rest
+}
+
+// formatConfigValue 菜单中显示的当前值，token 只显示末尾几位
+func formatConfigValue(key string, v reflect.Value) string {
+	var s string
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		s = strings.Join(parts, ",")
+	} else {
+		s = fmt.Sprint(v.Interface())
+	}
+	if strings.HasSuffix(key, "-token") && len(s) > 8 {
+		s = "****" + s[len(s)-4:]
+	}
+	if s == "" {
+		s = `""`
+	}
+	return s
+}
+
+// runConfigGroupsMenu 选择分组后逐项编辑
+func runConfigGroupsMenu() {
+	names := make([]string, 0, len(configGroups)+2)
+	for _, g := range configGroups {
+		names = append(names, g.Name)
+	}
+	if len(configGroupKeys("其他")) > 0 {
+		names = append(names, "其他")
+	}
+	names = append(names, "返回")
+	for {
+		var group string
+		if err := survey.AskOne(&survey.Select{Message: "配置分组", Options: names}, &group); err != nil || group == "返回" {
+			return
+		}
+		runConfigKeysMenu(group, configGroupKeys(group))
+	}
+}
+
+func runConfigKeysMenu(group string, keys []string) {
+	for {
+		cfg := configcheck.File{ConfigSet: Config}
+		options := make([]string, 0, len(keys)+1)
+		for _, key := range keys {
+			options = append(options, fmt.Sprintf("%s = %s", key, formatConfigValue(key, configcheck.Value(&cfg, key))))
+		}
+		options = append(options, "返回")
+		var choose string
+		if err := survey.AskOne(&survey.Select{Message: group, Options: options, PageSize: len(options)}, &choose); err != nil || choose == "返回" {
+			return
+		}
+		editConfigKey(strings.SplitN(choose, " = ", 2)[0])
+	}
+}
+
+// editConfigKey 按字段类型提示输入，检查通过后更新运行时配置，并可写回配置文件
+func editConfigKey(key string) {
+	cfg := configcheck.File{ConfigSet: Config}
+	field := configcheck.Value(&cfg, key)
+	if !field.IsValid() {
+		return
+	}
+	var raw string
+	switch {
+	case field.Kind() == reflect.Bool:
+		v := field.Bool()
+		if err := survey.AskOne(&survey.Confirm{Message: key, Default: v}, &v); err != nil {
+			return
+		}
+		raw = fmt.Sprint(v)
+	case configcheck.Enums[key] != nil:
+		sel := &survey.Select{Message: key, Options: configcheck.Enums[key]}
+		if configcheck.OneOf(field.String(), configcheck.Enums[key]...) == "" {
+			sel.Default = field.String()
+		}
+		if err := survey.AskOne(sel, &raw); err != nil {
+			return
+		}
+	default:
+		label := key
+		if field.Kind() == reflect.Slice {
+			label += "(逗号分隔)"
+		}
+		def := formatConfigValue(key, field)
+		if strings.HasSuffix(key, "-token") || def == `""` {
+			def = ""
+		}
+		prompt := promptui.Prompt{Label: label, Default: def, Templates: &promptui.PromptTemplates{Success: ""}}
+		s, err := prompt.Run()
+		if err != nil {
+			return
+		}
+		raw = strings.TrimSpace(s)
+		if strings.HasSuffix(key, "-token") && raw == "" {
+			fmt.Println("未输入，保持不变")
+			return
+		}
+	}
+
+	section := overrideSection([]configOverride{{Key: key, Value: raw}})
+	if issues := configcheck.CheckSection(section, "", nil, configcheck.Keys()); len(issues) > 0 {
+		for _, issue := range issues {
+			fmt.Println("无效的值:", issue.Msg)
+		}
+		return
+	}
+	if err := overlayConfig(section, &cfg); err != nil {
+		fmt.Println("无效的值:", err)
+		return
+	}
+	Config = cfg.ConfigSet
+	value := configcheck.Value(&cfg, key)
+	fmt.Printf("%s 已更新为: %s\n", key, formatConfigValue(key, value))
+	if restartConfigKeys[key] {
+		fmt.Println("该设置在下次运行时生效")
+	}
+	if askYesNo(fmt.Sprintf("是否写入配置文件的 %s? (y/N) ", key)) {
+		if err := writeConfigKey(key, value.Interface()); err != nil {
+			fmt.Println("写入配置失败:", err)
+		} else {
+			fmt.Println(configName(), "已更新", key)
+		}
+	}
+}
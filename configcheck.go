@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+
+	"main/utils/configcheck"
+)
+
+// 启动时检查的结果，由 loadConfig 填充
+var configIssues []configcheck.Issue
+
+// validateConfig 检查配置文件，问题来源为当前配置文件的名称
+func validateConfig(data []byte) ([]configcheck.Issue, error) {
+	return configcheck.Validate(data, configName())
+}
+
+// validateOverrides 检查环境变量与 --set 覆盖项，--set 中的未知键视为错误
+func validateOverrides(overrides []configOverride) []configcheck.Issue {
+	keys := configcheck.Keys()
+	delete(keys, "profiles")
+	var issues []configcheck.Issue
+	for _, o := range overrides {
+		if o.Key == "profiles" {
+			issues = append(issues, configcheck.Issue{Source: o.Source, Key: o.Name, Msg: "profiles cannot be overridden, use --profile"})
+			continue
+		}
+		for _, issue := range configcheck.CheckSection(overrideSection([]configOverride{o}), "", nil, keys) {
+			issue.Source, issue.Key = o.Source, o.Name
+			if o.Source == "--set" && issue.Warning {
+				// 命令行上写错的键不忽略
+				issue.Msg = strings.Replace(issue.Msg, "unknown key, ignored", "unknown key", 1)
+				issue.Warning = false
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"main/utils/configcheck"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 找不到配置文件时使用的内置默认配置（如容器中只通过环境变量配置）
+//
+//go:embed config-example.yaml
+var defaultConfig []byte
+
+var (
+	// 实际加载的配置文件路径，为空表示使用内置默认配置；向导等写回配置时使用
+	configPath string
+	// --profile 选择的配置档名称
+	configProfile string
+	// --set key=value 覆盖项
+	configSets []string
+)
+
+// 环境变量前缀，键名转为大写并将 "-" 换成 "_"，如 alac-max -> AMD_ALAC_MAX
+const configEnvPrefix = "AMD_"
+
+// configArgs 在 cobra 解析前取出的配置相关参数（配置需先于命令加载）
+type configArgs struct {
+	Path    string
+	Profile string
+	Sets    []string
+}
+
+// configArgsFrom 解析 --config/--profile/--set，未指定时 --config/--profile 取自 AMD_CONFIG/AMD_PROFILE
+func configArgsFrom(args []string) configArgs {
+	out := configArgs{
+		Path:    os.Getenv(configEnvPrefix + "CONFIG"),
+		Profile: os.Getenv(configEnvPrefix + "PROFILE"),
+	}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		for _, name := range []string{"--config", "--profile", "--set"} {
+			var v string
+			switch {
+			case a == name && i+1 < len(args):
+				i++
+				v = args[i]
+			case strings.HasPrefix(a, name+"="):
+				v = strings.TrimPrefix(a, name+"=")
+			default:
+				continue
+			}
+			switch name {
+			case "--config":
+				out.Path = v
+			case "--profile":
+				out.Profile = v
+			default:
+				out.Sets = append(out.Sets, v)
+			}
+		}
+	}
+	return out
+}
+
+// configCandidates 未指定 --config 时的查找顺序：
+// 当前目录、$XDG_CONFIG_HOME/amd（或系统配置目录）、~/.config/amd
+func configCandidates() []string {
+	paths := []string{"config.yaml"}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "amd", "config.yaml"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "amd", "config.yaml"))
+	}
+	seen := make(map[string]bool)
+	out := paths[:0]
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// resolveConfigPath 返回要加载的配置文件；都不存在时返回空路径，使用内置默认配置
+func resolveConfigPath(explicit string) (string, error) {
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return "", fmt.Errorf("config file %s: %w", explicit, err)
+		}
+		return explicit, nil
+	}
+	candidates := configCandidates()
+	for _, p := range candidates {
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p, nil
+		}
+	}
+	fmt.Fprintf(os.Stderr, "No config file found (searched %s), using built-in defaults\n", strings.Join(candidates, ", "))
+	return "", nil
+}
+
+// configName 用于提示信息的配置来源名称
+func configName() string {
+	if configPath == "" {
+		return "built-in defaults"
+	}
+	return configPath
+}
+
+// readConfigData 读取配置文件内容，未找到配置文件时返回内置默认配置
+func readConfigData() ([]byte, error) {
+	if configPath == "" {
+		return defaultConfig, nil
+	}
+	return os.ReadFile(configPath)
+}
+
+// applyProfile 将 profiles 中的指定配置档覆盖到 out 上，只替换配置档中出现的键
+func applyProfile(data []byte, profile string, out interface{}) error {
+	if profile == "" {
+		return nil
+	}
+	var file struct {
+		Profiles map[string]yaml.MapSlice `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	p, ok := file.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for name := range file.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("profile %q not found: config has no profiles", profile)
+		}
+		return fmt.Errorf("profile %q not found, available: %s", profile, strings.Join(names, ", "))
+	}
+	return overlayConfig(p, out)
+}
+
+// overlayConfig 将一组键值覆盖到 out 上，类型错误由 validateConfig 报告
+func overlayConfig(section yaml.MapSlice, out interface{}) error {
+	raw, err := yaml.Marshal(section)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(raw, out); err != nil {
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// configOverride 来自环境变量或 --set 的单个覆盖项
+type configOverride struct {
+	Source string // "environment" 或 "--set"
+	Name   string // 环境变量名或 --set 的键
+	Key    string
+	Value  string
+}
+
+// configEnvName 返回键对应的环境变量名
+func configEnvName(key string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// configOverrides 收集覆盖项，环境变量在前、--set 在后（后者优先）
+func configOverrides(sets []string) ([]configOverride, error) {
+	var out []configOverride
+	fields := configcheck.Fields()
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if v, ok := os.LookupEnv(configEnvName(key)); ok {
+			out = append(out, configOverride{Source: "environment", Name: configEnvName(key), Key: key, Value: v})
+		}
+	}
+	for _, s := range sets {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", s)
+		}
+		key = strings.TrimSpace(key)
+		out = append(out, configOverride{Source: "--set", Name: key, Key: key, Value: value})
+	}
+	return out, nil
+}
+
+// overrideSection 将覆盖项按字段类型转换为 yaml 键值：字符串原样使用，列表可用逗号分隔，其余按 yaml 标量解析
+func overrideSection(overrides []configOverride) yaml.MapSlice {
+	fields := configcheck.Fields()
+	section := make(yaml.MapSlice, 0, len(overrides))
+	for _, o := range overrides {
+		var v interface{} = o.Value
+		switch t := fields[o.Key]; {
+		case t == nil || t.Kind() == reflect.String:
+		case t.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(o.Value), "["):
+			list := []interface{}{}
+			for _, item := range strings.Split(o.Value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			v = list
+		default:
+			if err := yaml.Unmarshal([]byte(o.Value), &v); err != nil {
+				v = o.Value
+			}
+		}
+		section = append(section, yaml.MapItem{Key: o.Key, Value: v})
+	}
+	return section
+}
+
+// errNoConfig 配置文件路径尚未确定（如 loadConfig 之前调用写回，或正在使用内置默认配置）
+var errNoConfig = errors.New("no config file loaded")
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"main/pkg/downloader"
+	"main/utils/atomicfile"
+	"main/utils/store"
+)
+
+// 下载数据库文件名（位于输出根目录下）
+const downloadDBName = ".amd.db"
+
+// 持久化下载状态，由 openDownloadDB 在需要时打开；打开失败时为 nil，此时下载器仅使用内存记录
+var (
+	downloadDB     *store.DB
+	downloadDBOnce sync.Once
+)
+
+// openDownloadDB 首次调用时打开（必要时创建）输出目录下的下载数据库，之后返回同一实例；
+// 只由需要它的命令调用，--help、config 等命令不会创建目录或占用数据库锁
+func openDownloadDB() *store.DB {
+	downloadDBOnce.Do(func() {
+		if err := os.MkdirAll(OutputFolder, os.ModePerm); err != nil {
+			fmt.Println("Failed to create output folder:", err)
+			return
+		}
+		db, err := store.Open(filepath.Join(OutputFolder, downloadDBName), time.Second)
+		if err != nil {
+			// 其他进程占用时不阻塞，退化为仅内存记录
+			fmt.Println("Download database unavailable, resume state will not be persisted:", err)
+			return
+		}
+		downloadDB = db
+	})
+	return downloadDB
+}
+
+var sweepOnce sync.Once
+
+// sweepPartFiles 删除上次中断留下的临时文件，每次运行只扫描一次，由下载命令在创建下载器时调用；
+// 仅在持有数据库锁时执行，避免删除其他进程正在写入的文件
+func sweepPartFiles() {
+	if openDownloadDB() == nil {
+		return
+	}
+	sweepOnce.Do(doSweepPartFiles)
+}
+
+func doSweepPartFiles() {
+	removed, err := atomicfile.Sweep(OutputFolder)
+	if err != nil {
+		fmt.Println("Failed to clean up partial files:", err)
+		return
+	}
+	if removed > 0 {
+		fmt.Printf("Removed %d partial file(s) left by an interrupted run\n", removed)
+	}
+}
+
+func closeDownloadDB() {
+	if downloadDB != nil {
+		_ = downloadDB.Close()
+		downloadDB = nil
+	}
+}
+
+// failedEntityUrls 根据数据库中的失败记录重建实体 URL
+func failedEntityUrls() ([]string, error) {
+	if openDownloadDB() == nil {
+		return nil, fmt.Errorf("download database is not available")
+	}
+	recs, err := downloadDB.FailedTracks()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	var urls []string
+	for _, r := range recs {
+		if _, ok := seen[r.EntityID]; ok {
+			continue
+		}
+		seen[r.EntityID] = struct{}{}
+		storefront := r.Storefront
+		if storefront == "" {
+			storefront = Config.Storefront
+		}
+		urls = append(urls, downloader.EntityURL(r.EntityType, storefront, r.EntityID))
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
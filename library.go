@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"main/pkg/downloader"
+	"main/utils/atomicfile"
+	"main/utils/store"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// readLibraryItem 从 m4a 内嵌标签读取歌曲 ID 与 ISRC
+func readLibraryItem(path string) (store.LibraryItem, error) {
+	it := store.LibraryItem{Path: path}
+	mp4, err := mp4tag.Open(path)
+	if err != nil {
+		return it, err
+	}
+	defer mp4.Close()
+	tags, err := mp4.Read()
+	if err != nil {
+		return it, err
+	}
+	it.SongID = tags.Custom[downloader.SongIDTag]
+	it.ISRC = tags.Custom["ISRC"]
+	if c, err := downloader.DetectCodec(path); err == nil {
+		it.Codec = c
+	}
+	return it, nil
+}
+
+// reindexLibrary 扫描输出目录下所有 m4a，重建曲库索引；扫描完成后才替换原有索引，
+// 扫描中途失败时原有索引保持不变
+func reindexLibrary(root string) (indexed, skipped int, err error) {
+	if openDownloadDB() == nil {
+		return 0, 0, fmt.Errorf("download database is not available")
+	}
+	var items []store.LibraryItem
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// 无法读取的子目录与文件计为跳过，不中断扫描
+			skipped++
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".m4a") || atomicfile.IsPart(d.Name()) {
+			return nil
+		}
+		it, err := readLibraryItem(path)
+		if err != nil || (it.SongID == "" && it.ISRC == "") {
+			skipped++
+			return nil
+		}
+		items = append(items, it)
+		return nil
+	})
+	if err != nil {
+		return 0, skipped, err
+	}
+	if err := downloadDB.ReplaceLibrary(items); err != nil {
+		return 0, skipped, err
+	}
+	return len(items), skipped, nil
+}
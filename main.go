@@ -2,44 +2,27 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
-	"log"
-	"net"
-	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"regexp"
-	"sort"
+	"os/signal"
 	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
-	"time"
+	"syscall"
 
+	"main/pkg/downloader"
 	"main/utils/ampapi"
-	"main/utils/lyrics"
-	"main/utils/runv2"
-	"main/utils/runv3"
+	"main/utils/configcheck"
 	"main/utils/structs"
-	"main/utils/task"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/fatih/color"
-	"github.com/grafov/m3u8"
 	"github.com/olekukonko/tablewriter"
-	"github.com/zhaarey/go-mp4tag"
 	"gopkg.in/yaml.v2"
 )
 
 var (
-	forbiddenNames      = regexp.MustCompile(`[/\\<>:"|?*]`)
 	dl_atmos            bool
 	dl_aac              bool
 	dl_select           bool
@@ -48,36 +31,12 @@ var (
 	debug_mode          bool
 	aac_type            *string
 	Config              structs.ConfigSet
-	counter             structs.Counter
-	okDict              = make(map[string][]int)
 	OutputFolder        string
 	DownloadConcurrency int
-	statsMu             sync.Mutex
-	okMu                sync.Mutex
-	// 活跃任务计数（用于进度渲染）
-	activeDownloads int32
 	// 输入状态：交互式用户正在输入，暂停渲染器输出
 	inputActive int32
 	// 运行时覆盖的 codec-priority，仅本次运行有效
 	RuntimeCodecPriority []string
-	// 进度刷新通道（事件驱动）
-	progressCh chan struct{}
-	// 运行期问题记录（详情输出使用）
-	issueMu         sync.Mutex
-	warningMessages []string
-	errorMessages   []string
-	// 失败项记录与重试模式
-	failMu   sync.Mutex
-	failDict map[string]map[int]struct{}
-	// 实体级失败记录（album/playlist/station 等在进入阶段失败时）
-	failEntityMu sync.Mutex
-	failEntity   = make(map[string]struct{})
-	retryOnly    bool
-
-	// New globals for concurrency and HTTP
-	httpClient  *http.Client
-	downloadSem chan struct{}
-	tagSem      chan struct{}
 
 	// CPU profiling (PGO)
 	cpuProfilePath   string
@@ -86,80 +45,31 @@ var (
 )
 
 func main() {
-	// Try to load config.yaml. If missing, try to create it from config-example.yaml.
+	// Locate the config file (--config, ./config.yaml, XDG / ~/.config/amd), then apply --profile, AMD_* and --set
+	args := configArgsFrom(os.Args[1:])
+	resolved, err := resolveConfigPath(args.Path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	configPath, configProfile, configSets = resolved, args.Profile, args.Sets
 	if err := loadConfig(); err != nil {
-		if os.IsNotExist(err) {
-			// Attempt to copy from example
-			if data, err2 := os.ReadFile("config-example.yaml"); err2 == nil {
-				if err3 := os.WriteFile("config.yaml", data, 0644); err3 == nil {
-					fmt.Println("Created config.yaml from config-example.yaml")
-					// try loading again
-					if err4 := loadConfig(); err4 != nil {
-						fmt.Fprintln(os.Stderr, "Failed to load config after creating from example:", err4)
-						os.Exit(1)
-					}
-				} else {
-					fmt.Fprintln(os.Stderr, "Failed to create config.yaml from example:", err3)
-					os.Exit(1)
-				}
-			} else {
-				fmt.Fprintln(os.Stderr, "config.yaml not found and config-example.yaml missing:", err)
-				os.Exit(1)
-			}
-		} else {
-			fmt.Fprintln(os.Stderr, "Failed to load config.yaml:", err)
-			os.Exit(1)
-		}
+		fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", configName(), err)
+		os.Exit(1)
 	}
 
-	// Initialize runtime globals (semaphores, http client, etc.)
-	initGlobals()
-
 	// Apply loaded config to cobra flags so help shows correct defaults
 	applyConfigToFlags()
 
-	// Execute cobra CLI
-	if err := rootCmd.Execute(); err != nil {
+	// Execute cobra CLI; Ctrl+C cancels the command context
+	err = rootCmd.ExecuteContext(interruptContext())
+	closeDownloadDB()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-// initialize global runtime helpers (call after loadConfig)
-func initGlobals() {
-	if DownloadConcurrency <= 0 {
-		DownloadConcurrency = 4
-	}
-	downloadSem = make(chan struct{}, DownloadConcurrency)
-	// progress channel
-	if progressCh == nil {
-		progressCh = make(chan struct{}, 1)
-	}
-	reqTimeout := 30 * time.Second
-	if Config.RequestTimeoutSec > 0 {
-		reqTimeout = time.Duration(Config.RequestTimeoutSec) * time.Second
-	}
-	httpClient = &http.Client{
-		Timeout: reqTimeout,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   reqTimeout,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout: 10 * time.Second,
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 20,
-			IdleConnTimeout:     60 * time.Second,
-		},
-	}
-	tagConc := 2
-	if Config.TaggingConcurrency > 0 {
-		tagConc = Config.TaggingConcurrency
-	}
-	tagSem = make(chan struct{}, tagConc)
-}
-
 // applyConfigToFlags sets cobra persistent flag values based on loaded Config.
 func applyConfigToFlags() {
 	if rootCmd == nil {
@@ -185,46 +95,52 @@ func applyConfigToFlags() {
 	}
 }
 
-// acquireDownloadSlot blocks until a download slot is available
-func acquireDownloadSlot() {
-	downloadSem <- struct{}{}
-}
-
-// releaseDownloadSlot releases a previously acquired slot
-func releaseDownloadSlot() {
-	select {
-	case <-downloadSem:
-	default:
-	}
-}
-
-func acquireTagSlot() { tagSem <- struct{}{} }
-func releaseTagSlot() {
-	select {
-	case <-tagSem:
-	default:
-	}
-}
-
-// runCmdTimeout runs an external command with a timeout and returns its error
-func runCmdTimeout(timeout time.Duration, name string, args ...string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run()
+// interruptContext 第一次 Ctrl+C/SIGTERM 取消 ctx：不再开始新曲目，进行中的下载中止并清理未完成的文件；
+// 第二次直接退出
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up... (press Ctrl+C again to force quit)")
+		cancel()
+		<-sigs
+		closeDownloadDB()
+		os.Exit(130)
+	}()
+	return ctx
 }
 
+// loadConfig 按优先级合并配置：配置文件 < --profile 配置档 < AMD_* 环境变量 < --set；
+// 专用命令行标志（如 --alac-max）在命令执行前再覆盖
 func loadConfig() error {
-	data, err := os.ReadFile("config.yaml")
+	data, err := readConfigData()
 	if err != nil {
 		return err
 	}
-	err = yaml.Unmarshal(data, &Config)
+	if configIssues, err = validateConfig(data); err != nil {
+		return err
+	}
+	overrides, err := configOverrides(configSets)
 	if err != nil {
 		return err
 	}
+	configIssues = append(configIssues, validateOverrides(overrides)...)
+
+	var cfg configcheck.File
+	// 类型错误已由 validateConfig 按键报告，其余键照常加载
+	err = yaml.Unmarshal(data, &cfg)
+	if _, ok := err.(*yaml.TypeError); err != nil && !ok {
+		return err
+	}
+	if err := applyProfile(data, configProfile, &cfg); err != nil {
+		return err
+	}
+	if err := overlayConfig(overrideSection(overrides), &cfg); err != nil {
+		return err
+	}
+	Config = cfg.ConfigSet
 	if len(Config.Storefront) != 2 {
 		Config.Storefront = "us"
 	}
@@ -237,141 +153,18 @@ func loadConfig() error {
 	if strings.TrimSpace(Config.LrcFormat) == "" {
 		Config.LrcFormat = "lrc"
 	}
-	// 读取 output-folder（不修改外部 ConfigSet 结构）
-	var cfgOut struct {
-		OutputFolder string `yaml:"output-folder"`
-	}
-	_ = yaml.Unmarshal(data, &cfgOut)
-	OutputFolder = strings.TrimSpace(cfgOut.OutputFolder)
+	// 无论来自配置文件、AMD_* 还是 --set，相对路径都以当前工作目录为基准
+	OutputFolder = strings.TrimSpace(cfg.OutputFolder)
 	if OutputFolder == "" {
 		OutputFolder = "output"
 	}
-	var cfgConc struct {
-		DownloadConcurrency int `yaml:"download-concurrency"`
-	}
-	_ = yaml.Unmarshal(data, &cfgConc)
-	DownloadConcurrency = cfgConc.DownloadConcurrency
+	DownloadConcurrency = cfg.DownloadConcurrency
 	if DownloadConcurrency <= 0 {
 		DownloadConcurrency = 4
 	}
 	return nil
 }
 
-// Thread-safe counters and okDict helpers
-func incTotal() {
-	statsMu.Lock()
-	counter.Total++
-	statsMu.Unlock()
-	signalProgress()
-}
-func incSuccess() {
-	statsMu.Lock()
-	counter.Success++
-	statsMu.Unlock()
-	signalProgress()
-}
-func incError() {
-	statsMu.Lock()
-	counter.Error++
-	statsMu.Unlock()
-	signalProgress()
-}
-func incUnavailable() {
-	statsMu.Lock()
-	counter.Unavailable++
-	statsMu.Unlock()
-	signalProgress()
-}
-
-func addOk(id string, num int) {
-	okMu.Lock()
-	okDict[id] = append(okDict[id], num)
-	okMu.Unlock()
-}
-
-// 失败项记录与查询
-func addFail(id string, num int) {
-	failMu.Lock()
-	if failDict == nil {
-		failDict = make(map[string]map[int]struct{})
-	}
-	set, ok := failDict[id]
-	if !ok {
-		set = make(map[int]struct{})
-		failDict[id] = set
-	}
-	set[num] = struct{}{}
-	failMu.Unlock()
-}
-func removeFail(id string, num int) {
-	failMu.Lock()
-	if set, ok := failDict[id]; ok {
-		delete(set, num)
-		if len(set) == 0 {
-			delete(failDict, id)
-		}
-	}
-	failMu.Unlock()
-}
-func getFail(id string) []int {
-	failMu.Lock()
-	set, ok := failDict[id]
-	failMu.Unlock()
-	if !ok {
-		return nil
-	}
-	out := make([]int, 0, len(set))
-	for n := range set {
-		out = append(out, n)
-	}
-	sort.Ints(out)
-	return out
-}
-func clearFail() {
-	failMu.Lock()
-	failDict = make(map[string]map[int]struct{})
-	failMu.Unlock()
-}
-
-// 是否存在任何失败项（轨道或实体级）
-func hasAnyFail() bool {
-	failMu.Lock()
-	fd := len(failDict) > 0
-	failMu.Unlock()
-	failEntityMu.Lock()
-	ed := len(failEntity) > 0
-	failEntityMu.Unlock()
-	return fd || ed
-}
-
-// 实体级失败记录与查询
-func addEntityFail(id string) {
-	failEntityMu.Lock()
-	failEntity[id] = struct{}{}
-	failEntityMu.Unlock()
-}
-func removeEntityFail(id string) {
-	failEntityMu.Lock()
-	delete(failEntity, id)
-	failEntityMu.Unlock()
-}
-func clearEntityFail() {
-	failEntityMu.Lock()
-	failEntity = make(map[string]struct{})
-	failEntityMu.Unlock()
-}
-
-// Thread-safe read of okDict slice for an id
-func getOk(id string) []int {
-	okMu.Lock()
-	s := okDict[id]
-	// make a copy to avoid data race on caller side
-	out := make([]int, len(s))
-	copy(out, s)
-	okMu.Unlock()
-	return out
-}
-
 // 当前 codec 优先级：优先使用运行时覆盖，其次使用配置
 func currentCodecPriority() []string {
 	if len(RuntimeCodecPriority) > 0 {
@@ -380,46 +173,58 @@ func currentCodecPriority() []string {
 	return Config.CodecPriority
 }
 
-// 发出一次进度刷新信号（非阻塞）
-func signalProgress() {
-	if progressCh != nil {
-		select {
-		case progressCh <- struct{}{}:
-		default:
+// newDownloader 按当前配置与命令行选项创建下载命令使用的下载器，每个命令（或向导中的每次操作）各用一个；
+// 创建前清理上次中断留下的临时文件
+func newDownloader() *downloader.Downloader {
+	sweepPartFiles()
+	opts := downloaderOptions()
+	opts.DB = openDownloadDB()
+	return downloader.New(opts)
+}
+
+// downloaderOptions 由全局配置与命令行参数生成下载器选项，不含下载数据库
+func downloaderOptions() downloader.Options {
+	cfg := Config
+	cfg.CodecPriority = currentCodecPriority()
+	return downloader.Options{
+		Config:            cfg,
+		OutputFolder:      OutputFolder,
+		Concurrency:       DownloadConcurrency,
+		Token:             cliToken,
+		Atmos:             dl_atmos,
+		AAC:               dl_aac,
+		Song:              dl_song,
+		Select:            dl_select,
+		Debug:             debug_mode,
+		DryRun:            dryRunFormat != "",
+		Logger:            Logger,
+		SelectArtistItems: selectArtistItems,
+	}
+}
+
+// ripInteractive 下载单个链接，完成后显示详细告警/错误信息并询问是否重试失败项
+func ripInteractive(ctx context.Context, rawURL string) {
+	d := newDownloader()
+	res, err := d.RipURL(ctx, rawURL, downloader.RipOptions{})
+	if dryRunFormat != "" {
+		printPlan(res)
+		return
+	}
+	printIssuesSummary(d)
+	for (err != nil || res.Failed()) && ctx.Err() == nil {
+		if !askYesNo("是否重试失败项? (y/N) ") {
+			break
 		}
+		d.ClearIssues()
+		res, err = d.RipURL(ctx, rawURL, downloader.RipOptions{Retry: true})
+		printIssuesSummary(d)
 	}
+	st := d.Stats()
+	fmt.Printf("=======  [\u2714 ] Completed: %d/%d  |  [\u26A0 ] Warnings: %d  |  [\u2716 ] Errors: %d  =======\n", st.Success, st.Total, st.Unavailable, st.Error)
 }
 
-// （已移除）REPL 模式相关的终端控制函数
-
-// 问题记录与打印
-func addWarning(msg string) {
-	issueMu.Lock()
-	warningMessages = append(warningMessages, msg)
-	issueMu.Unlock()
-	if Logger != nil {
-		Logger.Warn(msg)
-	}
-}
-func addError(msg string) {
-	issueMu.Lock()
-	errorMessages = append(errorMessages, msg)
-	issueMu.Unlock()
-	if Logger != nil {
-		Logger.Error(msg)
-	}
-}
-func clearIssues() {
-	issueMu.Lock()
-	warningMessages = nil
-	errorMessages = nil
-	issueMu.Unlock()
-}
-func printIssuesSummary() {
-	issueMu.Lock()
-	w := append([]string{}, warningMessages...)
-	e := append([]string{}, errorMessages...)
-	issueMu.Unlock()
+func printIssuesSummary(d *downloader.Downloader) {
+	w, e := d.Issues()
 	if len(w) == 0 && len(e) == 0 {
 		return
 	}
@@ -448,168 +253,24 @@ func askYesNo(prompt string) bool {
 	return s == "y" || s == "yes"
 }
 
-// 进度渲染：事件驱动的底部刷新
-// （已移除）REPL 模式进度渲染器
-
-func LimitString(s string) string {
-	if len([]rune(s)) > Config.LimitMax {
-		return string([]rune(s)[:Config.LimitMax])
-	}
-	return s
-}
-
-func isInArray(arr []int, target int) bool {
-	for _, num := range arr {
-		if num == target {
-			return true
-		}
-	}
-	return false
-}
-
-func fileExists(path string) (bool, error) {
-	f, err := os.Stat(path)
-	if err == nil {
-		return !f.IsDir(), nil
-	} else if os.IsNotExist(err) {
-		return false, nil
-	}
-	return false, err
-}
-
-func checkUrl(url string) (string, string) {
-	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/album|\/album\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
-	matches := pat.FindAllStringSubmatch(url, -1)
-
-	if matches == nil {
-		return "", ""
-	} else {
-		return matches[0][1], matches[0][2]
-	}
-}
-func checkUrlMv(url string) (string, string) {
-	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music)\.apple\.com\/(\w{2})(?:\/music-video|\/music-video\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
-	matches := pat.FindAllStringSubmatch(url, -1)
-
-	if matches == nil {
-		return "", ""
-	} else {
-		return matches[0][1], matches[0][2]
-	}
-}
-func checkUrlSong(url string) (string, string) {
-	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/song|\/song\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
-	matches := pat.FindAllStringSubmatch(url, -1)
-
-	if matches == nil {
-		return "", ""
-	} else {
-		return matches[0][1], matches[0][2]
-	}
-}
-func checkUrlPlaylist(url string) (string, string) {
-	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/playlist|\/playlist\/.+))\/(?:id)?(pl\.[\w-]+)(?:$|\?)`)
-	matches := pat.FindAllStringSubmatch(url, -1)
-
-	if matches == nil {
-		return "", ""
-	} else {
-		return matches[0][1], matches[0][2]
-	}
-}
-
-func checkUrlStation(url string) (string, string) {
-	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music)\.apple\.com\/(\w{2})(?:\/station|\/station\/.+))\/(?:id)?(ra\.[\w-]+)(?:$|\?)`)
-	matches := pat.FindAllStringSubmatch(url, -1)
-
-	if matches == nil {
-		return "", ""
-	} else {
-		return matches[0][1], matches[0][2]
-	}
-}
-
-func checkUrlArtist(url string) (string, string) {
-	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/artist|\/artist\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
-	matches := pat.FindAllStringSubmatch(url, -1)
-
-	if matches == nil {
-		return "", ""
-	} else {
-		return matches[0][1], matches[0][2]
-	}
-}
-
-func getUrlArtistName(artistUrl string, token string) (string, string, error) {
-	storefront, artistId := checkUrlArtist(artistUrl)
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/artists/%s", storefront, artistId), nil)
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-	req.Header.Set("Origin", "https://music.apple.com")
-	query := url.Values{}
-	query.Set("l", Config.Language)
-	req.URL.RawQuery = query.Encode()
-	do, err := httpClient.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer do.Body.Close()
-	if do.StatusCode != http.StatusOK {
-		return "", "", errors.New(do.Status)
+// selectArtistItems 选择要下载的艺术家专辑/MV、唱片公司专辑或策展人歌单：过滤条件生效或 --all-album 时全选，否则交互选择
+func selectArtistItems(ctx context.Context, artistName string, relationship string, items []downloader.ArtistItem) []downloader.ArtistItem {
+	autoSelect := artistFilterActive()
+	if autoSelect {
+		before := len(items)
+		items = filterArtistItems(items, relationship, artistName)
+		fmt.Printf("Artist filters: %d of %d %s selected\n", len(items), before, relationship)
 	}
-	obj := new(structs.AutoGeneratedArtist)
-	err = json.NewDecoder(do.Body).Decode(&obj)
-	if err != nil {
-		return "", "", err
-	}
-	return obj.Data[0].Attributes.Name, obj.Data[0].ID, nil
-}
-
-func checkArtist(artistUrl string, token string, relationship string) ([]string, error) {
-	storefront, artistId := checkUrlArtist(artistUrl)
-	Num := 0
-	//id := 1
-	var args []string
-	var urls []string
+	var args []downloader.ArtistItem
 	var options [][]string
-	for {
-		req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/artists/%s/%s?limit=100&offset=%d&l=%s", storefront, artistId, relationship, Num, Config.Language), nil)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-		req.Header.Set("Origin", "https://music.apple.com")
-		do, err := httpClient.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer do.Body.Close()
-		if do.StatusCode != http.StatusOK {
-			return nil, errors.New(do.Status)
-		}
-		obj := new(structs.AutoGeneratedArtist)
-		err = json.NewDecoder(do.Body).Decode(&obj)
-		if err != nil {
-			return nil, err
-		}
-		for _, album := range obj.Data {
-			options = append(options, []string{album.Attributes.Name, album.Attributes.ReleaseDate, album.ID, album.Attributes.URL})
-		}
-		Num = Num + 100
-		if len(obj.Next) == 0 {
-			break
+	for _, it := range items {
+		// 歌单没有发行日期，显示策展人
+		date := it.ReleaseDate
+		if relationship == "playlists" {
+			date = it.ArtistName
 		}
+		options = append(options, []string{it.Name, date, it.ID, it.URL})
 	}
-	sort.Slice(options, func(i, j int) bool {
-		// 将日期字符串解析为 time.Time 类型进行比较
-		dateI, _ := time.Parse("2006-01-02", options[i][1])
-		dateJ, _ := time.Parse("2006-01-02", options[j][1])
-		return dateI.Before(dateJ) // 返回 true 表示 i 在 j 前面
-	})
 
 	table := tablewriter.NewWriter(os.Stdout)
 	switch relationship {
@@ -617,6 +278,8 @@ func checkArtist(artistUrl string, token string, relationship string) ([]string,
 		table.SetHeader([]string{"", "Album Name", "Date", "Album ID"})
 	case "music-videos":
 		table.SetHeader([]string{"", "MV Name", "Date", "MV ID"})
+	case "playlists":
+		table.SetHeader([]string{"", "Playlist Name", "Curator", "Playlist ID"})
 	}
 	table.SetRowLine(false)
 	table.SetHeaderColor(tablewriter.Colors{},
@@ -629,14 +292,13 @@ func checkArtist(artistUrl string, token string, relationship string) ([]string,
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor})
 	for i, v := range options {
-		urls = append(urls, v[3])
 		options[i] = append([]string{fmt.Sprint(i + 1)}, v[:3]...)
 		table.Append(options[i])
 	}
 	table.Render()
-	if artist_select {
+	if artist_select || autoSelect {
 		fmt.Println("You have selected all options:")
-		return urls, nil
+		return items
 	}
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Println("Please select from the " + relationship + " options above (multiple options separated by commas, ranges supported, or type 'all' to select all)")
@@ -647,7 +309,7 @@ func checkArtist(artistUrl string, token string, relationship string) ([]string,
 	input = strings.TrimSpace(input)
 	if input == "all" {
 		fmt.Println("You have selected all options:")
-		return urls, nil
+		return items
 	}
 
 	selectedOptions := [][]string{}
@@ -671,7 +333,7 @@ func checkArtist(artistUrl string, token string, relationship string) ([]string,
 			}
 			if num > 0 && num <= len(options) {
 				fmt.Println(options[num-1])
-				args = append(args, urls[num-1])
+				args = append(args, items[num-1])
 			} else {
 				fmt.Println("Option out of range:", opt[0])
 			}
@@ -688,106 +350,13 @@ func checkArtist(artistUrl string, token string, relationship string) ([]string,
 			}
 			for i := start; i <= end; i++ {
 				fmt.Println(options[i-1])
-				args = append(args, urls[i-1])
+				args = append(args, items[i-1])
 			}
 		} else {
 			fmt.Println("Invalid option:", opt)
 		}
 	}
-	return args, nil
-}
-
-func writeCover(sanAlbumFolder, name string, url string) (string, error) {
-	originalUrl := url
-	var ext string
-	var covPath string
-	if Config.CoverFormat == "original" {
-		ext = strings.Split(url, "/")[len(strings.Split(url, "/"))-2]
-		ext = ext[strings.LastIndex(ext, ".")+1:]
-		covPath = filepath.Join(sanAlbumFolder, name+"."+ext)
-	} else {
-		covPath = filepath.Join(sanAlbumFolder, name+"."+Config.CoverFormat)
-	}
-	exists, err := fileExists(covPath)
-	if err != nil {
-		fmt.Println("Failed to check if cover exists.")
-		return "", err
-	}
-	if exists {
-		_ = os.Remove(covPath)
-	}
-	if Config.CoverFormat == "png" {
-		re := regexp.MustCompile(`\{w\}x\{h\}`)
-		parts := re.Split(url, 2)
-		url = parts[0] + "{w}x{h}" + strings.Replace(parts[1], ".jpg", ".png", 1)
-	}
-	url = strings.Replace(url, "{w}x{h}", Config.CoverSize, 1)
-	if Config.CoverFormat == "original" {
-		url = strings.Replace(url, "is1-ssl.mzstatic.com/image/thumb", "a5.mzstatic.com/us/r1000/0", 1)
-		url = url[:strings.LastIndex(url, "/")]
-	}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-	do, err := httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer do.Body.Close()
-	if do.StatusCode != http.StatusOK {
-		if Config.CoverFormat == "original" {
-			fmt.Println("Failed to get cover, falling back to " + ext + " url.")
-			splitByDot := strings.Split(originalUrl, ".")
-			last := splitByDot[len(splitByDot)-1]
-			fallback := originalUrl[:len(originalUrl)-len(last)] + ext
-			fallback = strings.Replace(fallback, "{w}x{h}", Config.CoverSize, 1)
-			fmt.Println("Fallback URL:", fallback)
-			req, err = http.NewRequest("GET", fallback, nil)
-			if err != nil {
-				fmt.Println("Failed to create request for fallback url.")
-				return "", err
-			}
-			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-			do, err = httpClient.Do(req)
-			if err != nil {
-				fmt.Println("Failed to get cover from fallback url.")
-				return "", err
-			}
-			defer do.Body.Close()
-			if do.StatusCode != http.StatusOK {
-				fmt.Println(fallback)
-				return "", errors.New(do.Status)
-			}
-		} else {
-			return "", errors.New(do.Status)
-		}
-	}
-	f, err := os.Create(covPath)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-	_, err = io.Copy(f, do.Body)
-	if err != nil {
-		return "", err
-	}
-	return covPath, nil
-}
-
-func writeLyrics(sanAlbumFolder, filename string, lrc string) error {
-	lyricspath := filepath.Join(sanAlbumFolder, filename)
-	f, err := os.Create(lyricspath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.WriteString(lrc)
-	if err != nil {
-		return err
-	}
-	return nil
+	return args
 }
 
 func contains(slice []string, item string) bool {
@@ -799,17 +368,6 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// formatAvailability returns a human-friendly availability/quality string.
-func formatAvailability(available bool, quality string) string {
-	if !available {
-		return "Not Available"
-	}
-	if quality == "" {
-		return "Available"
-	}
-	return quality
-}
-
 // START: New functions for search functionality
 
 // SearchResultItem is a unified struct to hold search results for display.
@@ -881,7 +439,7 @@ func promptForQuality(item SearchResultItem, token string) (string, error) {
 }
 
 // handleSearch manages the entire interactive search process.
-func handleSearch(searchType string, queryParts []string, token string) (string, error) {
+func handleSearch(ctx context.Context, searchType string, queryParts []string, token string) (string, error) {
 	query := strings.Join(queryParts, " ")
 	validTypes := map[string]bool{"album": true, "song": true, "artist": true}
 	if !validTypes[searchType] {
@@ -896,7 +454,7 @@ func handleSearch(searchType string, queryParts []string, token string) (string,
 	apiSearchType := searchType + "s"
 
 	for {
-		searchResp, err := ampapi.Search(Config.Storefront, query, apiSearchType, Config.Language, token, limit, offset)
+		searchResp, err := ampapi.Search(ctx, Config.Storefront, query, apiSearchType, Config.Language, token, limit, offset)
 		if err != nil {
 			return "", fmt.Errorf("error fetching search results: %w", err)
 		}
@@ -1018,2040 +576,3 @@ func handleSearch(searchType string, queryParts []string, token string) (string,
 }
 
 // END: New functions for search functionality
-
-// CONVERSION FEATURE: Determine if source codec is lossy (rough heuristic by extension/codec name).
-func isLossySource(ext string, codec string) bool {
-	ext = strings.ToLower(ext)
-	if ext == ".m4a" && (codec == "AAC" || strings.Contains(codec, "AAC") || strings.Contains(codec, "ATMOS")) {
-		return true
-	}
-	if ext == ".mp3" || ext == ".opus" || ext == ".ogg" {
-		return true
-	}
-	return false
-}
-
-// CONVERSION FEATURE: Build ffmpeg arguments for desired target.
-func buildFFmpegArgs(ffmpegPath, inPath, outPath, targetFmt, extraArgs string) ([]string, error) {
-	args := []string{"-y", "-i", inPath, "-vn"}
-	switch targetFmt {
-	case "flac":
-		args = append(args, "-c:a", "flac")
-	case "mp3":
-		// VBR quality 2 ~ high quality
-		args = append(args, "-c:a", "libmp3lame", "-qscale:a", "2")
-	case "opus":
-		// Medium/high quality
-		args = append(args, "-c:a", "libopus", "-b:a", "192k", "-vbr", "on")
-	case "wav":
-		args = append(args, "-c:a", "pcm_s16le")
-	case "copy":
-		// Just container copy (probably pointless for same container)
-		args = append(args, "-c", "copy")
-	default:
-		return nil, fmt.Errorf("unsupported convert-format: %s", targetFmt)
-	}
-	if extraArgs != "" {
-		// naive split; for complex quoting you could enhance
-		args = append(args, strings.Fields(extraArgs)...)
-	}
-	args = append(args, outPath)
-	return args, nil
-}
-
-// CONVERSION FEATURE: Perform conversion if enabled.
-func convertIfNeeded(track *task.Track) {
-	if !Config.ConvertAfterDownload {
-		return
-	}
-	if Config.ConvertFormat == "" {
-		return
-	}
-	srcPath := track.SavePath
-	if srcPath == "" {
-		return
-	}
-	ext := strings.ToLower(filepath.Ext(srcPath))
-	targetFmt := strings.ToLower(Config.ConvertFormat)
-
-	// Map extension for output
-	if targetFmt == "copy" {
-		fmt.Println("Convert (copy) requested; skipping because it produces no new format.")
-		return
-	}
-
-	if Config.ConvertSkipIfSourceMatch {
-		if ext == "."+targetFmt {
-			fmt.Printf("Conversion skipped (already %s)\n", targetFmt)
-			return
-		}
-	}
-
-	outBase := strings.TrimSuffix(srcPath, ext)
-	outPath := outBase + "." + targetFmt
-
-	// Warn about lossy -> lossless
-	if Config.ConvertWarnLossyToLossless && (targetFmt == "flac" || targetFmt == "wav") &&
-		isLossySource(ext, track.Codec) {
-		fmt.Println("Warning: Converting lossy source to lossless container will not improve quality.")
-	}
-
-	if _, err := exec.LookPath(Config.FFmpegPath); err != nil {
-		fmt.Printf("ffmpeg not found at '%s'; skipping conversion.\n", Config.FFmpegPath)
-		return
-	}
-
-	args, err := buildFFmpegArgs(Config.FFmpegPath, srcPath, outPath, targetFmt, Config.ConvertExtraArgs)
-	if err != nil {
-		fmt.Println("Conversion config error:", err)
-		return
-	}
-
-	fmt.Printf("Converting -> %s ...\n", targetFmt)
-	// Use a longer timeout for conversions
-	if err := runCmdTimeout(30*time.Minute, Config.FFmpegPath, args...); err != nil {
-		fmt.Println("Conversion failed:", err)
-		// leave original
-		return
-	}
-	fmt.Printf("Conversion completed in %s: %s\n", time.Since(time.Now()).Truncate(time.Millisecond), filepath.Base(outPath))
-
-	if !Config.ConvertKeepOriginal {
-		if err := os.Remove(srcPath); err != nil {
-			fmt.Println("Failed to remove original after conversion:", err)
-		} else {
-			track.SavePath = outPath
-			track.SaveName = filepath.Base(outPath)
-			fmt.Println("Original removed.")
-		}
-	} else {
-		// Keep both but point track to new file (optional decision)
-		track.SavePath = outPath
-		track.SaveName = filepath.Base(outPath)
-	}
-}
-
-func ripTrack(track *task.Track, token string, mediaUserToken string) {
-	var err error
-	atomic.AddInt32(&activeDownloads, 1)
-	signalProgress()
-	defer func() {
-		atomic.AddInt32(&activeDownloads, -1)
-		signalProgress()
-	}()
-	incTotal()
-	fmt.Printf("Track %d of %d: %s\n", track.TaskNum, track.TaskTotal, track.Type)
-
-	//提前获取到的播放列表下track所在的专辑信息
-	if track.PreType == "playlists" && Config.UseSongInfoForPlaylist {
-		track.GetAlbumData(token)
-	}
-
-	//mv dl dev
-	if track.Type == "music-videos" {
-		if len(mediaUserToken) <= 50 {
-			fmt.Println("meida-user-token is not set, skip MV dl")
-			incSuccess()
-			addWarning("MV skipped: media-user-token not set")
-			return
-		}
-		if _, err := exec.LookPath("mp4decrypt"); err != nil {
-			fmt.Println("mp4decrypt is not found, skip MV dl")
-			incSuccess()
-			addWarning("MV skipped: mp4decrypt not found")
-			return
-		}
-		// 歌曲上下文标签用于即时错误提示
-		songTag := fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name)
-		err := mvDownloader(track.ID, track.SaveDir, token, track.Storefront, mediaUserToken, track)
-		if err != nil {
-			fmt.Println("\u26A0 Failed to dl MV:", songTag, err)
-			incError()
-			addFail(track.PreID, track.TaskNum)
-			addError(fmt.Sprintf("%s MV download failed: %v", songTag, err))
-			return
-		}
-		incSuccess()
-		return
-	}
-
-	// Derive per-track download flags from track.Codec when available to avoid global races.
-	localDlAtmos := false
-	localDlAac := false
-	if strings.EqualFold(track.Codec, "ATMOS") {
-		localDlAtmos = true
-	} else if strings.EqualFold(track.Codec, "AAC") {
-		localDlAac = true
-	} else {
-		// fallback to global flags if track codec not set
-		localDlAtmos = dl_atmos
-		localDlAac = dl_aac
-	}
-
-	needDlAacLc := false
-	if localDlAac && Config.AacType == "aac-lc" {
-		needDlAacLc = true
-	}
-	if track.WebM3u8 == "" && !needDlAacLc {
-		if localDlAtmos {
-			fmt.Println("Unavailable")
-			incUnavailable()
-			addFail(track.PreID, track.TaskNum)
-			addWarning("Atmos unavailable, fallback not possible")
-			return
-		}
-		fmt.Println("Unavailable, trying to dl aac-lc")
-		needDlAacLc = true
-	}
-	needCheck := false
-
-	if Config.GetM3u8Mode == "all" {
-		needCheck = true
-	} else if Config.GetM3u8Mode == "hires" && contains(track.Resp.Attributes.AudioTraits, "hi-res-lossless") {
-		needCheck = true
-	}
-	var EnhancedHls_m3u8 string
-	if needCheck && !needDlAacLc {
-		EnhancedHls_m3u8, _ = checkM3u8(track.ID, "song")
-		if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
-			track.DeviceM3u8 = EnhancedHls_m3u8
-			track.M3u8 = EnhancedHls_m3u8
-		}
-	}
-	var Quality string
-	if strings.Contains(Config.SongFileFormat, "Quality") {
-		if localDlAtmos {
-			Quality = fmt.Sprintf("%dKbps", Config.AtmosMax-2000)
-		} else if needDlAacLc {
-			Quality = "256Kbps"
-		} else {
-			_, Quality, _, err = extractMedia(track.M3u8, true)
-			if err != nil {
-				fmt.Println("Failed to extract quality from manifest.\n", err)
-				incError()
-				addFail(track.PreID, track.TaskNum)
-				return
-			}
-		}
-	}
-	track.Quality = Quality
-
-	stringsToJoin := []string{}
-	if track.Resp.Attributes.IsAppleDigitalMaster {
-		if Config.AppleMasterChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.AppleMasterChoice)
-		}
-	}
-	if track.Resp.Attributes.ContentRating == "explicit" {
-		if Config.ExplicitChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.ExplicitChoice)
-		}
-	}
-	if track.Resp.Attributes.ContentRating == "clean" {
-		if Config.CleanChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.CleanChoice)
-		}
-	}
-	Tag_string := strings.Join(stringsToJoin, " ")
-
-	songName := strings.NewReplacer(
-		"{SongId}", track.ID,
-		"{SongNumer}", fmt.Sprintf("%02d", track.TaskNum),
-		"{SongName}", LimitString(track.Resp.Attributes.Name),
-		"{DiscNumber}", fmt.Sprintf("%0d", track.Resp.Attributes.DiscNumber),
-		"{TrackNumber}", fmt.Sprintf("%0d", track.Resp.Attributes.TrackNumber),
-		"{Quality}", Quality,
-		"{Tag}", Tag_string,
-		"{Codec}", track.Codec,
-	).Replace(Config.SongFileFormat)
-	fmt.Println(songName)
-	filename := fmt.Sprintf("%s.m4a", forbiddenNames.ReplaceAllString(songName, "_"))
-	track.SaveName = filename
-	trackPath := filepath.Join(track.SaveDir, track.SaveName)
-	lrcFilename := fmt.Sprintf("%s.%s", forbiddenNames.ReplaceAllString(songName, "_"), Config.LrcFormat)
-
-	// Determine possible post-conversion target file (so we can skip re-download)
-	var convertedPath string
-	considerConverted := false
-	if Config.ConvertAfterDownload &&
-		Config.ConvertFormat != "" &&
-		strings.ToLower(Config.ConvertFormat) != "copy" &&
-		!Config.ConvertKeepOriginal {
-		convertedPath = strings.TrimSuffix(trackPath, filepath.Ext(trackPath)) + "." + strings.ToLower(Config.ConvertFormat)
-		considerConverted = true
-	}
-
-	//get lrc
-	var lrc string = ""
-	if Config.EmbedLrc || Config.SaveLrcFile {
-		lrcStr, err := lyrics.Get(track.Storefront, track.ID, Config.LrcType, Config.Language, Config.LrcFormat, token, mediaUserToken)
-		if err != nil {
-			fmt.Println(err)
-		} else {
-			if Config.SaveLrcFile {
-				err := writeLyrics(track.SaveDir, lrcFilename, lrcStr)
-				if err != nil {
-					fmt.Printf("Failed to write lyrics")
-				}
-			}
-			if Config.EmbedLrc {
-				lrc = lrcStr
-			}
-		}
-	}
-
-	// Existence check now considers converted output (if original was deleted)
-	existsOriginal, err := fileExists(trackPath)
-	if err != nil {
-		fmt.Println("Failed to check if track exists.")
-	}
-	if existsOriginal {
-		fmt.Println("Track already exists locally.")
-		incSuccess()
-		addOk(track.PreID, track.TaskNum)
-		return
-	}
-	if considerConverted {
-		existsConverted, err2 := fileExists(convertedPath)
-		if err2 == nil && existsConverted {
-			fmt.Println("Converted track already exists locally.")
-			incSuccess()
-			addOk(track.PreID, track.TaskNum)
-			return
-		}
-	}
-
-	if needDlAacLc {
-		acquireDownloadSlot()
-		defer releaseDownloadSlot()
-		if len(mediaUserToken) <= 50 {
-			fmt.Println("Invalid media-user-token:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name))
-			incError()
-			addError(fmt.Sprintf("[%s - %s] AAC-LC download failed: invalid media-user-token", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name))
-			return
-		}
-		_, err := runv3.Run(track.ID, trackPath, token, mediaUserToken, false, "")
-		if err != nil {
-			fmt.Println("Failed to dl aac-lc:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
-			if err.Error() == "Unavailable" {
-				incUnavailable()
-				addWarning("AAC-LC unavailable")
-				return
-			}
-			incError()
-			addFail(track.PreID, track.TaskNum)
-			addError(fmt.Sprintf("[%s - %s] AAC-LC download failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
-			return
-		}
-	} else {
-		acquireDownloadSlot()
-		defer releaseDownloadSlot()
-		trackM3u8Url, _, _, err := extractMedia(track.M3u8, false)
-		if err != nil {
-			fmt.Println("\u26A0 Failed to extract info from manifest:", err)
-			incUnavailable()
-			addFail(track.PreID, track.TaskNum)
-			addWarning(fmt.Sprintf("Manifest extract failed: %v", err))
-			return
-		}
-		//边下载边解密
-		err = runv2.Run(track.ID, trackM3u8Url, trackPath, Config)
-		if err != nil {
-			fmt.Println("Failed to run v2:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
-			incError()
-			addFail(track.PreID, track.TaskNum)
-			addError(fmt.Sprintf("[%s - %s] HLS run failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
-			return
-		}
-	}
-	tags := []string{
-		"tool=",
-		"artist=AppleMusic",
-	}
-	if Config.EmbedCover {
-		if (strings.Contains(track.PreID, "pl.") || strings.Contains(track.PreID, "ra.")) && Config.DlAlbumcoverForPlaylist {
-			track.CoverPath, err = writeCover(track.SaveDir, track.ID, track.Resp.Attributes.Artwork.URL)
-			if err != nil {
-				fmt.Println("Failed to write cover.")
-				addWarning("Embed cover failed")
-			}
-		}
-		tags = append(tags, fmt.Sprintf("cover=%s", track.CoverPath))
-	}
-	tagsString := strings.Join(tags, ":")
-	acquireTagSlot()
-	if err := runCmdTimeout(2*time.Minute, "MP4Box", "-itags", tagsString, trackPath); err != nil {
-		fmt.Printf("Embed failed %s: %v\n", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
-		incError()
-		addFail(track.PreID, track.TaskNum)
-		addError(fmt.Sprintf("[%s - %s] Tag embed failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
-		releaseTagSlot()
-		return
-	}
-	releaseTagSlot()
-	if (strings.Contains(track.PreID, "pl.") || strings.Contains(track.PreID, "ra.")) && Config.DlAlbumcoverForPlaylist {
-		if err := os.Remove(track.CoverPath); err != nil {
-			fmt.Printf("Error deleting file %s: %s\n", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), track.CoverPath)
-			incError()
-			addFail(track.PreID, track.TaskNum)
-			addError(fmt.Sprintf("[%s - %s] Delete cover failed: %s", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, track.CoverPath))
-			return
-		}
-	}
-	track.SavePath = trackPath
-	err = writeMP4Tags(track, lrc)
-	if err != nil {
-		fmt.Println("\u26A0 Failed to write tags in media:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
-		incUnavailable()
-		addFail(track.PreID, track.TaskNum)
-		addWarning(fmt.Sprintf("Write MP4 tags failed: %v", err))
-		return
-	}
-
-	// CONVERSION FEATURE hook
-	convertIfNeeded(track)
-
-	incSuccess()
-	addOk(track.PreID, track.TaskNum)
-	removeFail(track.PreID, track.TaskNum)
-}
-
-func ripStation(albumId string, token string, storefront string, mediaUserToken string) error {
-	station := task.NewStation(storefront, albumId)
-	err := station.GetResp(mediaUserToken, token, Config.Language)
-	if err != nil {
-		return err
-	}
-	fmt.Println(" -", station.Type)
-	meta := station.Resp
-
-	// Make a local mutable copy of user/runtime codec preferences to avoid concurrent writes
-	localDlAtmos := dl_atmos
-	localDlAac := dl_aac
-	var Codec string
-	if localDlAtmos {
-		Codec = "ATMOS"
-	} else if localDlAac {
-		Codec = "AAC"
-	} else {
-		Codec = "ALAC"
-	}
-	station.Codec = Codec
-	var singerFoldername string
-	if Config.ArtistFolderFormat != "" {
-		singerFoldername = strings.NewReplacer(
-			"{ArtistName}", "Apple Music Station",
-			"{ArtistId}", "",
-			"{UrlArtistName}", "Apple Music Station",
-		).Replace(Config.ArtistFolderFormat)
-		if strings.HasSuffix(singerFoldername, ".") {
-			singerFoldername = strings.ReplaceAll(singerFoldername, ".", "")
-		}
-		singerFoldername = strings.TrimSpace(singerFoldername)
-		fmt.Println(singerFoldername)
-	}
-	// 使用统一的输出根目录，不再根据 codec 分类保存目录
-	singerFolder := filepath.Join(OutputFolder, forbiddenNames.ReplaceAllString(singerFoldername, "_"))
-	if err := os.MkdirAll(singerFolder, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create singer folder '%s': %w", singerFolder, err)
-	}
-	station.SaveDir = singerFolder
-
-	playlistFolder := strings.NewReplacer(
-		"{ArtistName}", "Apple Music Station",
-		"{PlaylistName}", LimitString(station.Name),
-		"{PlaylistId}", station.ID,
-		"{Quality}", "",
-		"{Codec}", Codec,
-		"{Tag}", "",
-	).Replace(Config.PlaylistFolderFormat)
-	if strings.HasSuffix(playlistFolder, ".") {
-		playlistFolder = strings.ReplaceAll(playlistFolder, ".", "")
-	}
-	playlistFolder = strings.TrimSpace(playlistFolder)
-	playlistFolderPath := filepath.Join(singerFolder, forbiddenNames.ReplaceAllString(playlistFolder, "_"))
-	if err := os.MkdirAll(playlistFolderPath, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create playlist folder '%s': %w", playlistFolderPath, err)
-	}
-	station.SaveName = playlistFolder
-	fmt.Println(playlistFolder)
-
-	covPath, err := writeCover(playlistFolderPath, "cover", meta.Data[0].Attributes.Artwork.URL)
-	if err != nil {
-		fmt.Println("Failed to write cover.")
-	}
-	station.CoverPath = covPath
-
-	if Config.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionSquare.Video != "" {
-		fmt.Println("Found Animation Artwork.")
-
-		motionvideoUrlSquare, err := extractVideo(meta.Data[0].Attributes.EditorialVideo.MotionSquare.Video)
-		if err != nil {
-			fmt.Println("no motion video square.\n", err)
-		} else {
-			exists, err := fileExists(filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"))
-			if err != nil {
-				fmt.Println("Failed to check if animated artwork square exists.")
-			}
-			if exists {
-				fmt.Println("Animated artwork square already exists locally.")
-			} else {
-				fmt.Println("Animation Artwork Square Downloading...")
-				if err := runCmdTimeout(2*time.Minute, "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy", filepath.Join(playlistFolderPath, "square_animated_artwork.mp4")); err != nil {
-					fmt.Printf("animated artwork square dl err: %v\n", err)
-				} else {
-					fmt.Println("Animation Artwork Square Downloaded")
-				}
-			}
-		}
-
-		if Config.EmbyAnimatedArtwork {
-			if err := runCmdTimeout(1*time.Minute, "ffmpeg", "-i", filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif", filepath.Join(playlistFolderPath, "folder.jpg")); err != nil {
-				fmt.Printf("animated artwork square to gif err: %v\n", err)
-			}
-		}
-	}
-	if station.Type == "stream" {
-		incTotal()
-		if isInArray(getOk(station.ID), 1) {
-			incSuccess()
-			return nil
-		}
-		songName := strings.NewReplacer(
-			"{SongId}", station.ID,
-			"{SongNumer}", "01",
-			"{SongName}", LimitString(station.Name),
-			"{DiscNumber}", "1",
-			"{TrackNumber}", "1",
-			"{Quality}", "256Kbps",
-			"{Tag}", "",
-			"{Codec}", "AAC",
-		).Replace(Config.SongFileFormat)
-		fmt.Println(songName)
-		trackPath := filepath.Join(playlistFolderPath, fmt.Sprintf("%s.m4a", forbiddenNames.ReplaceAllString(songName, "_")))
-		exists, _ := fileExists(trackPath)
-		if exists {
-			counter.Success++
-			okDict[station.ID] = append(okDict[station.ID], 1)
-
-			fmt.Println("Radio already exists locally.")
-			return nil
-		}
-		assetsUrl, serverUrl, err := ampapi.GetStationAssetsUrlAndServerUrl(station.ID, mediaUserToken, token)
-		if err != nil {
-			fmt.Println("Failed to get station assets url.", err)
-			incError()
-			return err
-		}
-		trackM3U8 := strings.ReplaceAll(assetsUrl, "index.m3u8", "256/prog_index.m3u8")
-		keyAndUrls, _ := runv3.Run(station.ID, trackM3U8, token, mediaUserToken, true, serverUrl)
-		err = runv3.ExtMvData(keyAndUrls, trackPath, Config.MVSegmentConcurrency)
-		if err != nil {
-			fmt.Println("Failed to download station stream.", err)
-			incError()
-			return err
-		}
-		tags := []string{
-			"tool=",
-			"disk=1/1",
-			"track=1",
-			"tracknum=1/1",
-			fmt.Sprintf("artist=%s", "Apple Music Station"),
-			fmt.Sprintf("performer=%s", "Apple Music Station"),
-			fmt.Sprintf("album_artist=%s", "Apple Music Station"),
-			fmt.Sprintf("album=%s", station.Name),
-			fmt.Sprintf("title=%s", station.Name),
-		}
-		if Config.EmbedCover {
-			tags = append(tags, fmt.Sprintf("cover=%s", station.CoverPath))
-		}
-		tagsString := strings.Join(tags, ":")
-		if err := runCmdTimeout(2*time.Minute, "MP4Box", "-itags", tagsString, trackPath); err != nil {
-			fmt.Printf("Embed failed: %v\n", err)
-		}
-		incSuccess()
-		addOk(station.ID, 1)
-		return nil
-	}
-
-	for i := range station.Tracks {
-		station.Tracks[i].CoverPath = covPath
-		station.Tracks[i].SaveDir = playlistFolderPath
-		station.Tracks[i].Codec = Codec
-	}
-
-	trackTotal := len(station.Tracks)
-	arr := make([]int, trackTotal)
-	for i := 0; i < trackTotal; i++ {
-		arr[i] = i + 1
-	}
-	var selected []int
-	if retryOnly {
-		selected = getFail(station.ID)
-		if len(selected) == 0 {
-			selected = arr
-		}
-	} else {
-		selected = arr
-	}
-	// Worker pool for station tracks
-	type dlJob struct {
-		idx int // 1-based index in station.Tracks
-		seq int // sequential TaskNum within toProcess
-	}
-	var toProcess []int
-	doneList := getOk(station.ID)
-	for i := range station.Tracks {
-		num := i + 1
-		if isInArray(doneList, num) {
-			incTotal()
-			incSuccess()
-			continue
-		}
-		if isInArray(selected, num) {
-			toProcess = append(toProcess, num)
-		}
-	}
-	if len(toProcess) == 0 {
-		return nil
-	}
-	var wg sync.WaitGroup
-	workerCount := DownloadConcurrency
-	if workerCount > len(toProcess) {
-		workerCount = len(toProcess)
-	}
-	jobs := make(chan dlJob, workerCount)
-	wg.Add(workerCount)
-	for w := 0; w < workerCount; w++ {
-		go func() {
-			defer wg.Done()
-			for job := range jobs {
-				tk := &station.Tracks[job.idx-1]
-				tk.TaskNum = job.seq
-				tk.TaskTotal = len(toProcess)
-				log.Printf("Start station track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
-				ripTrack(tk, token, mediaUserToken)
-				log.Printf("Done  station track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
-			}
-		}()
-	}
-	for seq, idx := range toProcess {
-		tr := station.Tracks[idx-1]
-		log.Printf("Enqueue station track %d/%d: %s - %s", seq+1, len(toProcess), tr.Resp.Attributes.ArtistName, tr.Resp.Attributes.Name)
-		jobs <- dlJob{idx: idx, seq: seq + 1}
-	}
-	close(jobs)
-	wg.Wait()
-	return nil
-}
-
-func ripAlbum(albumId string, token string, storefront string, mediaUserToken string, urlArg_i string) error {
-	album := task.NewAlbum(storefront, albumId)
-	err := album.GetResp(token, Config.Language)
-	if err != nil {
-		fmt.Println("Failed to get album response.")
-		return err
-	}
-	meta := album.Resp
-	if debug_mode {
-		fmt.Println(meta.Data[0].Attributes.ArtistName)
-		fmt.Println(meta.Data[0].Attributes.Name)
-
-		for trackNum, track := range meta.Data[0].Relationships.Tracks.Data {
-			trackNum++
-			fmt.Printf("\nTrack %d of %d:\n", trackNum, len(meta.Data[0].Relationships.Tracks.Data))
-			fmt.Printf("%02d. %s\n", trackNum, track.Attributes.Name)
-
-			manifest, err := ampapi.GetSongResp(storefront, track.ID, album.Language, token)
-			if err != nil {
-				fmt.Printf("Failed to get manifest for track %d: %v\n", trackNum, err)
-				continue
-			}
-
-			var m3u8Url string
-			if manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls != "" {
-				m3u8Url = manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls
-			}
-			needCheck := false
-			if Config.GetM3u8Mode == "all" {
-				needCheck = true
-			} else if Config.GetM3u8Mode == "hires" && contains(track.Attributes.AudioTraits, "hi-res-lossless") {
-				needCheck = true
-			}
-			if needCheck {
-				fullM3u8Url, err := checkM3u8(track.ID, "song")
-				if err == nil && strings.HasSuffix(fullM3u8Url, ".m3u8") {
-					m3u8Url = fullM3u8Url
-				} else {
-					fmt.Println("Failed to get best quality m3u8 from device m3u8 port, will use m3u8 from Web API")
-				}
-			}
-
-			_, _, _, err = extractMedia(m3u8Url, true)
-			if err != nil {
-				fmt.Printf("Failed to extract quality info for track %d: %v\n", trackNum, err)
-				continue
-			}
-		}
-		return nil
-	}
-	// Local mutable copy of user/runtime codec preferences
-	localDlAtmos := dl_atmos
-	localDlAac := dl_aac
-	var Codec string
-	if localDlAtmos {
-		Codec = "ATMOS"
-	} else if localDlAac {
-		Codec = "AAC"
-	} else {
-		Codec = "ALAC"
-	}
-	album.Codec = Codec
-	var singerFoldername string
-	if Config.ArtistFolderFormat != "" {
-		if len(meta.Data[0].Relationships.Artists.Data) > 0 {
-			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistName}", LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistId}", meta.Data[0].Relationships.Artists.Data[0].ID,
-			).Replace(Config.ArtistFolderFormat)
-		} else {
-			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistName}", LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistId}", "",
-			).Replace(Config.ArtistFolderFormat)
-		}
-		if strings.HasSuffix(singerFoldername, ".") {
-			singerFoldername = strings.ReplaceAll(singerFoldername, ".", "")
-		}
-		singerFoldername = strings.TrimSpace(singerFoldername)
-		fmt.Println(singerFoldername)
-	}
-	var singerFolder string
-	var Quality string
-	if strings.Contains(Config.AlbumFolderFormat, "Quality") {
-		if dl_atmos {
-			Quality = fmt.Sprintf("%dKbps", Config.AtmosMax-2000)
-		} else if dl_aac && Config.AacType == "aac-lc" {
-			Quality = "256Kbps"
-		} else {
-			manifest1, err := ampapi.GetSongResp(storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, album.Language, token)
-			if err != nil {
-				fmt.Println("Failed to get manifest.\n", err)
-			} else {
-				if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
-					Codec = "AAC"
-					Quality = "256Kbps"
-				} else {
-					needCheck := false
-
-					if Config.GetM3u8Mode == "all" {
-						needCheck = true
-					} else if Config.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
-						needCheck = true
-					}
-					var EnhancedHls_m3u8 string
-					if needCheck {
-						EnhancedHls_m3u8, _ = checkM3u8(meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
-						if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
-							manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
-						}
-					}
-					var codecs string
-					_, Quality, codecs, err = extractMedia(manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
-					if err != nil {
-						fmt.Println("Failed to extract quality from manifest.\n", err)
-					}
-					switch codecs {
-					case "ec-3", "ac-3":
-						localDlAtmos = true
-						localDlAac = false
-					case "mp4a.40.2":
-						localDlAtmos = false
-						localDlAac = true
-					default:
-						localDlAtmos = false
-						localDlAac = false
-					}
-				}
-			}
-		}
-	}
-	// Ensure codec-priority routing applies even without Quality placeholder
-	if !localDlAtmos && !localDlAac {
-		manifest1, err := ampapi.GetSongResp(storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, album.Language, token)
-		if err == nil {
-			if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
-				// fallback to AAC if no EnhancedHls
-				localDlAtmos = false
-				localDlAac = true
-			} else {
-				needCheck := false
-				if Config.GetM3u8Mode == "all" {
-					needCheck = true
-				} else if Config.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
-					needCheck = true
-				}
-				var EnhancedHls_m3u8 string
-				if needCheck {
-					EnhancedHls_m3u8, _ = checkM3u8(meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
-					if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
-						manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
-					}
-				}
-				var codecs string
-				_, _, codecs, err = extractMedia(manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
-				if err == nil {
-					switch codecs {
-					case "ec-3", "ac-3":
-						localDlAtmos = true
-						localDlAac = false
-					case "mp4a.40.2":
-						localDlAtmos = false
-						localDlAac = true
-					default:
-						localDlAtmos = false
-						localDlAac = false
-					}
-				}
-			}
-		}
-	}
-	// 使用统一的输出根目录，不再根据 codec 分类保存目录
-	singerFolder = filepath.Join(OutputFolder, forbiddenNames.ReplaceAllString(singerFoldername, "_"))
-	if err := os.MkdirAll(singerFolder, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create singer folder '%s': %w", singerFolder, err)
-	}
-	album.SaveDir = singerFolder
-	stringsToJoin := []string{}
-	if meta.Data[0].Attributes.IsAppleDigitalMaster || meta.Data[0].Attributes.IsMasteredForItunes {
-		if Config.AppleMasterChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.AppleMasterChoice)
-		}
-	}
-	if meta.Data[0].Attributes.ContentRating == "explicit" {
-		if Config.ExplicitChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.ExplicitChoice)
-		}
-	}
-	if meta.Data[0].Attributes.ContentRating == "clean" {
-		if Config.CleanChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.CleanChoice)
-		}
-	}
-	Tag_string := strings.Join(stringsToJoin, " ")
-	var albumFolderName string
-	albumFolderName = strings.NewReplacer(
-		"{ReleaseDate}", meta.Data[0].Attributes.ReleaseDate,
-		"{ReleaseYear}", meta.Data[0].Attributes.ReleaseDate[:4],
-		"{ArtistName}", LimitString(meta.Data[0].Attributes.ArtistName),
-		"{AlbumName}", LimitString(meta.Data[0].Attributes.Name),
-		"{UPC}", meta.Data[0].Attributes.Upc,
-		"{RecordLabel}", meta.Data[0].Attributes.RecordLabel,
-		"{Copyright}", meta.Data[0].Attributes.Copyright,
-		"{AlbumId}", albumId,
-		"{Quality}", Quality,
-		"{Codec}", Codec,
-		"{Tag}", Tag_string,
-	).Replace(Config.AlbumFolderFormat)
-
-	if strings.HasSuffix(albumFolderName, ".") {
-		albumFolderName = strings.ReplaceAll(albumFolderName, ".", "")
-	}
-	albumFolderName = strings.TrimSpace(albumFolderName)
-	albumFolderPath := filepath.Join(singerFolder, forbiddenNames.ReplaceAllString(albumFolderName, "_"))
-	if err := os.MkdirAll(albumFolderPath, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create album folder '%s': %w", albumFolderPath, err)
-	}
-	album.SaveName = albumFolderName
-	fmt.Println(albumFolderName)
-	if Config.SaveArtistCover && len(meta.Data[0].Relationships.Artists.Data) > 0 {
-		if meta.Data[0].Relationships.Artists.Data[0].Attributes.Artwork.Url != "" {
-			_, err = writeCover(singerFolder, "folder", meta.Data[0].Relationships.Artists.Data[0].Attributes.Artwork.Url)
-			if err != nil {
-				fmt.Println("Failed to write artist cover.")
-			}
-		}
-	}
-	covPath, err := writeCover(albumFolderPath, "cover", meta.Data[0].Attributes.Artwork.URL)
-	if err != nil {
-		fmt.Println("Failed to write cover.")
-	}
-	if Config.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video != "" {
-		fmt.Println("Found Animation Artwork.")
-
-		motionvideoUrlSquare, err := extractVideo(meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video)
-		if err != nil {
-			fmt.Println("no motion video square.\n", err)
-		} else {
-			exists, err := fileExists(filepath.Join(albumFolderPath, "square_animated_artwork.mp4"))
-			if err != nil {
-				fmt.Println("Failed to check if animated artwork square exists.")
-			}
-			if exists {
-				fmt.Println("Animated artwork square already exists locally.")
-			} else {
-				fmt.Println("Animation Artwork Square Downloading...")
-				if err := runCmdTimeout(2*time.Minute, "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy", filepath.Join(albumFolderPath, "square_animated_artwork.mp4")); err != nil {
-					fmt.Printf("animated artwork square dl err: %v\n", err)
-				} else {
-					fmt.Println("Animation Artwork Square Downloaded")
-				}
-			}
-		}
-
-		if Config.EmbyAnimatedArtwork {
-			if err := runCmdTimeout(1*time.Minute, "ffmpeg", "-i", filepath.Join(albumFolderPath, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif", filepath.Join(albumFolderPath, "folder.jpg")); err != nil {
-				fmt.Printf("animated artwork square to gif err: %v\n", err)
-			}
-		}
-
-		motionvideoUrlTall, err := extractVideo(meta.Data[0].Attributes.EditorialVideo.MotionDetailTall.Video)
-		if err != nil {
-			fmt.Println("no motion video tall.\n", err)
-		} else {
-			exists, err := fileExists(filepath.Join(albumFolderPath, "tall_animated_artwork.mp4"))
-			if err != nil {
-				fmt.Println("Failed to check if animated artwork tall exists.")
-			}
-			if exists {
-				fmt.Println("Animated artwork tall already exists locally.")
-			} else {
-				fmt.Println("Animation Artwork Tall Downloading...")
-				if err := runCmdTimeout(2*time.Minute, "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlTall, "-c", "copy", filepath.Join(albumFolderPath, "tall_animated_artwork.mp4")); err != nil {
-					fmt.Printf("animated artwork tall dl err: %v\n", err)
-				} else {
-					fmt.Println("Animation Artwork Tall Downloaded")
-				}
-			}
-		}
-	}
-	for i := range album.Tracks {
-		album.Tracks[i].CoverPath = covPath
-		album.Tracks[i].SaveDir = albumFolderPath
-		album.Tracks[i].Codec = Codec
-	}
-	trackTotal := len(meta.Data[0].Relationships.Tracks.Data)
-	arr := make([]int, trackTotal)
-	for i := 0; i < trackTotal; i++ {
-		arr[i] = i + 1
-	}
-
-	if dl_song {
-		if urlArg_i == "" {
-		} else {
-			for i := range album.Tracks {
-				if urlArg_i == album.Tracks[i].ID {
-					ripTrack(&album.Tracks[i], token, mediaUserToken)
-					return nil
-				}
-			}
-		}
-		return nil
-	}
-	var selected []int
-	if retryOnly {
-		selected = getFail(albumId)
-		if len(selected) == 0 {
-			selected = arr
-		}
-	} else if !dl_select {
-		selected = arr
-	} else {
-		selected = album.ShowSelect()
-	}
-	// Worker pool for album tracks
-	type dlJob struct {
-		idx int // 1-based index in album.Tracks
-		seq int // sequential TaskNum within toProcess
-	}
-	var toProcess []int
-	doneList := getOk(albumId)
-	for i := range album.Tracks {
-		num := i + 1
-		if isInArray(doneList, num) {
-			incTotal()
-			incSuccess()
-			continue
-		}
-		if isInArray(selected, num) {
-			toProcess = append(toProcess, num)
-		}
-	}
-	if len(toProcess) == 0 {
-		return nil
-	}
-	var wg sync.WaitGroup
-	workerCount := DownloadConcurrency
-	if workerCount > len(toProcess) {
-		workerCount = len(toProcess)
-	}
-	jobs := make(chan dlJob, workerCount)
-	wg.Add(workerCount)
-	for w := 0; w < workerCount; w++ {
-		go func() {
-			defer wg.Done()
-			for job := range jobs {
-				tk := &album.Tracks[job.idx-1]
-				tk.TaskNum = job.seq
-				tk.TaskTotal = len(toProcess)
-				log.Printf("Start album track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
-				ripTrack(tk, token, mediaUserToken)
-				log.Printf("Done  album track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
-			}
-		}()
-	}
-	for seq, idx := range toProcess {
-		tr := album.Tracks[idx-1]
-		log.Printf("Enqueue album track %d/%d: %s - %s", seq+1, len(toProcess), tr.Resp.Attributes.ArtistName, tr.Resp.Attributes.Name)
-		jobs <- dlJob{idx: idx, seq: seq + 1}
-	}
-	close(jobs)
-	wg.Wait()
-	return nil
-
-}
-func ripPlaylist(playlistId string, token string, storefront string, mediaUserToken string) error {
-	playlist := task.NewPlaylist(storefront, playlistId)
-	err := playlist.GetResp(token, Config.Language)
-	if err != nil {
-		fmt.Println("Failed to get playlist response.")
-		return err
-	}
-	meta := playlist.Resp
-	if debug_mode {
-		fmt.Println(meta.Data[0].Attributes.ArtistName)
-		fmt.Println(meta.Data[0].Attributes.Name)
-
-		for trackNum, track := range meta.Data[0].Relationships.Tracks.Data {
-			trackNum++
-			fmt.Printf("\nTrack %d of %d:\n", trackNum, len(meta.Data[0].Relationships.Tracks.Data))
-			fmt.Printf("%02d. %s\n", trackNum, track.Attributes.Name)
-
-			manifest, err := ampapi.GetSongResp(storefront, track.ID, playlist.Language, token)
-			if err != nil {
-				fmt.Printf("Failed to get manifest for track %d: %v\n", trackNum, err)
-				continue
-			}
-
-			var m3u8Url string
-			if manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls != "" {
-				m3u8Url = manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls
-			}
-			needCheck := false
-			if Config.GetM3u8Mode == "all" {
-				needCheck = true
-			} else if Config.GetM3u8Mode == "hires" && contains(track.Attributes.AudioTraits, "hi-res-lossless") {
-				needCheck = true
-			}
-			if needCheck {
-				fullM3u8Url, err := checkM3u8(track.ID, "song")
-				if err == nil && strings.HasSuffix(fullM3u8Url, ".m3u8") {
-					m3u8Url = fullM3u8Url
-				} else {
-					fmt.Println("Failed to get best quality m3u8 from device m3u8 port, will use m3u8 from Web API")
-				}
-			}
-
-			_, _, _, err = extractMedia(m3u8Url, true)
-			if err != nil {
-				fmt.Printf("Failed to extract quality info for track %d: %v\n", trackNum, err)
-				continue
-			}
-		}
-		return nil
-	}
-	// Local mutable copy of user/runtime codec preferences
-	localDlAtmos := dl_atmos
-	localDlAac := dl_aac
-	var Codec string
-	if localDlAtmos {
-		Codec = "ATMOS"
-	} else if localDlAac {
-		Codec = "AAC"
-	} else {
-		Codec = "ALAC"
-	}
-	playlist.Codec = Codec
-	var singerFoldername string
-	if Config.ArtistFolderFormat != "" {
-		singerFoldername = strings.NewReplacer(
-			"{ArtistName}", "Apple Music",
-			"{ArtistId}", "",
-			"{UrlArtistName}", "Apple Music",
-		).Replace(Config.ArtistFolderFormat)
-		if strings.HasSuffix(singerFoldername, ".") {
-			singerFoldername = strings.ReplaceAll(singerFoldername, ".", "")
-		}
-		singerFoldername = strings.TrimSpace(singerFoldername)
-		fmt.Println(singerFoldername)
-	}
-	var singerFolder string
-
-	var Quality string
-	if strings.Contains(Config.AlbumFolderFormat, "Quality") {
-		if dl_atmos {
-			Quality = fmt.Sprintf("%dKbps", Config.AtmosMax-2000)
-		} else if dl_aac && Config.AacType == "aac-lc" {
-			Quality = "256Kbps"
-		} else {
-			manifest1, err := ampapi.GetSongResp(storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, playlist.Language, token)
-			if err != nil {
-				fmt.Println("Failed to get manifest.\n", err)
-			} else {
-				if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
-					Codec = "AAC"
-					Quality = "256Kbps"
-				} else {
-					needCheck := false
-
-					if Config.GetM3u8Mode == "all" {
-						needCheck = true
-					} else if Config.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
-						needCheck = true
-					}
-					var EnhancedHls_m3u8 string
-					if needCheck {
-						EnhancedHls_m3u8, _ = checkM3u8(meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
-						if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
-							manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
-						}
-					}
-					var codecs string
-					_, Quality, codecs, err = extractMedia(manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
-					if err != nil {
-						fmt.Println("Failed to extract quality from manifest.\n", err)
-					}
-					// Resolve flags from selected codec (priority-driven)
-					switch codecs {
-					case "ec-3", "ac-3":
-						localDlAtmos = true
-						localDlAac = false
-					case "mp4a.40.2":
-						localDlAtmos = false
-						localDlAac = true
-					default:
-						localDlAtmos = false
-						localDlAac = false
-					}
-				}
-			}
-		}
-	}
-	// Ensure codec-priority routing applies even without Quality placeholder
-	if !localDlAtmos && !localDlAac {
-		manifest1, err := ampapi.GetSongResp(storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, playlist.Language, token)
-		if err == nil {
-			if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
-				// fallback to AAC if no EnhancedHls
-				localDlAtmos = false
-				localDlAac = true
-			} else {
-				needCheck := false
-				if Config.GetM3u8Mode == "all" {
-					needCheck = true
-				} else if Config.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
-					needCheck = true
-				}
-				var EnhancedHls_m3u8 string
-				if needCheck {
-					EnhancedHls_m3u8, _ = checkM3u8(meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
-					if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
-						manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
-					}
-				}
-				var codecs string
-				_, _, codecs, err = extractMedia(manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
-				if err == nil {
-					switch codecs {
-					case "ec-3", "ac-3":
-						localDlAtmos = true
-						localDlAac = false
-					case "mp4a.40.2":
-						localDlAtmos = false
-						localDlAac = true
-					default:
-						localDlAtmos = false
-						localDlAac = false
-					}
-				}
-			}
-		}
-	}
-	// 使用统一的输出根目录，不再根据 codec 分类保存目录
-	singerFolder = filepath.Join(OutputFolder, forbiddenNames.ReplaceAllString(singerFoldername, "_"))
-	if err := os.MkdirAll(singerFolder, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create singer folder '%s': %w", singerFolder, err)
-	}
-	playlist.SaveDir = singerFolder
-	stringsToJoin := []string{}
-	if meta.Data[0].Attributes.IsAppleDigitalMaster || meta.Data[0].Attributes.IsMasteredForItunes {
-		if Config.AppleMasterChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.AppleMasterChoice)
-		}
-	}
-	if meta.Data[0].Attributes.ContentRating == "explicit" {
-		if Config.ExplicitChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.ExplicitChoice)
-		}
-	}
-	if meta.Data[0].Attributes.ContentRating == "clean" {
-		if Config.CleanChoice != "" {
-			stringsToJoin = append(stringsToJoin, Config.CleanChoice)
-		}
-	}
-	Tag_string := strings.Join(stringsToJoin, " ")
-	playlistFolder := strings.NewReplacer(
-		"{ArtistName}", "Apple Music",
-		"{PlaylistName}", LimitString(meta.Data[0].Attributes.Name),
-		"{PlaylistId}", playlistId,
-		"{Quality}", Quality,
-		"{Codec}", Codec,
-		"{Tag}", Tag_string,
-	).Replace(Config.PlaylistFolderFormat)
-	if strings.HasSuffix(playlistFolder, ".") {
-		playlistFolder = strings.ReplaceAll(playlistFolder, ".", "")
-	}
-	playlistFolder = strings.TrimSpace(playlistFolder)
-	playlistFolderPath := filepath.Join(singerFolder, forbiddenNames.ReplaceAllString(playlistFolder, "_"))
-	if err := os.MkdirAll(playlistFolderPath, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create playlist folder '%s': %w", playlistFolderPath, err)
-	}
-	playlist.SaveName = playlistFolder
-	fmt.Println(playlistFolder)
-	covPath, err := writeCover(playlistFolderPath, "cover", meta.Data[0].Attributes.Artwork.URL)
-	if err != nil {
-		fmt.Println("Failed to write cover.")
-	}
-
-	for i := range playlist.Tracks {
-		playlist.Tracks[i].CoverPath = covPath
-		playlist.Tracks[i].SaveDir = playlistFolderPath
-		playlist.Tracks[i].Codec = Codec
-	}
-
-	if Config.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video != "" {
-		fmt.Println("Found Animation Artwork.")
-
-		motionvideoUrlSquare, err := extractVideo(meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video)
-		if err != nil {
-			fmt.Println("no motion video square.\n", err)
-		} else {
-			exists, err := fileExists(filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"))
-			if err != nil {
-				fmt.Println("Failed to check if animated artwork square exists.")
-			}
-			if exists {
-				fmt.Println("Animated artwork square already exists locally.")
-			} else {
-				fmt.Println("Animation Artwork Square Downloading...")
-				if err := runCmdTimeout(2*time.Minute, "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy", filepath.Join(playlistFolderPath, "square_animated_artwork.mp4")); err != nil {
-					fmt.Printf("animated artwork square dl err: %v\n", err)
-				} else {
-					fmt.Println("Animation Artwork Square Downloaded")
-				}
-			}
-		}
-
-		if Config.EmbyAnimatedArtwork {
-			if err := runCmdTimeout(1*time.Minute, "ffmpeg", "-i", filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif", filepath.Join(playlistFolderPath, "folder.jpg")); err != nil {
-				fmt.Printf("animated artwork square to gif err: %v\n", err)
-			}
-		}
-
-		motionvideoUrlTall, err := extractVideo(meta.Data[0].Attributes.EditorialVideo.MotionDetailTall.Video)
-		if err != nil {
-			fmt.Println("no motion video tall.\n", err)
-		} else {
-			exists, err := fileExists(filepath.Join(playlistFolderPath, "tall_animated_artwork.mp4"))
-			if err != nil {
-				fmt.Println("Failed to check if animated artwork tall exists.")
-			}
-			if exists {
-				fmt.Println("Animated artwork tall already exists locally.")
-			} else {
-				fmt.Println("Animation Artwork Tall Downloading...")
-				if err := runCmdTimeout(2*time.Minute, "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlTall, "-c", "copy", filepath.Join(playlistFolderPath, "tall_animated_artwork.mp4")); err != nil {
-					fmt.Printf("animated artwork tall dl err: %v\n", err)
-				} else {
-					fmt.Println("Animation Artwork Tall Downloaded")
-				}
-			}
-		}
-	}
-	trackTotal := len(meta.Data[0].Relationships.Tracks.Data)
-	arr := make([]int, trackTotal)
-	for i := 0; i < trackTotal; i++ {
-		arr[i] = i + 1
-	}
-	var selected []int
-	if retryOnly {
-		selected = getFail(playlistId)
-		if len(selected) == 0 {
-			selected = arr
-		}
-	} else if !dl_select {
-		selected = arr
-	} else {
-		selected = playlist.ShowSelect()
-	}
-	// Worker pool for playlist tracks
-	type dlJob struct {
-		idx int
-		seq int
-	}
-	toProcess := []int{}
-	for i := range playlist.Tracks {
-		num := i + 1
-		if isInArray(getOk(playlistId), num) {
-			incTotal()
-			incSuccess()
-			continue
-		}
-		if isInArray(selected, num) {
-			toProcess = append(toProcess, num)
-		}
-	}
-	if len(toProcess) == 0 {
-		return nil
-	}
-	workerCount := DownloadConcurrency
-	if workerCount <= 0 {
-		workerCount = 1
-	}
-	if workerCount > len(toProcess) {
-		workerCount = len(toProcess)
-	}
-	jobs := make(chan dlJob, workerCount)
-	var wg sync.WaitGroup
-	wg.Add(workerCount)
-	for w := 0; w < workerCount; w++ {
-		go func() {
-			defer wg.Done()
-			for job := range jobs {
-				tk := &playlist.Tracks[job.idx-1]
-				tk.TaskNum = job.seq
-				tk.TaskTotal = len(toProcess)
-				log.Printf("Start playlist track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
-				ripTrack(tk, token, mediaUserToken)
-				log.Printf("Done  playlist track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
-			}
-		}()
-	}
-	for seq, idx := range toProcess {
-		tr := playlist.Tracks[idx-1]
-		log.Printf("Enqueue playlist track %d/%d: %s - %s", seq+1, len(toProcess), tr.Resp.Attributes.ArtistName, tr.Resp.Attributes.Name)
-		jobs <- dlJob{idx: idx, seq: seq + 1}
-	}
-	close(jobs)
-	wg.Wait()
-	return nil
-}
-
-func mvDownloader(adamID string, saveDir string, token string, storefront string, mediaUserToken string, track *task.Track) error {
-	MVInfo, err := ampapi.GetMusicVideoResp(storefront, adamID, Config.Language, token)
-	if err != nil {
-		fmt.Println("\u26A0 Failed to get MV manifest:", err)
-		return nil
-	}
-
-	if strings.HasSuffix(saveDir, ".") {
-		saveDir = strings.ReplaceAll(saveDir, ".", "")
-	}
-	saveDir = strings.TrimSpace(saveDir)
-
-	vidPath := filepath.Join(saveDir, fmt.Sprintf("%s_vid.mp4", adamID))
-	audPath := filepath.Join(saveDir, fmt.Sprintf("%s_aud.mp4", adamID))
-	mvSaveName := fmt.Sprintf("%s (%s)", MVInfo.Data[0].Attributes.Name, adamID)
-	if track != nil {
-		mvSaveName = fmt.Sprintf("%02d. %s", track.TaskNum, MVInfo.Data[0].Attributes.Name)
-	}
-
-	mvOutPath := filepath.Join(saveDir, fmt.Sprintf("%s.mp4", forbiddenNames.ReplaceAllString(mvSaveName, "_")))
-
-	fmt.Println(MVInfo.Data[0].Attributes.Name)
-
-	exists, _ := fileExists(mvOutPath)
-	if exists {
-		fmt.Println("MV already exists locally.")
-		return nil
-	}
-
-	mvm3u8url, _, _, _ := runv3.GetWebplayback(adamID, token, mediaUserToken, true)
-	if mvm3u8url == "" {
-		return errors.New("media-user-token may wrong or expired")
-	}
-
-	if err := os.MkdirAll(saveDir, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create saveDir '%s': %w", saveDir, err)
-	}
-	videom3u8url, _ := extractVideo(mvm3u8url)
-	videokeyAndUrls, _ := runv3.Run(adamID, videom3u8url, token, mediaUserToken, true, "")
-	_ = runv3.ExtMvData(videokeyAndUrls, vidPath, Config.MVSegmentConcurrency)
-	defer os.Remove(vidPath)
-	audiom3u8url, _ := extractMvAudio(mvm3u8url)
-	audiokeyAndUrls, _ := runv3.Run(adamID, audiom3u8url, token, mediaUserToken, true, "")
-	_ = runv3.ExtMvData(audiokeyAndUrls, audPath, Config.MVSegmentConcurrency)
-	defer os.Remove(audPath)
-
-	tags := []string{
-		"tool=",
-		fmt.Sprintf("artist=%s", MVInfo.Data[0].Attributes.ArtistName),
-		fmt.Sprintf("title=%s", MVInfo.Data[0].Attributes.Name),
-		fmt.Sprintf("genre=%s", MVInfo.Data[0].Attributes.GenreNames[0]),
-		fmt.Sprintf("created=%s", MVInfo.Data[0].Attributes.ReleaseDate),
-		fmt.Sprintf("ISRC=%s", MVInfo.Data[0].Attributes.Isrc),
-	}
-
-	switch MVInfo.Data[0].Attributes.ContentRating {
-	case "explicit":
-		tags = append(tags, "rating=1")
-	case "clean":
-		tags = append(tags, "rating=2")
-	default:
-		tags = append(tags, "rating=0")
-	}
-
-	if track != nil {
-		if track.PreType == "playlists" && !Config.UseSongInfoForPlaylist {
-			tags = append(tags, "disk=1/1")
-			tags = append(tags, fmt.Sprintf("album=%s", track.PlaylistData.Attributes.Name))
-			tags = append(tags, fmt.Sprintf("track=%d", track.TaskNum))
-			tags = append(tags, fmt.Sprintf("tracknum=%d/%d", track.TaskNum, track.TaskTotal))
-			tags = append(tags, fmt.Sprintf("album_artist=%s", track.PlaylistData.Attributes.ArtistName))
-			tags = append(tags, fmt.Sprintf("performer=%s", track.Resp.Attributes.ArtistName))
-		} else if track.PreType == "playlists" && Config.UseSongInfoForPlaylist {
-			tags = append(tags, fmt.Sprintf("album=%s", track.AlbumData.Attributes.Name))
-			tags = append(tags, fmt.Sprintf("disk=%d/%d", track.Resp.Attributes.DiscNumber, track.DiscTotal))
-			tags = append(tags, fmt.Sprintf("track=%d", track.Resp.Attributes.TrackNumber))
-			tags = append(tags, fmt.Sprintf("tracknum=%d/%d", track.Resp.Attributes.TrackNumber, track.AlbumData.Attributes.TrackCount))
-			tags = append(tags, fmt.Sprintf("album_artist=%s", track.AlbumData.Attributes.ArtistName))
-			tags = append(tags, fmt.Sprintf("performer=%s", track.Resp.Attributes.ArtistName))
-			tags = append(tags, fmt.Sprintf("copyright=%s", track.AlbumData.Attributes.Copyright))
-			tags = append(tags, fmt.Sprintf("UPC=%s", track.AlbumData.Attributes.Upc))
-		} else {
-			tags = append(tags, fmt.Sprintf("album=%s", track.AlbumData.Attributes.Name))
-			tags = append(tags, fmt.Sprintf("disk=%d/%d", track.Resp.Attributes.DiscNumber, track.DiscTotal))
-			tags = append(tags, fmt.Sprintf("track=%d", track.Resp.Attributes.TrackNumber))
-			tags = append(tags, fmt.Sprintf("tracknum=%d/%d", track.Resp.Attributes.TrackNumber, track.AlbumData.Attributes.TrackCount))
-			tags = append(tags, fmt.Sprintf("album_artist=%s", track.AlbumData.Attributes.ArtistName))
-			tags = append(tags, fmt.Sprintf("performer=%s", track.Resp.Attributes.ArtistName))
-			tags = append(tags, fmt.Sprintf("copyright=%s", track.AlbumData.Attributes.Copyright))
-			tags = append(tags, fmt.Sprintf("UPC=%s", track.AlbumData.Attributes.Upc))
-		}
-	} else {
-		tags = append(tags, fmt.Sprintf("album=%s", MVInfo.Data[0].Attributes.AlbumName))
-		tags = append(tags, fmt.Sprintf("disk=%d", MVInfo.Data[0].Attributes.DiscNumber))
-		tags = append(tags, fmt.Sprintf("track=%d", MVInfo.Data[0].Attributes.TrackNumber))
-		tags = append(tags, fmt.Sprintf("tracknum=%d", MVInfo.Data[0].Attributes.TrackNumber))
-		tags = append(tags, fmt.Sprintf("performer=%s", MVInfo.Data[0].Attributes.ArtistName))
-	}
-
-	var covPath string
-	if true {
-		thumbURL := MVInfo.Data[0].Attributes.Artwork.URL
-		baseThumbName := forbiddenNames.ReplaceAllString(mvSaveName, "_") + "_thumbnail"
-		covPath, err = writeCover(saveDir, baseThumbName, thumbURL)
-		if err != nil {
-			fmt.Println("Failed to save MV thumbnail:", err)
-		} else {
-			tags = append(tags, fmt.Sprintf("cover=%s", covPath))
-		}
-	}
-	defer os.Remove(covPath)
-
-	tagsString := strings.Join(tags, ":")
-	muxCmdArgs := []string{"-itags", tagsString, "-quiet", "-add", vidPath, "-add", audPath, "-keep-utc", "-new", mvOutPath}
-	fmt.Printf("MV Remuxing...")
-	acquireTagSlot()
-	if err := runCmdTimeout(30*time.Minute, "MP4Box", muxCmdArgs...); err != nil {
-		fmt.Printf("MV mux failed: %v\n", err)
-		releaseTagSlot()
-		return err
-	}
-	releaseTagSlot()
-	fmt.Printf("\\rMV Remuxed.   \n")
-	return nil
-}
-
-func extractMvAudio(c string) (string, error) {
-	MediaUrl, err := url.Parse(c)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := httpClient.Get(c)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(resp.Status)
-	}
-
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, io.LimitReader(resp.Body, 2<<20))
-	if err != nil {
-		return "", err
-	}
-	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(buf.Bytes()), true)
-	if err != nil || listType != m3u8.MASTER {
-		return "", errors.New("m3u8 not of media type")
-	}
-
-	audio := from.(*m3u8.MasterPlaylist)
-
-	var audioPriority = []string{"audio-atmos", "audio-ac3", "audio-stereo-256"}
-	switch Config.MVAudioType {
-	case "ac3":
-		audioPriority = []string{"audio-ac3", "audio-stereo-256"}
-	case "aac":
-		audioPriority = []string{"audio-stereo-256"}
-	}
-
-	re := regexp.MustCompile(`_gr(\d+)_`)
-
-	type AudioStream struct {
-		URL     string
-		Rank    int
-		GroupID string
-	}
-	var audioStreams []AudioStream
-
-	for _, variant := range audio.Variants {
-		for _, audiov := range variant.Alternatives {
-			if audiov.URI != "" {
-				for _, priority := range audioPriority {
-					if audiov.GroupId == priority {
-						matches := re.FindStringSubmatch(audiov.URI)
-						if len(matches) == 2 {
-							var rank int
-							fmt.Sscanf(matches[1], "%d", &rank)
-							streamUrl, _ := MediaUrl.Parse(audiov.URI)
-							audioStreams = append(audioStreams, AudioStream{
-								URL:     streamUrl.String(),
-								Rank:    rank,
-								GroupID: audiov.GroupId,
-							})
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if len(audioStreams) == 0 {
-		return "", errors.New("no suitable audio stream found")
-	}
-
-	sort.Slice(audioStreams, func(i, j int) bool {
-		return audioStreams[i].Rank > audioStreams[j].Rank
-	})
-	fmt.Println("Audio: " + audioStreams[0].GroupID)
-	return audioStreams[0].URL, nil
-}
-
-func checkM3u8(b string, f string) (string, error) {
-	var EnhancedHls string
-	if Config.GetM3u8FromDevice {
-		adamID := b
-		conn, err := net.Dial("tcp", Config.GetM3u8Port)
-		if err != nil {
-			fmt.Println("Error connecting to device:", err)
-			return "none", err
-		}
-		defer conn.Close()
-		if f == "song" {
-			fmt.Println("Connected to device")
-		}
-
-		adamIDBuffer := []byte(adamID)
-		lengthBuffer := []byte{byte(len(adamIDBuffer))}
-
-		_, err = conn.Write(lengthBuffer)
-		if err != nil {
-			fmt.Println("Error writing length to device:", err)
-			return "none", err
-		}
-
-		_, err = conn.Write(adamIDBuffer)
-		if err != nil {
-			fmt.Println("Error writing adamID to device:", err)
-			return "none", err
-		}
-
-		response, err := bufio.NewReader(conn).ReadBytes('\n')
-		if err != nil {
-			fmt.Println("Error reading response from device:", err)
-			return "none", err
-		}
-
-		response = bytes.TrimSpace(response)
-		if len(response) > 0 {
-			if f == "song" {
-				fmt.Println("Received URL:", string(response))
-			}
-			EnhancedHls = string(response)
-		} else {
-			fmt.Println("Received an empty response")
-		}
-	}
-	return EnhancedHls, nil
-}
-func extractMedia(b string, more_mode bool) (string, string, string, error) {
-	masterUrl, err := url.Parse(b)
-	if err != nil {
-		return "", "", "", err
-	}
-	resp, err := httpClient.Get(b)
-	if err != nil {
-		return "", "", "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", "", "", errors.New(resp.Status)
-	}
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, io.LimitReader(resp.Body, 2<<20))
-	if err != nil {
-		return "", "", "", err
-	}
-	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(buf.Bytes()), true)
-	if err != nil || listType != m3u8.MASTER {
-		return "", "", "", errors.New("m3u8 not of master type")
-	}
-	master := from.(*m3u8.MasterPlaylist)
-	var streamUrl *url.URL
-	sort.Slice(master.Variants, func(i, j int) bool {
-		return master.Variants[i].AverageBandwidth > master.Variants[j].AverageBandwidth
-	})
-	if debug_mode && more_mode {
-		fmt.Println("\nDebug: All Available Variants:")
-		var data [][]string
-		for _, variant := range master.Variants {
-			data = append(data, []string{variant.Codecs, variant.Audio, fmt.Sprint(variant.Bandwidth)})
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Codec", "Audio", "Bandwidth"})
-		table.SetAutoMergeCells(true)
-		table.SetRowLine(true)
-		table.AppendBulk(data)
-		table.Render()
-
-		var hasAAC, hasLossless, hasHiRes, hasAtmos, hasDolbyAudio bool
-		var aacQuality, losslessQuality, hiResQuality, atmosQuality, dolbyAudioQuality string
-
-		for _, variant := range master.Variants {
-			if variant.Codecs == "mp4a.40.2" { // AAC
-				hasAAC = true
-				split := strings.Split(variant.Audio, "-")
-				if len(split) >= 3 {
-					bitrate, _ := strconv.Atoi(split[2])
-					currentBitrate := 0
-					if aacQuality != "" {
-						current := strings.Split(aacQuality, " | ")[2]
-						current = strings.Split(current, " ")[0]
-						currentBitrate, _ = strconv.Atoi(current)
-					}
-					if bitrate > currentBitrate {
-						aacQuality = fmt.Sprintf("AAC | 2 Channel | %d Kbps", bitrate)
-					}
-				}
-			} else if variant.Codecs == "ec-3" && strings.Contains(variant.Audio, "atmos") { // Dolby Atmos
-				hasAtmos = true
-				split := strings.Split(variant.Audio, "-")
-				if len(split) > 0 {
-					bitrateStr := split[len(split)-1]
-					if len(bitrateStr) == 4 && bitrateStr[0] == '2' {
-						bitrateStr = bitrateStr[1:]
-					}
-					bitrate, _ := strconv.Atoi(bitrateStr)
-					currentBitrate := 0
-					if atmosQuality != "" {
-						current := strings.Split(strings.Split(atmosQuality, " | ")[2], " ")[0]
-						currentBitrate, _ = strconv.Atoi(current)
-					}
-					if bitrate > currentBitrate {
-						atmosQuality = fmt.Sprintf("E-AC-3 | 16 Channel | %d Kbps", bitrate)
-					}
-				}
-			} else if variant.Codecs == "alac" { // ALAC (Lossless or Hi-Res)
-				split := strings.Split(variant.Audio, "-")
-				if len(split) >= 3 {
-					bitDepth := split[len(split)-1]
-					sampleRate := split[len(split)-2]
-					sampleRateInt, _ := strconv.Atoi(sampleRate)
-					if sampleRateInt > 48000 { // Hi-Res
-						hasHiRes = true
-						hiResQuality = fmt.Sprintf("ALAC | 2 Channel | %s-bit/%d kHz", bitDepth, sampleRateInt/1000)
-					} else { // Standard Lossless
-						hasLossless = true
-						losslessQuality = fmt.Sprintf("ALAC | 2 Channel | %s-bit/%d kHz", bitDepth, sampleRateInt/1000)
-					}
-				}
-			} else if variant.Codecs == "ac-3" { // Dolby Audio
-				hasDolbyAudio = true
-				split := strings.Split(variant.Audio, "-")
-				if len(split) > 0 {
-					bitrate, _ := strconv.Atoi(split[len(split)-1])
-					dolbyAudioQuality = fmt.Sprintf("AC-3 |  16 Channel | %d Kbps", bitrate)
-				}
-			}
-		}
-
-		fmt.Println("Available Audio Formats:")
-		fmt.Println("------------------------")
-		fmt.Printf("AAC             : %s\n", formatAvailability(hasAAC, aacQuality))
-		fmt.Printf("Lossless        : %s\n", formatAvailability(hasLossless, losslessQuality))
-		fmt.Printf("Hi-Res Lossless : %s\n", formatAvailability(hasHiRes, hiResQuality))
-		fmt.Printf("Dolby Atmos     : %s\n", formatAvailability(hasAtmos, atmosQuality))
-		fmt.Printf("Dolby Audio     : %s\n", formatAvailability(hasDolbyAudio, dolbyAudioQuality))
-		fmt.Println("------------------------")
-
-		return "", "", "", nil
-	}
-	var Quality string
-	codecVariants := make(map[string][]*m3u8.Variant)
-	for _, v := range master.Variants {
-		codecVariants[v.Codecs] = append(codecVariants[v.Codecs], v)
-	}
-
-	var variant *m3u8.Variant
-	variant = codecAlternative(codecVariants, currentCodecPriority())
-	if variant != nil {
-		// Do not mutate global download preference flags here (they represent user/runtime choice).
-		// Instead return the codec name to the caller for per-entity decision.
-		fmt.Println("DEBUG: Selected codec:", variant.Codecs)
-		streamUrl, err = masterUrl.Parse(variant.URI)
-		if err != nil {
-			return "", "", "", err
-		}
-	}
-
-	if streamUrl == nil {
-		return "", "", "", errors.New("no codec found")
-	}
-	codecName := ""
-	if variant != nil {
-		codecName = variant.Codecs
-	}
-	return streamUrl.String(), Quality, codecName, nil
-}
-func extractVideo(c string) (string, error) {
-	MediaUrl, err := url.Parse(c)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := httpClient.Get(c)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(resp.Status)
-	}
-
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, io.LimitReader(resp.Body, 2<<20))
-	if err != nil {
-		return "", err
-	}
-	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(buf.Bytes()), true)
-	if err != nil || listType != m3u8.MASTER {
-		return "", errors.New("m3u8 not of media type")
-	}
-
-	video := from.(*m3u8.MasterPlaylist)
-
-	re := regexp.MustCompile(`_(\d+)x(\d+)`)
-
-	var streamUrl *url.URL
-	sort.Slice(video.Variants, func(i, j int) bool {
-		return video.Variants[i].AverageBandwidth > video.Variants[j].AverageBandwidth
-	})
-
-	maxHeight := Config.MVMax
-
-	for _, variant := range video.Variants {
-		matches := re.FindStringSubmatch(variant.URI)
-		if len(matches) == 3 {
-			height := matches[2]
-			var h int
-			_, err := fmt.Sscanf(height, "%d", &h)
-			if err != nil {
-				continue
-			}
-			if h <= maxHeight {
-				streamUrl, err = MediaUrl.Parse(variant.URI)
-				if err != nil {
-					return "", err
-				}
-				fmt.Println("Video: " + variant.Resolution + "-" + variant.VideoRange)
-				break
-			}
-		}
-	}
-
-	if streamUrl == nil {
-		return "", errors.New("no suitable video stream found")
-	}
-
-	return streamUrl.String(), nil
-}
-
-func codecAlternative(availableCodecs map[string][]*m3u8.Variant, priority []string) *m3u8.Variant {
-	for _, codec := range priority {
-		if variants, ok := availableCodecs[codec]; ok {
-			if len(variants) > 0 {
-				return variants[0]
-			}
-		}
-	}
-	return nil
-}
-
-func ripSong(songId string, token string, storefront string, mediaUserToken string) error {
-	// Get song info to find album ID
-	manifest, err := ampapi.GetSongResp(storefront, songId, Config.Language, token)
-	if err != nil {
-		fmt.Println("Failed to get song response.")
-		return err
-	}
-
-	songData := manifest.Data[0]
-	albumId := songData.Relationships.Albums.Data[0].ID
-
-	// Use album approach but only download the specific song
-	dl_song = true
-	err = ripAlbum(albumId, token, storefront, mediaUserToken, songId)
-	if err != nil {
-		fmt.Println("Failed to rip song:", err)
-		return err
-	}
-
-	return nil
-}
-
-// 处理单个 URL 的包装函数，便于 REPL 调用
-func handleSingleURL(urlRaw string, token string) {
-	if strings.Contains(urlRaw, "/artist/") {
-		urlArtistName, urlArtistID, err := getUrlArtistName(urlRaw, token)
-		if err != nil {
-			fmt.Println("Failed to get artistname.")
-			return
-		}
-		Config.ArtistFolderFormat = strings.NewReplacer(
-			"{UrlArtistName}", LimitString(urlArtistName),
-			"{ArtistId}", urlArtistID,
-		).Replace(Config.ArtistFolderFormat)
-		albumArgs, err := checkArtist(urlRaw, token, "albums")
-		if err != nil {
-			fmt.Println("Failed to get artist albums.")
-			return
-		}
-		mvArgs, err := checkArtist(urlRaw, token, "music-videos")
-		if err != nil {
-			fmt.Println("Failed to get artist music-videos.")
-		}
-		for _, a := range append(albumArgs, mvArgs...) {
-			handleSingleURL(a, token)
-		}
-		return
-	}
-
-	var storefront, albumId string
-	parse, err := url.Parse(urlRaw)
-	if err != nil {
-		log.Fatalf("Invalid URL: %v", err)
-	}
-	var urlArg_i = parse.Query().Get("i")
-
-	if strings.Contains(urlRaw, "/music-video/") {
-		fmt.Println("Music Video")
-		if debug_mode {
-			return
-		}
-		incTotal()
-		if len(Config.MediaUserToken) <= 50 {
-			fmt.Println(": meida-user-token is not set, skip MV dl")
-			incSuccess()
-			return
-		}
-		if _, err := exec.LookPath("mp4decrypt"); err != nil {
-			fmt.Println(": mp4decrypt is not found, skip MV dl")
-			incSuccess()
-			return
-		}
-		mvSaveDir := strings.NewReplacer(
-			"{ArtistName}", "",
-			"{UrlArtistName}", "",
-			"{ArtistId}", "",
-		).Replace(Config.ArtistFolderFormat)
-		if mvSaveDir != "" {
-			mvSaveDir = filepath.Join(OutputFolder, forbiddenNames.ReplaceAllString(mvSaveDir, "_"))
-		} else {
-			mvSaveDir = OutputFolder
-		}
-		storefront, albumId = checkUrlMv(urlRaw)
-		err := mvDownloader(albumId, mvSaveDir, token, storefront, Config.MediaUserToken, nil)
-		if err != nil {
-			fmt.Println("\u26A0 Failed to dl MV:", err)
-			incError()
-			return
-		}
-		incSuccess()
-		return
-	}
-
-	if strings.Contains(urlRaw, "/song/") {
-		fmt.Printf("Song->")
-		storefront, songId := checkUrlSong(urlRaw)
-		if storefront == "" || songId == "" {
-			fmt.Println("Invalid song URL format.")
-			addError("Invalid song URL format")
-			return
-		}
-		err := ripSong(songId, token, storefront, Config.MediaUserToken)
-		if err != nil {
-			fmt.Println("Failed to rip song:", err)
-			addError(fmt.Sprintf("Rip song failed: %v", err))
-			addEntityFail(songId)
-		} else {
-			removeEntityFail(songId)
-		}
-		return
-	}
-
-	if strings.Contains(urlRaw, "/album/") {
-		fmt.Println("Album")
-		storefront, albumId = checkUrl(urlRaw)
-		err := ripAlbum(albumId, token, storefront, Config.MediaUserToken, urlArg_i)
-		if err != nil {
-			fmt.Println("Failed to rip album:", err)
-			addError(fmt.Sprintf("Rip album failed: %v", err))
-			addEntityFail(albumId)
-		} else {
-			removeEntityFail(albumId)
-		}
-		return
-	}
-	if strings.Contains(urlRaw, "/playlist/") {
-		fmt.Println("Playlist")
-		storefront, albumId = checkUrlPlaylist(urlRaw)
-		err := ripPlaylist(albumId, token, storefront, Config.MediaUserToken)
-		if err != nil {
-			fmt.Println("Failed to rip playlist:", err)
-			addError(fmt.Sprintf("Rip playlist failed: %v", err))
-			addEntityFail(albumId)
-		} else {
-			removeEntityFail(albumId)
-		}
-		return
-	}
-	if strings.Contains(urlRaw, "/station/") {
-		fmt.Printf("Station")
-		storefront, albumId = checkUrlStation(urlRaw)
-		if len(Config.MediaUserToken) <= 50 {
-			fmt.Println(": meida-user-token is not set, skip station dl")
-			addWarning("Station skipped: media-user-token not set")
-			return
-		}
-		err := ripStation(albumId, token, storefront, Config.MediaUserToken)
-		if err != nil {
-			fmt.Println("Failed to rip station:", err)
-			addError(fmt.Sprintf("Rip station failed: %v", err))
-			addEntityFail(albumId)
-		} else {
-			removeEntityFail(albumId)
-		}
-		return
-	}
-	fmt.Println("Invalid type")
-}
-
-func writeMP4Tags(track *task.Track, lrc string) error {
-	// Build MP4 tag structure
-	t := &mp4tag.MP4Tags{
-		Title:      track.Resp.Attributes.Name,
-		TitleSort:  track.Resp.Attributes.Name,
-		Artist:     track.Resp.Attributes.ArtistName,
-		ArtistSort: track.Resp.Attributes.ArtistName,
-		Custom: map[string]string{
-			"PERFORMER":   track.Resp.Attributes.ArtistName,
-			"RELEASETIME": track.Resp.Attributes.ReleaseDate,
-			"ISRC":        track.Resp.Attributes.Isrc,
-		},
-		Composer:     track.Resp.Attributes.ComposerName,
-		ComposerSort: track.Resp.Attributes.ComposerName,
-		Lyrics:       lrc,
-		TrackNumber:  int16(track.Resp.Attributes.TrackNumber),
-		DiscNumber:   int16(track.Resp.Attributes.DiscNumber),
-		Album:        track.Resp.Attributes.AlbumName,
-		AlbumSort:    track.Resp.Attributes.AlbumName,
-	}
-
-	// Optional fields depending on pre-type
-	if track.PreType == "albums" {
-		if albumID, err := strconv.ParseUint(track.PreID, 10, 32); err == nil {
-			t.ItunesAlbumID = int32(albumID)
-		}
-	}
-	if len(track.Resp.Relationships.Artists.Data) > 0 {
-		if artistID, err := strconv.ParseUint(track.Resp.Relationships.Artists.Data[0].ID, 10, 32); err == nil {
-			t.ItunesArtistID = int32(artistID)
-		}
-	}
-
-	if (track.PreType == "playlists" || track.PreType == "stations") && !Config.UseSongInfoForPlaylist {
-		t.DiscNumber = 1
-		t.DiscTotal = 1
-		t.TrackNumber = int16(track.TaskNum)
-		t.TrackTotal = int16(track.TaskTotal)
-		t.Album = track.PlaylistData.Attributes.Name
-		t.AlbumSort = track.PlaylistData.Attributes.Name
-		t.AlbumArtist = track.PlaylistData.Attributes.ArtistName
-		t.AlbumArtistSort = track.PlaylistData.Attributes.ArtistName
-	} else if (track.PreType == "playlists" || track.PreType == "stations") && Config.UseSongInfoForPlaylist {
-		t.DiscTotal = int16(track.DiscTotal)
-		t.TrackTotal = int16(track.AlbumData.Attributes.TrackCount)
-		t.AlbumArtist = track.AlbumData.Attributes.ArtistName
-		t.AlbumArtistSort = track.AlbumData.Attributes.ArtistName
-		t.Custom["UPC"] = track.AlbumData.Attributes.Upc
-		t.Custom["LABEL"] = track.AlbumData.Attributes.RecordLabel
-		t.Date = track.AlbumData.Attributes.ReleaseDate
-		t.Copyright = track.AlbumData.Attributes.Copyright
-		t.Publisher = track.AlbumData.Attributes.RecordLabel
-	} else {
-		t.DiscTotal = int16(track.DiscTotal)
-		t.TrackTotal = int16(track.AlbumData.Attributes.TrackCount)
-		t.AlbumArtist = track.AlbumData.Attributes.ArtistName
-		t.AlbumArtistSort = track.AlbumData.Attributes.ArtistName
-		t.Custom["UPC"] = track.AlbumData.Attributes.Upc
-		t.Date = track.AlbumData.Attributes.ReleaseDate
-		t.Copyright = track.AlbumData.Attributes.Copyright
-		t.Publisher = track.AlbumData.Attributes.RecordLabel
-	}
-
-	switch track.Resp.Attributes.ContentRating {
-	case "explicit":
-		t.ItunesAdvisory = mp4tag.ItunesAdvisoryExplicit
-	case "clean":
-		t.ItunesAdvisory = mp4tag.ItunesAdvisoryClean
-	default:
-		t.ItunesAdvisory = mp4tag.ItunesAdvisoryNone
-	}
-
-	mp4f, err := mp4tag.Open(track.SavePath)
-	if err != nil {
-		return err
-	}
-	defer mp4f.Close()
-	if err := mp4f.Write(t, []string{}); err != nil {
-		return err
-	}
-	return nil
-}
@@ -0,0 +1,48 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+
+	"main/utils/ampapi"
+)
+
+// chartPaths 榜单类型对应的下载链接路径
+var chartPaths = map[string]string{
+	"albums":       "album",
+	"songs":        "song",
+	"playlists":    "playlist",
+	"music-videos": "music-video",
+}
+
+// Charts 获取 storefront 的榜单前 limit 项，按名次排列；返回榜单名称与条目。
+// 歌曲的 url 属性指向所属专辑，因此下载链接统一按 ID 生成
+func (d *Downloader) Charts(ctx context.Context, storefront string, kind string, genre string, limit int) (string, []ArtistItem, error) {
+	path, ok := chartPaths[kind]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown chart type %q", kind)
+	}
+	chart, err := ampapi.GetCharts(ctx, storefront, kind, genre, limit, d.cfg.Language, d.token)
+	if err != nil {
+		return "", nil, err
+	}
+	items := make([]ArtistItem, 0, len(chart.Data))
+	for _, data := range chart.Data {
+		a := data.Attributes
+		artist := a.ArtistName
+		if artist == "" {
+			artist = a.CuratorName
+		}
+		items = append(items, ArtistItem{
+			ID:            data.ID,
+			Name:          a.Name,
+			ReleaseDate:   a.ReleaseDate,
+			URL:           fmt.Sprintf("https://music.apple.com/%s/%s/%s", storefront, path, data.ID),
+			ArtistName:    artist,
+			IsSingle:      a.IsSingle,
+			IsCompilation: a.IsCompilation,
+			TrackCount:    a.TrackCount,
+		})
+	}
+	return chart.Name, items, nil
+}
@@ -0,0 +1,134 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"main/utils/atomicfile"
+	"main/utils/events"
+	"main/utils/task"
+)
+
+// CONVERSION FEATURE: Determine if source codec is lossy (rough heuristic by extension/codec name).
+func isLossySource(ext string, codec string) bool {
+	ext = strings.ToLower(ext)
+	if ext == ".m4a" && (codec == "AAC" || strings.Contains(codec, "AAC") || strings.Contains(codec, "ATMOS")) {
+		return true
+	}
+	if ext == ".mp3" || ext == ".opus" || ext == ".ogg" {
+		return true
+	}
+	return false
+}
+
+// CONVERSION FEATURE: Build ffmpeg arguments for desired target.
+func buildFFmpegArgs(ffmpegPath, inPath, outPath, targetFmt, extraArgs string) ([]string, error) {
+	args := []string{"-y", "-i", inPath, "-vn"}
+	switch targetFmt {
+	case "flac":
+		args = append(args, "-c:a", "flac")
+	case "mp3":
+		// VBR quality 2 ~ high quality
+		args = append(args, "-c:a", "libmp3lame", "-qscale:a", "2")
+	case "opus":
+		// Medium/high quality
+		args = append(args, "-c:a", "libopus", "-b:a", "192k", "-vbr", "on")
+	case "wav":
+		args = append(args, "-c:a", "pcm_s16le")
+	case "copy":
+		// Just container copy (probably pointless for same container)
+		args = append(args, "-c", "copy")
+	default:
+		return nil, fmt.Errorf("unsupported convert-format: %s", targetFmt)
+	}
+	if extraArgs != "" {
+		// naive split; for complex quoting you could enhance
+		args = append(args, strings.Fields(extraArgs)...)
+	}
+	args = append(args, outPath)
+	return args, nil
+}
+
+// CONVERSION FEATURE: Perform conversion if enabled.
+func (j *job) convertIfNeeded(ctx context.Context, track *task.Track) {
+	if !j.cfg.ConvertAfterDownload {
+		return
+	}
+	if j.cfg.ConvertFormat == "" {
+		return
+	}
+	srcPath := track.SavePath
+	if srcPath == "" {
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(srcPath))
+	targetFmt := strings.ToLower(j.cfg.ConvertFormat)
+
+	// Map extension for output
+	if targetFmt == "copy" {
+		fmt.Println("Convert (copy) requested; skipping because it produces no new format.")
+		return
+	}
+
+	if j.cfg.ConvertSkipIfSourceMatch {
+		if ext == "."+targetFmt {
+			fmt.Printf("Conversion skipped (already %s)\n", targetFmt)
+			return
+		}
+	}
+
+	outBase := strings.TrimSuffix(srcPath, ext)
+	outPath := outBase + "." + targetFmt
+
+	// Warn about lossy -> lossless
+	if j.cfg.ConvertWarnLossyToLossless && (targetFmt == "flac" || targetFmt == "wav") &&
+		isLossySource(ext, track.Codec) {
+		fmt.Println("Warning: Converting lossy source to lossless container will not improve quality.")
+	}
+
+	if _, err := exec.LookPath(j.cfg.FFmpegPath); err != nil {
+		fmt.Printf("ffmpeg not found at '%s'; skipping conversion.\n", j.cfg.FFmpegPath)
+		return
+	}
+
+	partPath := atomicfile.PartPath(outPath)
+	args, err := buildFFmpegArgs(j.cfg.FFmpegPath, srcPath, partPath, targetFmt, j.cfg.ConvertExtraArgs)
+	if err != nil {
+		fmt.Println("Conversion config error:", err)
+		return
+	}
+
+	fmt.Printf("Converting -> %s ...\n", targetFmt)
+	// Use a longer timeout for conversions
+	if err := runCmdTimeout(ctx, 30*time.Minute, j.cfg.FFmpegPath, args...); err != nil {
+		fmt.Println("Conversion failed:", err)
+		// leave original, drop the partial output
+		_ = os.Remove(partPath)
+		return
+	}
+	if err := atomicfile.Rename(partPath, outPath); err != nil {
+		fmt.Println("Conversion failed:", err)
+		return
+	}
+	fmt.Printf("Conversion completed in %s: %s\n", time.Since(time.Now()).Truncate(time.Millisecond), filepath.Base(outPath))
+
+	if !j.cfg.ConvertKeepOriginal {
+		if err := os.Remove(srcPath); err != nil {
+			fmt.Println("Failed to remove original after conversion:", err)
+		} else {
+			track.SavePath = outPath
+			track.SaveName = filepath.Base(outPath)
+			fmt.Println("Original removed.")
+		}
+	} else {
+		// Keep both but point track to new file (optional decision)
+		track.SavePath = outPath
+		track.SaveName = filepath.Base(outPath)
+	}
+	j.bus.Publish(events.Event{Type: events.Converted, Kind: track.PreType, EntityID: track.PreID, SongID: track.ID, Format: targetFmt, Path: outPath})
+}
@@ -0,0 +1,518 @@
+// Package downloader 下载流程（专辑、歌单、电台、单曲、MV、艺术家），可在其他 Go 程序中使用。
+//
+// 每个 Downloader 持有自己的配置、计数与失败记录，不同设置的多个 Downloader 可以并发运行；
+// 同一个 Downloader 的 Rip* 方法也可并发调用，每次调用的差异（重试、艺术家目录名等）通过 RipOptions 传入。
+package downloader
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"main/utils/atomicfile"
+	"main/utils/events"
+	"main/utils/sanitize"
+	"main/utils/store"
+	"main/utils/structs"
+)
+
+// ArtistSelector 从艺术家、唱片公司或策展人的条目列表中选出要下载的条目，
+// relationship 为 albums、music-videos 或 playlists；唱片公司与策展人的 artistName 为空
+type ArtistSelector func(ctx context.Context, artistName string, relationship string, items []ArtistItem) []ArtistItem
+
+// Options 创建 Downloader 的参数，零值字段使用默认值
+type Options struct {
+	Config       structs.ConfigSet
+	OutputFolder string // 默认 output
+	Concurrency  int    // 同时下载的曲目数，默认 4
+	// 开发者 token（Authorization: Bearer），media-user-token 取自 Config
+	Token string
+
+	Atmos  bool // 下载 Dolby Atmos
+	AAC    bool // 下载 AAC
+	Song   bool // 专辑链接带 ?i= 时只下载该曲目
+	Select bool // 交互选择专辑/歌单中的曲目
+	Debug  bool // 只显示可用音质，不下载
+	// 只解析元数据、音轨与目标路径，不下载也不写入任何文件（包括目录、封面与下载数据库）
+	DryRun bool
+
+	// 下载数据库，为 nil 时续传、失败重试与曲库去重只使用内存记录
+	DB *store.DB
+	// 事件总线，默认 events.Default
+	Events *events.Bus
+	Logger *slog.Logger
+	// 艺术家、唱片公司与策展人链接的选择方式，为 nil 时下载全部
+	SelectArtistItems ArtistSelector
+	HTTPClient        *http.Client
+}
+
+// RipOptions 单次下载的参数
+type RipOptions struct {
+	Storefront string // 默认 Config.Storefront
+	Retry      bool   // 只下载之前失败的曲目
+	TrackID    string // 专辑中只下载该曲目
+	// 本次下载的事件总线，默认 Options.Events；同一下载器同时处理多个任务时用于区分各任务的事件
+	Events *events.Bus
+	// 艺术家目录名中 {UrlArtistName} / {ArtistId} 的取值，为空时使用专辑艺术家
+	ArtistName string
+	ArtistID   string
+}
+
+// 曲目结果状态
+const (
+	TrackOK          = "ok"
+	TrackFailed      = "failed"
+	TrackUnavailable = "unavailable"
+	TrackSkipped     = "skipped" // 之前已完成，或缺少 media-user-token 等未下载
+	TrackPlanned     = "planned" // DryRun 时将会下载
+	TrackRemoved     = "removed" // playlist sync 时已移出歌单
+)
+
+// Stats 曲目计数
+type Stats struct {
+	Total       int `json:"total"`
+	Success     int `json:"success"`
+	Error       int `json:"error"`
+	Unavailable int `json:"unavailable"`
+}
+
+func (s *Stats) add(o Stats) {
+	s.Total += o.Total
+	s.Success += o.Success
+	s.Error += o.Error
+	s.Unavailable += o.Unavailable
+}
+
+// TrackResult 单个曲目的下载结果
+type TrackResult struct {
+	Index   int    `json:"index"` // 在专辑/歌单中的序号（从 1 开始）
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Codec   string `json:"codec,omitempty"`
+	Quality string `json:"quality,omitempty"`
+	Variant string `json:"variant,omitempty"` // 选中的 HLS 音轨编码，aac-lc 为单独的下载方式
+	Path    string `json:"path,omitempty"`
+	Lyrics  string `json:"lyrics,omitempty"` // 歌词文件路径
+}
+
+// Result 一次 Rip* 调用的结果
+type Result struct {
+	Kind   string        `json:"kind"` // album / playlist / station / song / music-video / artist
+	ID     string        `json:"id"`
+	Name   string        `json:"name,omitempty"`
+	Dir    string        `json:"dir,omitempty"`
+	Stats  Stats         `json:"stats"`
+	Covers []string      `json:"covers,omitempty"` // 封面、艺术家图片与动态封面
+	Tracks []TrackResult `json:"tracks,omitempty"`
+	Items  []*Result     `json:"items,omitempty"` // 艺术家链接下的专辑与 MV
+	Error  string        `json:"error,omitempty"`
+	// Skipped 整个实体未下载时的原因（如电台缺少 media-user-token）
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Failed 是否有失败（实体加载失败、曲目失败或子项失败）
+func (r *Result) Failed() bool {
+	if r.Error != "" || r.Stats.Error > 0 {
+		return true
+	}
+	for _, t := range r.Tracks {
+		if t.Status == TrackFailed {
+			return true
+		}
+	}
+	for _, it := range r.Items {
+		if it.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Downloader 下载器，所有状态都属于实例本身
+type Downloader struct {
+	cfg          structs.ConfigSet
+	outputFolder string
+	concurrency  int
+	token        string
+	atmos        bool
+	aac          bool
+	song         bool
+	selectTracks bool
+	debug        bool
+	dryRun       bool
+	db           *store.DB
+	bus          *events.Bus
+	logger       *slog.Logger
+	selectArtist ArtistSelector
+	httpClient   *http.Client
+	downloadSem  chan struct{}
+	tagSem       chan struct{}
+	names        *sanitize.Profile
+
+	statsMu sync.Mutex
+	stats   Stats
+	okMu    sync.Mutex
+	okDict  map[string][]int
+	failMu  sync.Mutex
+	failed  map[string]map[int]struct{}
+	// 运行期问题记录（详情输出使用）
+	issueMu  sync.Mutex
+	warnings []string
+	errors   []string
+}
+
+// New 按 opts 创建下载器
+func New(opts Options) *Downloader {
+	cfg := opts.Config
+	if len(cfg.Storefront) != 2 {
+		cfg.Storefront = "us"
+	}
+	if strings.TrimSpace(cfg.Language) == "" {
+		cfg.Language = "en"
+	}
+	if strings.TrimSpace(cfg.LrcType) == "" {
+		cfg.LrcType = "lyrics"
+	}
+	if strings.TrimSpace(cfg.LrcFormat) == "" {
+		cfg.LrcFormat = "lrc"
+	}
+	if strings.TrimSpace(cfg.MVFileFormat) == "" {
+		cfg.MVFileFormat = "{SongName} ({SongId})"
+	}
+	// 未设置 filename-profile 时按 windows 处理字符，但只有显式选择配置档或设置 max-path-length 才限制路径长度
+	names, err := sanitize.Lookup(cfg.FilenameProfile, cfg.MaxPathLength, cfg.FilenameNormalization)
+	if err != nil {
+		// 取值错误已由配置检查报告，这里退回默认规则
+		names, _ = sanitize.Lookup("", cfg.MaxPathLength, "")
+	}
+	d := &Downloader{
+		cfg:          cfg,
+		outputFolder: strings.TrimSpace(opts.OutputFolder),
+		concurrency:  opts.Concurrency,
+		token:        opts.Token,
+		atmos:        opts.Atmos,
+		aac:          opts.AAC,
+		song:         opts.Song,
+		selectTracks: opts.Select,
+		debug:        opts.Debug,
+		dryRun:       opts.DryRun,
+		db:           opts.DB,
+		bus:          opts.Events,
+		logger:       opts.Logger,
+		selectArtist: opts.SelectArtistItems,
+		httpClient:   opts.HTTPClient,
+		names:        names,
+		okDict:       make(map[string][]int),
+		failed:       make(map[string]map[int]struct{}),
+	}
+	if d.outputFolder == "" {
+		d.outputFolder = "output"
+	}
+	if d.concurrency <= 0 {
+		d.concurrency = 4
+	}
+	if d.bus == nil {
+		d.bus = events.Default
+	}
+	if d.httpClient == nil {
+		reqTimeout := 30 * time.Second
+		if cfg.RequestTimeoutSec > 0 {
+			reqTimeout = time.Duration(cfg.RequestTimeoutSec) * time.Second
+		}
+		d.httpClient = &http.Client{
+			Timeout: reqTimeout,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   reqTimeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 20,
+				IdleConnTimeout:     60 * time.Second,
+			},
+		}
+	}
+	d.downloadSem = make(chan struct{}, d.concurrency)
+	tagConc := 2
+	if cfg.TaggingConcurrency > 0 {
+		tagConc = cfg.TaggingConcurrency
+	}
+	d.tagSem = make(chan struct{}, tagConc)
+	return d
+}
+
+// Config 返回下载器使用的配置（已填充默认值）
+func (d *Downloader) Config() structs.ConfigSet { return d.cfg }
+
+// OutputFolder 返回输出根目录
+func (d *Downloader) OutputFolder() string { return d.outputFolder }
+
+// Stats 返回下载器创建以来的累计计数
+func (d *Downloader) Stats() Stats {
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+	return d.stats
+}
+
+// Issues 返回运行期记录的警告与错误
+func (d *Downloader) Issues() (warnings []string, errors []string) {
+	d.issueMu.Lock()
+	defer d.issueMu.Unlock()
+	return append([]string{}, d.warnings...), append([]string{}, d.errors...)
+}
+
+func (d *Downloader) ClearIssues() {
+	d.issueMu.Lock()
+	d.warnings = nil
+	d.errors = nil
+	d.issueMu.Unlock()
+}
+
+// ClearFailures 清空内存中的失败曲目记录（数据库中的记录不受影响）
+func (d *Downloader) ClearFailures() {
+	d.failMu.Lock()
+	d.failed = make(map[string]map[int]struct{})
+	d.failMu.Unlock()
+}
+
+// AddWarning 记录一条警告，随 Issues 返回
+func (d *Downloader) AddWarning(msg string) {
+	d.issueMu.Lock()
+	d.warnings = append(d.warnings, msg)
+	d.issueMu.Unlock()
+	if d.logger != nil {
+		d.logger.Warn(msg)
+	}
+}
+
+// AddError 记录一条错误，随 Issues 返回
+func (d *Downloader) AddError(msg string) {
+	d.issueMu.Lock()
+	d.errors = append(d.errors, msg)
+	d.issueMu.Unlock()
+	if d.logger != nil {
+		d.logger.Error(msg)
+	}
+}
+
+func (d *Downloader) addOk(id string, num int) {
+	d.okMu.Lock()
+	d.okDict[id] = append(d.okDict[id], num)
+	d.okMu.Unlock()
+}
+
+// getOk 返回副本，避免调用方与写入方竞争
+func (d *Downloader) getOk(id string) []int {
+	d.okMu.Lock()
+	defer d.okMu.Unlock()
+	return append([]int(nil), d.okDict[id]...)
+}
+
+func (d *Downloader) addFail(id string, num int) {
+	d.failMu.Lock()
+	set, ok := d.failed[id]
+	if !ok {
+		set = make(map[int]struct{})
+		d.failed[id] = set
+	}
+	set[num] = struct{}{}
+	d.failMu.Unlock()
+}
+
+func (d *Downloader) removeFail(id string, num int) {
+	d.failMu.Lock()
+	if set, ok := d.failed[id]; ok {
+		delete(set, num)
+		if len(set) == 0 {
+			delete(d.failed, id)
+		}
+	}
+	d.failMu.Unlock()
+}
+
+func (d *Downloader) getFail(id string) []int {
+	d.failMu.Lock()
+	set := d.failed[id]
+	out := make([]int, 0, len(set))
+	for n := range set {
+		out = append(out, n)
+	}
+	d.failMu.Unlock()
+	sort.Ints(out)
+	return out
+}
+
+func (d *Downloader) acquireDownloadSlot() { d.downloadSem <- struct{}{} }
+func (d *Downloader) releaseDownloadSlot() {
+	select {
+	case <-d.downloadSem:
+	default:
+	}
+}
+
+func (d *Downloader) acquireTagSlot() { d.tagSem <- struct{}{} }
+func (d *Downloader) releaseTagSlot() {
+	select {
+	case <-d.tagSem:
+	default:
+	}
+}
+
+func (d *Downloader) limitString(s string) string {
+	if len([]rune(s)) > d.cfg.LimitMax {
+		return string([]rune(s)[:d.cfg.LimitMax])
+	}
+	return s
+}
+
+// extReserve 文件名中为扩展名与 "_thumbnail" 等后缀预留的字节数
+const extReserve = 16
+
+// safeName 按 filename-profile 处理 dir 下的目录名/文件名；reserve 为之后追加的后缀预留的字节数
+func (d *Downloader) safeName(dir string, name string, reserve int) string {
+	return d.names.Fit(dir, name, reserve)
+}
+
+// job 一次 Rip* 调用：选项与结果属于调用本身，计数同时累加到 Downloader
+type job struct {
+	*Downloader
+	opts RipOptions
+	mu   sync.Mutex
+	res  *Result
+	sync *playlistSync // playlist sync 时非空
+	bus  *events.Bus   // RipOptions.Events，未设置时为下载器的总线
+}
+
+func (d *Downloader) newJob(kind string, id string, opts RipOptions) *job {
+	if opts.Storefront == "" {
+		opts.Storefront = d.cfg.Storefront
+	}
+	bus := opts.Events
+	if bus == nil {
+		bus = d.bus
+	}
+	return &job{Downloader: d, opts: opts, res: &Result{Kind: kind, ID: id}, bus: bus}
+}
+
+func (j *job) count(f func(s *Stats)) {
+	j.statsMu.Lock()
+	f(&j.stats)
+	j.statsMu.Unlock()
+	j.mu.Lock()
+	f(&j.res.Stats)
+	j.mu.Unlock()
+}
+
+func (j *job) incTotal()       { j.count(func(s *Stats) { s.Total++ }) }
+func (j *job) incSuccess()     { j.count(func(s *Stats) { s.Success++ }) }
+func (j *job) incError()       { j.count(func(s *Stats) { s.Error++ }) }
+func (j *job) incUnavailable() { j.count(func(s *Stats) { s.Unavailable++ }) }
+
+func (j *job) addTrack(t TrackResult) {
+	j.mu.Lock()
+	j.res.Tracks = append(j.res.Tracks, t)
+	j.mu.Unlock()
+}
+
+func (j *job) addCover(path string) {
+	j.mu.Lock()
+	j.res.Covers = append(j.res.Covers, path)
+	j.mu.Unlock()
+}
+
+// mkdirAll 创建输出目录，DryRun 时不创建
+func (j *job) mkdirAll(path string) error {
+	if j.dryRun {
+		return nil
+	}
+	return os.MkdirAll(path, os.ModePerm)
+}
+
+// saveCover 写入封面并记入结果，DryRun 时只返回目标路径
+func (j *job) saveCover(ctx context.Context, dir, name string, url string) (string, error) {
+	path, err := j.writeCover(ctx, dir, name, url)
+	if err == nil {
+		j.addCover(path)
+	}
+	return path, err
+}
+
+// saveArtwork 用外部命令生成动态封面并记入结果，DryRun 时只记录路径
+func (j *job) saveArtwork(ctx context.Context, timeout time.Duration, outPath string, name string, args ...string) error {
+	if !j.dryRun {
+		if err := runCmdOutput(ctx, timeout, outPath, name, args...); err != nil {
+			return err
+		}
+	}
+	j.addCover(outPath)
+	return nil
+}
+
+// finish 整理结果，err 记入 Result.Error
+func (j *job) finish(err error) (*Result, error) {
+	sort.SliceStable(j.res.Tracks, func(a, b int) bool { return j.res.Tracks[a].Index < j.res.Tracks[b].Index })
+	if err != nil {
+		j.res.Error = err.Error()
+	}
+	return j.res, err
+}
+
+func isInArray(arr []int, target int) bool {
+	for _, num := range arr {
+		if num == target {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(slice []string, item string) bool {
+	for _, v := range slice {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) (bool, error) {
+	f, err := os.Stat(path)
+	if err == nil {
+		return !f.IsDir(), nil
+	} else if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// runCmdTimeout runs an external command with a timeout (or until ctx is canceled) and returns its error
+func runCmdTimeout(ctx context.Context, timeout time.Duration, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	return cmd.Run()
+}
+
+// runCmdOutput 与 runCmdTimeout 相同，但命令输出到 outPath 的同目录临时文件（作为最后一个参数），成功后再重命名为 outPath
+func runCmdOutput(ctx context.Context, timeout time.Duration, outPath string, name string, args ...string) error {
+	part := atomicfile.PartPath(outPath)
+	_ = os.Remove(part)
+	if err := runCmdTimeout(ctx, timeout, name, append(args, part)...); err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	return atomicfile.Rename(part, outPath)
+}
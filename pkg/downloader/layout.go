@@ -0,0 +1,200 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"main/utils/task"
+)
+
+// libraryTrack playlist-layout 为 library 时，歌单与电台中的歌曲按专辑目录结构保存；
+// MV 与取不到专辑信息的曲目仍保存在歌单目录中
+func (d *Downloader) libraryTrack(track *task.Track) bool {
+	return d.cfg.PlaylistLayout == "library" &&
+		(track.PreType == "playlists" || track.PreType == "stations") &&
+		track.Type != "music-videos" &&
+		track.AlbumData.ID != ""
+}
+
+// libraryPlaylist tracks 为按 playlist-layout: library 保存的歌单或电台
+func (d *Downloader) libraryPlaylist(tracks []task.Track) bool {
+	return d.cfg.PlaylistLayout == "library" && len(tracks) > 0 &&
+		(tracks[0].PreType == "playlists" || tracks[0].PreType == "stations")
+}
+
+// albumPosition 曲目在所属专辑中的序号，与下载整张专辑时的 {SongNumer} 一致
+func albumPosition(track *task.Track) int {
+	for i, t := range track.AlbumData.Relationships.Tracks.Data {
+		if t.ID == track.ID {
+			return i + 1
+		}
+	}
+	return track.Resp.Attributes.TrackNumber
+}
+
+// choiceTag 由 apple-master-choice、explicit-choice、clean-choice 组成的 {Tag}
+func (d *Downloader) choiceTag(master bool, contentRating string) string {
+	var parts []string
+	if master && d.cfg.AppleMasterChoice != "" {
+		parts = append(parts, d.cfg.AppleMasterChoice)
+	}
+	if contentRating == "explicit" && d.cfg.ExplicitChoice != "" {
+		parts = append(parts, d.cfg.ExplicitChoice)
+	}
+	if contentRating == "clean" && d.cfg.CleanChoice != "" {
+		parts = append(parts, d.cfg.CleanChoice)
+	}
+	return strings.Join(parts, " ")
+}
+
+// libraryFolder 歌单曲目在专辑目录结构中的保存目录：<艺术家>/<专辑>[/<分碟>]，与下载整张专辑时相同
+func (j *job) libraryFolder(track *task.Track, quality string, atmos, aac bool) string {
+	fields := j.albumFields(track.AlbumData)
+	a := track.AlbumData.Attributes
+	fields.Codec, fields.Quality = track.Codec, quality
+	fields.Tag = j.choiceTag(a.IsAppleDigitalMaster || a.IsMasteredForItunes, a.ContentRating)
+	fields.SampleRate = sampleRate(quality, atmos, aac)
+	singerFolder := filepath.Join(j.outputFolder, j.safeName(j.outputFolder, j.artistFolder(fields), 0))
+	albumFolder := strings.TrimSpace(j.render("album-folder-format", j.cfg.AlbumFolderFormat, fields))
+	albumFolderPath := filepath.Join(singerFolder, j.safeName(singerFolder, albumFolder, 0))
+	return j.discFolder(albumFolderPath, fields, track.Resp.Attributes.DiscNumber)
+}
+
+// linkPlaylistTracks playlist-links 为 symlink/hardlink 时，在歌单目录中为每首已下载的曲目
+// 建立指向专辑目录中文件的链接，文件名按 song-file-format 以歌单序号生成；
+// 不再属于歌单的链接会被删除：只删除指向输出目录的符号链接与上次记录的链接，
+// 本次没有取到文件的曲目保留原有链接
+func (j *job) linkPlaylistTracks(folder string, tracks []task.Track) {
+	mode := j.cfg.PlaylistLinks
+	if !j.libraryPlaylist(tracks) || (mode != "symlink" && mode != "hardlink") || j.dryRun {
+		return
+	}
+	playlistID := tracks[0].PreID
+	previous := make(map[string]bool)
+	if j.db != nil {
+		recorded, err := j.db.PlaylistLinks(playlistID)
+		if err != nil {
+			fmt.Println("Failed to read download database:", err)
+		}
+		for _, link := range recorded {
+			previous[link] = true
+		}
+	}
+	links := make(map[string]bool)   // 本次建立的链接
+	keep := make(map[string]bool)    // 建立失败的链接，原有文件不动
+	pending := make(map[string]bool) // 没有取到文件的曲目的链接，不含扩展名
+	for i := range tracks {
+		track := &tracks[i]
+		if track.Type == "music-videos" {
+			continue
+		}
+		target := j.trackFilePath(track)
+		if target != "" && filepath.Dir(target) == folder {
+			continue
+		}
+		fields := j.trackFields(track)
+		fields.PlaylistName = j.limitString(track.PlaylistData.Attributes.Name)
+		fields.PlaylistId = track.PreID
+		fields.SongNumber = i + 1
+		fields.Tag = j.choiceTag(track.Resp.Attributes.IsAppleDigitalMaster, track.Resp.Attributes.ContentRating)
+		name := j.render("song-file-format", j.cfg.SongFileFormat, fields)
+		base := filepath.Join(folder, j.safeName(folder, name, extReserve))
+		if target == "" {
+			pending[base] = true
+			continue
+		}
+		link := base + filepath.Ext(target)
+		if err := j.linkFile(mode, target, link); err != nil {
+			fmt.Println("Failed to link track:", err)
+			j.AddWarning(fmt.Sprintf("Link %s failed: %v", link, err))
+			keep[link] = true
+			continue
+		}
+		links[link] = true
+	}
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(folder, e.Name())
+		if links[path] || keep[path] {
+			continue
+		}
+		if pending[strings.TrimSuffix(path, filepath.Ext(path))] {
+			if previous[path] {
+				links[path] = true
+			}
+			continue
+		}
+		if previous[path] || e.Type()&os.ModeSymlink != 0 && j.linksIntoOutput(path) {
+			if err := os.Remove(path); err != nil {
+				fmt.Println("Failed to remove link:", err)
+			}
+		}
+	}
+	if j.db == nil {
+		return
+	}
+	recorded := make([]string, 0, len(links))
+	for link := range links {
+		recorded = append(recorded, link)
+	}
+	sort.Strings(recorded)
+	if err := j.db.PutPlaylistLinks(playlistID, recorded); err != nil {
+		fmt.Println("Failed to update download database:", err)
+	}
+}
+
+// linksIntoOutput 符号链接 link 指向输出目录中的文件
+func (j *job) linksIntoOutput(link string) bool {
+	dest, err := os.Readlink(link)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(link), dest)
+	}
+	root, err := filepath.Abs(j.outputFolder)
+	if err != nil {
+		return false
+	}
+	if dest, err = filepath.Abs(dest); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, dest)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// linkFile 建立或更新 link，符号链接使用相对路径；同名的普通文件不会被覆盖
+func (j *job) linkFile(mode string, target string, link string) error {
+	if fi, err := os.Lstat(link); err == nil {
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			if dest, err := os.Readlink(link); err == nil && mode == "symlink" && filepath.Join(filepath.Dir(link), dest) == target {
+				return nil
+			}
+			if err := os.Remove(link); err != nil {
+				return err
+			}
+		case mode == "hardlink":
+			if ti, err := os.Stat(target); err == nil && os.SameFile(fi, ti) {
+				return nil
+			}
+			return fmt.Errorf("%s already exists", link)
+		default:
+			return fmt.Errorf("%s already exists", link)
+		}
+	}
+	if mode == "hardlink" {
+		return os.Link(target, link)
+	}
+	rel, err := filepath.Rel(filepath.Dir(link), target)
+	if err != nil {
+		rel = target
+	}
+	return os.Symlink(rel, link)
+}
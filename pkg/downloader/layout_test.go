@@ -0,0 +1,169 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"main/utils/events"
+	"main/utils/store"
+	"main/utils/structs"
+	"main/utils/task"
+)
+
+// newTestJob 使用临时数据库与输出目录的歌单任务
+func newTestJob(t *testing.T, cfg structs.ConfigSet) (*job, string) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := store.Open(filepath.Join(dir, "test.db"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	d := New(Options{Config: cfg, OutputFolder: filepath.Join(dir, "output"), DB: db, Events: &events.Bus{}})
+	return d.newJob("playlist", "pl.test", RipOptions{}), dir
+}
+
+func writeFiles(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exists(path string) bool {
+	ok, _ := fileExists(path)
+	return ok
+}
+
+func TestLinkPlaylistTracks(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		resolved   []bool            // 每首曲目本次是否取到了文件
+		symlinks   map[string]string // 歌单目录中已有的符号链接 -> 目标（相对临时目录）
+		files      []string          // 歌单目录中已有的普通文件
+		recorded   []string          // 上次记录的链接
+		want       []string          // 之后仍存在的文件
+		wantGone   []string          // 之后被删除的文件
+		wantRecord map[string]bool   // 之后记录的链接
+	}{
+		{
+			name:       "links tracks",
+			mode:       "symlink",
+			resolved:   []bool{true, true},
+			want:       []string{"01. Song a.m4a", "02. Song b.m4a"},
+			wantRecord: map[string]bool{"01. Song a.m4a": true, "02. Song b.m4a": true},
+		},
+		{
+			name:       "stale symlink into output",
+			mode:       "symlink",
+			resolved:   []bool{true, true},
+			symlinks:   map[string]string{"03. Song c.m4a": "output/Artist/Album/03. Song c.m4a"},
+			wantGone:   []string{"03. Song c.m4a"},
+			wantRecord: map[string]bool{"01. Song a.m4a": true, "02. Song b.m4a": true},
+		},
+		{
+			name:       "user symlink",
+			mode:       "symlink",
+			resolved:   []bool{true, true},
+			symlinks:   map[string]string{"mine.m4a": "elsewhere/mine.m4a"},
+			want:       []string{"mine.m4a"},
+			wantRecord: map[string]bool{"01. Song a.m4a": true, "02. Song b.m4a": true},
+		},
+		{
+			// 第二首本次没有取到文件，保留上次的链接
+			name:       "unresolved track",
+			mode:       "symlink",
+			resolved:   []bool{true, false},
+			symlinks:   map[string]string{"02. Song b.m4a": "output/Artist/Album/02. Song b.m4a"},
+			recorded:   []string{"01. Song a.m4a", "02. Song b.m4a"},
+			want:       []string{"01. Song a.m4a", "02. Song b.m4a"},
+			wantRecord: map[string]bool{"01. Song a.m4a": true, "02. Song b.m4a": true},
+		},
+		{
+			name:       "stale hardlink",
+			mode:       "hardlink",
+			resolved:   []bool{true, true},
+			files:      []string{"03. Song c.m4a", "notes.m4a"},
+			recorded:   []string{"01. Song a.m4a", "02. Song b.m4a", "03. Song c.m4a"},
+			want:       []string{"01. Song a.m4a", "02. Song b.m4a", "notes.m4a"},
+			wantGone:   []string{"03. Song c.m4a"},
+			wantRecord: map[string]bool{"01. Song a.m4a": true, "02. Song b.m4a": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, dir := newTestJob(t, structs.ConfigSet{
+				PlaylistLayout: "library",
+				PlaylistLinks:  tt.mode,
+				SongFileFormat: "{SongNumer}. {SongName}",
+				LimitMax:       200,
+			})
+			folder := filepath.Join(dir, "output", "Playlists", "Mix")
+			album := filepath.Join(dir, "output", "Artist", "Album")
+			writeFiles(t, filepath.Join(album, "01. Song a.m4a"), filepath.Join(album, "02. Song b.m4a"),
+				filepath.Join(album, "03. Song c.m4a"), filepath.Join(dir, "elsewhere", "mine.m4a"))
+			if err := os.MkdirAll(folder, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tt.files {
+				writeFiles(t, filepath.Join(folder, f))
+			}
+			for link, target := range tt.symlinks {
+				if err := os.Symlink(filepath.Join(dir, target), filepath.Join(folder, link)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var recorded []string
+			for _, r := range tt.recorded {
+				recorded = append(recorded, filepath.Join(folder, r))
+			}
+			if err := j.db.PutPlaylistLinks("pl.test", recorded); err != nil {
+				t.Fatal(err)
+			}
+
+			var tracks []task.Track
+			for i, resolved := range tt.resolved {
+				id := string(rune('a' + i))
+				track := task.Track{ID: id, TaskNum: i + 1, PreType: "playlists", PreID: "pl.test"}
+				track.Resp.Attributes.Name = "Song " + id
+				if resolved {
+					track.SavePath = filepath.Join(album, fmt.Sprintf("%02d. Song %s.m4a", i+1, id))
+				}
+				tracks = append(tracks, track)
+			}
+			j.linkPlaylistTracks(folder, tracks)
+
+			for _, f := range tt.want {
+				if !exists(filepath.Join(folder, f)) {
+					t.Errorf("%s was removed", f)
+				}
+			}
+			for _, f := range tt.wantGone {
+				if _, err := os.Lstat(filepath.Join(folder, f)); err == nil {
+					t.Errorf("%s was kept", f)
+				}
+			}
+			got, err := j.db.PlaylistLinks("pl.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.wantRecord) {
+				t.Errorf("recorded links = %v, want %v", got, tt.wantRecord)
+			}
+			for _, link := range got {
+				if filepath.Dir(link) != folder || !tt.wantRecord[filepath.Base(link)] {
+					t.Errorf("unexpected recorded link %s", link)
+				}
+			}
+		})
+	}
+}
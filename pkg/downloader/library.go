@@ -0,0 +1,109 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"main/utils/store"
+	"main/utils/task"
+
+	"github.com/itouakirai/mp4ff/mp4"
+)
+
+// SongIDTag 写入 m4a 的自定义标签，记录 Apple Music 歌曲 ID，供曲库索引识别
+const SongIDTag = "ITUNESSONGID"
+
+// codecSatisfies 判断已有文件的编码能否满足本次下载需求：
+// 相同编码视为满足，ALAC/ATMOS 也可满足 AAC 需求
+func codecSatisfies(have, want string) bool {
+	have, want = strings.ToUpper(have), strings.ToUpper(want)
+	if have == "" {
+		return false
+	}
+	if have == want {
+		return true
+	}
+	return want == "AAC" && (have == "ALAC" || have == "ATMOS")
+}
+
+// DetectCodec 读取 stsd 中的采样描述判断音频编码（ALAC / ATMOS / AAC）
+func DetectCodec(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	parsed, err := mp4.DecodeFile(f, mp4.WithDecodeMode(mp4.DecModeLazyMdat))
+	if err != nil {
+		return "", err
+	}
+	if parsed.Moov == nil || parsed.Moov.Trak == nil || parsed.Moov.Trak.Mdia == nil ||
+		parsed.Moov.Trak.Mdia.Minf == nil || parsed.Moov.Trak.Mdia.Minf.Stbl == nil ||
+		parsed.Moov.Trak.Mdia.Minf.Stbl.Stsd == nil || len(parsed.Moov.Trak.Mdia.Minf.Stbl.Stsd.Children) == 0 {
+		return "", fmt.Errorf("no sample description in %s", path)
+	}
+	switch t := parsed.Moov.Trak.Mdia.Minf.Stbl.Stsd.Children[0].Type(); t {
+	case "alac":
+		return "ALAC", nil
+	case "ec-3", "ac-3":
+		return "ATMOS", nil
+	case "mp4a":
+		return "AAC", nil
+	default:
+		return "", fmt.Errorf("unknown sample entry %q", t)
+	}
+}
+
+// findInLibrary 按歌曲 ID（开启 library-match-isrc 时也按 ISRC）在曲库索引中查找可复用的文件，失效记录顺带清理
+func (d *Downloader) findInLibrary(track *task.Track, wantCodec string) (string, bool) {
+	if d.db == nil || !d.cfg.LibrarySkipExisting {
+		return "", false
+	}
+	// 同一 ISRC 可能对应不同的版本（专辑版、精选集、重制版），默认只按歌曲 ID 匹配
+	isrc := ""
+	if d.cfg.LibraryMatchISRC {
+		isrc = track.Resp.Attributes.Isrc
+	}
+	items, err := d.db.FindLibraryItems(track.ID, isrc)
+	if err != nil {
+		fmt.Println("Failed to read library index:", err)
+		return "", false
+	}
+	for _, it := range items {
+		if exists, _ := fileExists(it.Path); !exists {
+			if !d.dryRun {
+				_ = d.db.RemoveLibraryItem(it.Path)
+			}
+			continue
+		}
+		if d.cfg.LibraryMatchCodec && !codecSatisfies(it.Codec, wantCodec) {
+			continue
+		}
+		return it.Path, true
+	}
+	return "", false
+}
+
+// indexTrack 下载完成后把文件加入曲库索引
+func (d *Downloader) indexTrack(track *task.Track) {
+	if d.db == nil || track.SavePath == "" {
+		return
+	}
+	codec := track.Codec
+	if strings.EqualFold(filepath.Ext(track.SavePath), ".m4a") {
+		if c, err := DetectCodec(track.SavePath); err == nil {
+			codec = c
+		}
+	}
+	err := d.db.PutLibraryItem(store.LibraryItem{
+		Path:   track.SavePath,
+		SongID: track.ID,
+		ISRC:   track.Resp.Attributes.Isrc,
+		Codec:  codec,
+	})
+	if err != nil {
+		fmt.Println("Failed to update library index:", err)
+	}
+}
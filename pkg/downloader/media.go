@@ -0,0 +1,490 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"main/utils/atomicfile"
+
+	"github.com/grafov/m3u8"
+	"github.com/olekukonko/tablewriter"
+)
+
+func (d *Downloader) writeCover(ctx context.Context, sanAlbumFolder, name string, url string) (string, error) {
+	originalUrl := url
+	var ext string
+	var covPath string
+	if d.cfg.CoverFormat == "original" {
+		ext = strings.Split(url, "/")[len(strings.Split(url, "/"))-2]
+		ext = ext[strings.LastIndex(ext, ".")+1:]
+		covPath = filepath.Join(sanAlbumFolder, name+"."+ext)
+	} else {
+		covPath = filepath.Join(sanAlbumFolder, name+"."+d.cfg.CoverFormat)
+	}
+	if d.dryRun {
+		return covPath, nil
+	}
+	// 已有封面在新封面下载完成后才会被替换
+	if d.cfg.CoverFormat == "png" {
+		re := regexp.MustCompile(`\{w\}x\{h\}`)
+		parts := re.Split(url, 2)
+		url = parts[0] + "{w}x{h}" + strings.Replace(parts[1], ".jpg", ".png", 1)
+	}
+	url = strings.Replace(url, "{w}x{h}", d.cfg.CoverSize, 1)
+	if d.cfg.CoverFormat == "original" {
+		url = strings.Replace(url, "is1-ssl.mzstatic.com/image/thumb", "a5.mzstatic.com/us/r1000/0", 1)
+		url = url[:strings.LastIndex(url, "/")]
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	do, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer do.Body.Close()
+	if do.StatusCode != http.StatusOK {
+		if d.cfg.CoverFormat == "original" {
+			fmt.Println("Failed to get cover, falling back to " + ext + " url.")
+			splitByDot := strings.Split(originalUrl, ".")
+			last := splitByDot[len(splitByDot)-1]
+			fallback := originalUrl[:len(originalUrl)-len(last)] + ext
+			fallback = strings.Replace(fallback, "{w}x{h}", d.cfg.CoverSize, 1)
+			fmt.Println("Fallback URL:", fallback)
+			req, err = http.NewRequestWithContext(ctx, "GET", fallback, nil)
+			if err != nil {
+				fmt.Println("Failed to create request for fallback url.")
+				return "", err
+			}
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+			do, err = d.httpClient.Do(req)
+			if err != nil {
+				fmt.Println("Failed to get cover from fallback url.")
+				return "", err
+			}
+			defer do.Body.Close()
+			if do.StatusCode != http.StatusOK {
+				fmt.Println(fallback)
+				return "", errors.New(do.Status)
+			}
+		} else {
+			return "", errors.New(do.Status)
+		}
+	}
+	f, err := atomicfile.Create(covPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Abort()
+	_, err = io.Copy(f, do.Body)
+	if err != nil {
+		return "", err
+	}
+	if err := f.Commit(); err != nil {
+		return "", err
+	}
+	return covPath, nil
+}
+
+func writeLyrics(sanAlbumFolder, filename string, lrc string) error {
+	lyricspath := filepath.Join(sanAlbumFolder, filename)
+	return atomicfile.WriteFile(lyricspath, []byte(lrc), 0666)
+}
+
+// formatAvailability returns a human-friendly availability/quality string.
+func formatAvailability(available bool, quality string) string {
+	if !available {
+		return "Not Available"
+	}
+	if quality == "" {
+		return "Available"
+	}
+	return quality
+}
+
+func (d *Downloader) extractMvAudio(ctx context.Context, c string) (string, error) {
+	MediaUrl, err := url.Parse(c)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return "", err
+	}
+	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(buf.Bytes()), true)
+	if err != nil || listType != m3u8.MASTER {
+		return "", errors.New("m3u8 not of media type")
+	}
+
+	audio := from.(*m3u8.MasterPlaylist)
+
+	var audioPriority = []string{"audio-atmos", "audio-ac3", "audio-stereo-256"}
+	switch d.cfg.MVAudioType {
+	case "ac3":
+		audioPriority = []string{"audio-ac3", "audio-stereo-256"}
+	case "aac":
+		audioPriority = []string{"audio-stereo-256"}
+	}
+
+	re := regexp.MustCompile(`_gr(\d+)_`)
+
+	type AudioStream struct {
+		URL     string
+		Rank    int
+		GroupID string
+	}
+	var audioStreams []AudioStream
+
+	for _, variant := range audio.Variants {
+		for _, audiov := range variant.Alternatives {
+			if audiov.URI != "" {
+				for _, priority := range audioPriority {
+					if audiov.GroupId == priority {
+						matches := re.FindStringSubmatch(audiov.URI)
+						if len(matches) == 2 {
+							var rank int
+							fmt.Sscanf(matches[1], "%d", &rank)
+							streamUrl, _ := MediaUrl.Parse(audiov.URI)
+							audioStreams = append(audioStreams, AudioStream{
+								URL:     streamUrl.String(),
+								Rank:    rank,
+								GroupID: audiov.GroupId,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if len(audioStreams) == 0 {
+		return "", errors.New("no suitable audio stream found")
+	}
+
+	sort.Slice(audioStreams, func(i, j int) bool {
+		return audioStreams[i].Rank > audioStreams[j].Rank
+	})
+	fmt.Println("Audio: " + audioStreams[0].GroupID)
+	return audioStreams[0].URL, nil
+}
+
+func (d *Downloader) checkM3u8(ctx context.Context, b string, f string) (string, error) {
+	var EnhancedHls string
+	if d.cfg.GetM3u8FromDevice {
+		adamID := b
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", d.cfg.GetM3u8Port)
+		if err != nil {
+			fmt.Println("Error connecting to device:", err)
+			return "none", err
+		}
+		defer conn.Close()
+		if f == "song" {
+			fmt.Println("Connected to device")
+		}
+
+		adamIDBuffer := []byte(adamID)
+		lengthBuffer := []byte{byte(len(adamIDBuffer))}
+
+		_, err = conn.Write(lengthBuffer)
+		if err != nil {
+			fmt.Println("Error writing length to device:", err)
+			return "none", err
+		}
+
+		_, err = conn.Write(adamIDBuffer)
+		if err != nil {
+			fmt.Println("Error writing adamID to device:", err)
+			return "none", err
+		}
+
+		response, err := bufio.NewReader(conn).ReadBytes('\n')
+		if err != nil {
+			fmt.Println("Error reading response from device:", err)
+			return "none", err
+		}
+
+		response = bytes.TrimSpace(response)
+		if len(response) > 0 {
+			if f == "song" {
+				fmt.Println("Received URL:", string(response))
+			}
+			EnhancedHls = string(response)
+		} else {
+			fmt.Println("Received an empty response")
+		}
+	}
+	return EnhancedHls, nil
+}
+func (d *Downloader) extractMedia(ctx context.Context, b string, more_mode bool) (string, string, string, error) {
+	masterUrl, err := url.Parse(b)
+	if err != nil {
+		return "", "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", b, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", "", errors.New(resp.Status)
+	}
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return "", "", "", err
+	}
+	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(buf.Bytes()), true)
+	if err != nil || listType != m3u8.MASTER {
+		return "", "", "", errors.New("m3u8 not of master type")
+	}
+	master := from.(*m3u8.MasterPlaylist)
+	var streamUrl *url.URL
+	sort.Slice(master.Variants, func(i, j int) bool {
+		return master.Variants[i].AverageBandwidth > master.Variants[j].AverageBandwidth
+	})
+	if d.debug && more_mode {
+		fmt.Println("\nDebug: All Available Variants:")
+		var data [][]string
+		for _, variant := range master.Variants {
+			data = append(data, []string{variant.Codecs, variant.Audio, fmt.Sprint(variant.Bandwidth)})
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Codec", "Audio", "Bandwidth"})
+		table.SetAutoMergeCells(true)
+		table.SetRowLine(true)
+		table.AppendBulk(data)
+		table.Render()
+
+		var hasAAC, hasLossless, hasHiRes, hasAtmos, hasDolbyAudio bool
+		var aacQuality, losslessQuality, hiResQuality, atmosQuality, dolbyAudioQuality string
+
+		for _, variant := range master.Variants {
+			if variant.Codecs == "mp4a.40.2" { // AAC
+				hasAAC = true
+				split := strings.Split(variant.Audio, "-")
+				if len(split) >= 3 {
+					bitrate, _ := strconv.Atoi(split[2])
+					currentBitrate := 0
+					if aacQuality != "" {
+						current := strings.Split(aacQuality, " | ")[2]
+						current = strings.Split(current, " ")[0]
+						currentBitrate, _ = strconv.Atoi(current)
+					}
+					if bitrate > currentBitrate {
+						aacQuality = fmt.Sprintf("AAC | 2 Channel | %d Kbps", bitrate)
+					}
+				}
+			} else if variant.Codecs == "ec-3" && strings.Contains(variant.Audio, "atmos") { // Dolby Atmos
+				hasAtmos = true
+				split := strings.Split(variant.Audio, "-")
+				if len(split) > 0 {
+					bitrateStr := split[len(split)-1]
+					if len(bitrateStr) == 4 && bitrateStr[0] == '2' {
+						bitrateStr = bitrateStr[1:]
+					}
+					bitrate, _ := strconv.Atoi(bitrateStr)
+					currentBitrate := 0
+					if atmosQuality != "" {
+						current := strings.Split(strings.Split(atmosQuality, " | ")[2], " ")[0]
+						currentBitrate, _ = strconv.Atoi(current)
+					}
+					if bitrate > currentBitrate {
+						atmosQuality = fmt.Sprintf("E-AC-3 | 16 Channel | %d Kbps", bitrate)
+					}
+				}
+			} else if variant.Codecs == "alac" { // ALAC (Lossless or Hi-Res)
+				split := strings.Split(variant.Audio, "-")
+				if len(split) >= 3 {
+					bitDepth := split[len(split)-1]
+					sampleRate := split[len(split)-2]
+					sampleRateInt, _ := strconv.Atoi(sampleRate)
+					if sampleRateInt > 48000 { // Hi-Res
+						hasHiRes = true
+						hiResQuality = fmt.Sprintf("ALAC | 2 Channel | %s-bit/%d kHz", bitDepth, sampleRateInt/1000)
+					} else { // Standard Lossless
+						hasLossless = true
+						losslessQuality = fmt.Sprintf("ALAC | 2 Channel | %s-bit/%d kHz", bitDepth, sampleRateInt/1000)
+					}
+				}
+			} else if variant.Codecs == "ac-3" { // Dolby Audio
+				hasDolbyAudio = true
+				split := strings.Split(variant.Audio, "-")
+				if len(split) > 0 {
+					bitrate, _ := strconv.Atoi(split[len(split)-1])
+					dolbyAudioQuality = fmt.Sprintf("AC-3 |  16 Channel | %d Kbps", bitrate)
+				}
+			}
+		}
+
+		fmt.Println("Available Audio Formats:")
+		fmt.Println("------------------------")
+		fmt.Printf("AAC             : %s\n", formatAvailability(hasAAC, aacQuality))
+		fmt.Printf("Lossless        : %s\n", formatAvailability(hasLossless, losslessQuality))
+		fmt.Printf("Hi-Res Lossless : %s\n", formatAvailability(hasHiRes, hiResQuality))
+		fmt.Printf("Dolby Atmos     : %s\n", formatAvailability(hasAtmos, atmosQuality))
+		fmt.Printf("Dolby Audio     : %s\n", formatAvailability(hasDolbyAudio, dolbyAudioQuality))
+		fmt.Println("------------------------")
+
+		return "", "", "", nil
+	}
+	var Quality string
+	codecVariants := make(map[string][]*m3u8.Variant)
+	for _, v := range master.Variants {
+		codecVariants[v.Codecs] = append(codecVariants[v.Codecs], v)
+	}
+
+	var variant *m3u8.Variant
+	variant = codecAlternative(codecVariants, d.cfg.CodecPriority)
+	if variant != nil {
+		// Do not mutate global download preference flags here (they represent user/runtime choice).
+		// Instead return the codec name to the caller for per-entity decision.
+		fmt.Println("DEBUG: Selected codec:", variant.Codecs)
+		streamUrl, err = masterUrl.Parse(variant.URI)
+		if err != nil {
+			return "", "", "", err
+		}
+		Quality = variantQuality(variant)
+	}
+
+	if streamUrl == nil {
+		return "", "", "", errors.New("no codec found")
+	}
+	codecName := ""
+	if variant != nil {
+		codecName = variant.Codecs
+	}
+	return streamUrl.String(), Quality, codecName, nil
+}
+
+// variantQuality 音轨的音质描述：ALAC 为 "24B-96.0kHz"，其余为码率 "256Kbps"
+func variantQuality(v *m3u8.Variant) string {
+	split := strings.Split(v.Audio, "-")
+	if v.Codecs == "alac" && len(split) >= 3 {
+		rate, err := strconv.Atoi(split[len(split)-2])
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%sB-%.1fkHz", split[len(split)-1], float64(rate)/1000.0)
+	}
+	if bitrate, err := strconv.Atoi(split[len(split)-1]); err == nil {
+		if len(split[len(split)-1]) == 4 && bitrate >= 2000 && v.Codecs == "ec-3" {
+			bitrate -= 2000
+		}
+		return fmt.Sprintf("%dKbps", bitrate)
+	}
+	return ""
+}
+
+func (d *Downloader) extractVideo(ctx context.Context, c string) (string, error) {
+	MediaUrl, err := url.Parse(c)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return "", err
+	}
+	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(buf.Bytes()), true)
+	if err != nil || listType != m3u8.MASTER {
+		return "", errors.New("m3u8 not of media type")
+	}
+
+	video := from.(*m3u8.MasterPlaylist)
+
+	re := regexp.MustCompile(`_(\d+)x(\d+)`)
+
+	var streamUrl *url.URL
+	sort.Slice(video.Variants, func(i, j int) bool {
+		return video.Variants[i].AverageBandwidth > video.Variants[j].AverageBandwidth
+	})
+
+	maxHeight := d.cfg.MVMax
+
+	for _, variant := range video.Variants {
+		matches := re.FindStringSubmatch(variant.URI)
+		if len(matches) == 3 {
+			height := matches[2]
+			var h int
+			_, err := fmt.Sscanf(height, "%d", &h)
+			if err != nil {
+				continue
+			}
+			if h <= maxHeight {
+				streamUrl, err = MediaUrl.Parse(variant.URI)
+				if err != nil {
+					return "", err
+				}
+				fmt.Println("Video: " + variant.Resolution + "-" + variant.VideoRange)
+				break
+			}
+		}
+	}
+
+	if streamUrl == nil {
+		return "", errors.New("no suitable video stream found")
+	}
+
+	return streamUrl.String(), nil
+}
+
+func codecAlternative(availableCodecs map[string][]*m3u8.Variant, priority []string) *m3u8.Variant {
+	for _, codec := range priority {
+		if variants, ok := availableCodecs[codec]; ok {
+			if len(variants) > 0 {
+				return variants[0]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"main/utils/ampapi"
+	"main/utils/runv3"
+	"main/utils/task"
+)
+
+// mvSavePath MV 的输出文件：专辑/歌单中的 MV 与歌曲同样使用 song-file-format，单独下载时使用 mv-file-format
+func (j *job) mvSavePath(saveDir string, mv ampapi.MusicVideoRespData, track *task.Track) string {
+	saveDir = strings.TrimSpace(saveDir)
+	var mvSaveName string
+	if track != nil {
+		fields := j.trackFields(track)
+		fields.SongName, fields.Codec = j.limitString(mv.Attributes.Name), "MV"
+		mvSaveName = j.render("song-file-format", j.cfg.SongFileFormat, fields)
+	} else {
+		mvSaveName = j.render("mv-file-format", j.cfg.MVFileFormat, j.musicVideoFields(mv))
+	}
+	mvSaveName = j.safeName(saveDir, mvSaveName, extReserve)
+	return filepath.Join(saveDir, fmt.Sprintf("%s.mp4", mvSaveName))
+}
+
+// planMV DryRun 时 MV 将要写入的文件
+func (j *job) planMV(ctx context.Context, adamID string, saveDir string, token string, storefront string, track *task.Track) (string, error) {
+	MVInfo, err := ampapi.GetMusicVideoResp(ctx, storefront, adamID, j.cfg.Language, token)
+	if err != nil {
+		return "", err
+	}
+	if len(MVInfo.Data) == 0 {
+		return "", fmt.Errorf("music video %s not found", adamID)
+	}
+	return j.mvSavePath(saveDir, MVInfo.Data[0], track), nil
+}
+
+func (j *job) mvDownloader(ctx context.Context, adamID string, saveDir string, token string, storefront string, mediaUserToken string, track *task.Track) error {
+	MVInfo, err := ampapi.GetMusicVideoResp(ctx, storefront, adamID, j.cfg.Language, token)
+	if err != nil {
+		fmt.Println("\u26A0 Failed to get MV manifest:", err)
+		return err
+	}
+
+	saveDir = strings.TrimSpace(saveDir)
+
+	vidPath := filepath.Join(saveDir, fmt.Sprintf("%s_vid.mp4", adamID))
+	audPath := filepath.Join(saveDir, fmt.Sprintf("%s_aud.mp4", adamID))
+	mvOutPath := j.mvSavePath(saveDir, MVInfo.Data[0], track)
+	if track != nil {
+		track.SavePath = mvOutPath
+	}
+
+	fmt.Println(MVInfo.Data[0].Attributes.Name)
+
+	exists, _ := fileExists(mvOutPath)
+	if exists {
+		fmt.Println("MV already exists locally.")
+		return nil
+	}
+
+	mvm3u8url, _, _, _ := runv3.GetWebplayback(ctx, adamID, token, mediaUserToken, true)
+	if mvm3u8url == "" {
+		return errors.New("media-user-token may wrong or expired")
+	}
+
+	if err := os.MkdirAll(saveDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create saveDir '%s': %w", saveDir, err)
+	}
+	videom3u8url, _ := j.extractVideo(ctx, mvm3u8url)
+	videokeyAndUrls, _ := runv3.Run(ctx, adamID, videom3u8url, token, mediaUserToken, true, "", j.bus)
+	_ = runv3.ExtMvData(ctx, videokeyAndUrls, vidPath, j.cfg.MVSegmentConcurrency, j.bus)
+	defer os.Remove(vidPath)
+	audiom3u8url, _ := j.extractMvAudio(ctx, mvm3u8url)
+	audiokeyAndUrls, _ := runv3.Run(ctx, adamID, audiom3u8url, token, mediaUserToken, true, "", j.bus)
+	_ = runv3.ExtMvData(ctx, audiokeyAndUrls, audPath, j.cfg.MVSegmentConcurrency, j.bus)
+	defer os.Remove(audPath)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tags := []string{
+		"tool=",
+		fmt.Sprintf("artist=%s", MVInfo.Data[0].Attributes.ArtistName),
+		fmt.Sprintf("title=%s", MVInfo.Data[0].Attributes.Name),
+		fmt.Sprintf("genre=%s", MVInfo.Data[0].Attributes.GenreNames[0]),
+		fmt.Sprintf("created=%s", MVInfo.Data[0].Attributes.ReleaseDate),
+		fmt.Sprintf("ISRC=%s", MVInfo.Data[0].Attributes.Isrc),
+	}
+
+	switch MVInfo.Data[0].Attributes.ContentRating {
+	case "explicit":
+		tags = append(tags, "rating=1")
+	case "clean":
+		tags = append(tags, "rating=2")
+	default:
+		tags = append(tags, "rating=0")
+	}
+
+	if track != nil {
+		if track.PreType == "playlists" && !j.cfg.UseSongInfoForPlaylist {
+			tags = append(tags, "disk=1/1")
+			tags = append(tags, fmt.Sprintf("album=%s", track.PlaylistData.Attributes.Name))
+			tags = append(tags, fmt.Sprintf("track=%d", track.TaskNum))
+			tags = append(tags, fmt.Sprintf("tracknum=%d/%d", track.TaskNum, track.TaskTotal))
+			tags = append(tags, fmt.Sprintf("album_artist=%s", track.PlaylistData.Attributes.ArtistName))
+			tags = append(tags, fmt.Sprintf("performer=%s", track.Resp.Attributes.ArtistName))
+		} else if track.PreType == "playlists" && j.cfg.UseSongInfoForPlaylist {
+			tags = append(tags, fmt.Sprintf("album=%s", track.AlbumData.Attributes.Name))
+			tags = append(tags, fmt.Sprintf("disk=%d/%d", track.Resp.Attributes.DiscNumber, track.DiscTotal))
+			tags = append(tags, fmt.Sprintf("track=%d", track.Resp.Attributes.TrackNumber))
+			tags = append(tags, fmt.Sprintf("tracknum=%d/%d", track.Resp.Attributes.TrackNumber, track.AlbumData.Attributes.TrackCount))
+			tags = append(tags, fmt.Sprintf("album_artist=%s", track.AlbumData.Attributes.ArtistName))
+			tags = append(tags, fmt.Sprintf("performer=%s", track.Resp.Attributes.ArtistName))
+			tags = append(tags, fmt.Sprintf("copyright=%s", track.AlbumData.Attributes.Copyright))
+			tags = append(tags, fmt.Sprintf("UPC=%s", track.AlbumData.Attributes.Upc))
+		} else {
+			tags = append(tags, fmt.Sprintf("album=%s", track.AlbumData.Attributes.Name))
+			tags = append(tags, fmt.Sprintf("disk=%d/%d", track.Resp.Attributes.DiscNumber, track.DiscTotal))
+			tags = append(tags, fmt.Sprintf("track=%d", track.Resp.Attributes.TrackNumber))
+			tags = append(tags, fmt.Sprintf("tracknum=%d/%d", track.Resp.Attributes.TrackNumber, track.AlbumData.Attributes.TrackCount))
+			tags = append(tags, fmt.Sprintf("album_artist=%s", track.AlbumData.Attributes.ArtistName))
+			tags = append(tags, fmt.Sprintf("performer=%s", track.Resp.Attributes.ArtistName))
+			tags = append(tags, fmt.Sprintf("copyright=%s", track.AlbumData.Attributes.Copyright))
+			tags = append(tags, fmt.Sprintf("UPC=%s", track.AlbumData.Attributes.Upc))
+		}
+	} else {
+		tags = append(tags, fmt.Sprintf("album=%s", MVInfo.Data[0].Attributes.AlbumName))
+		tags = append(tags, fmt.Sprintf("disk=%d", MVInfo.Data[0].Attributes.DiscNumber))
+		tags = append(tags, fmt.Sprintf("track=%d", MVInfo.Data[0].Attributes.TrackNumber))
+		tags = append(tags, fmt.Sprintf("tracknum=%d", MVInfo.Data[0].Attributes.TrackNumber))
+		tags = append(tags, fmt.Sprintf("performer=%s", MVInfo.Data[0].Attributes.ArtistName))
+	}
+
+	var covPath string
+	if true {
+		thumbURL := MVInfo.Data[0].Attributes.Artwork.URL
+		baseThumbName := strings.TrimSuffix(filepath.Base(mvOutPath), ".mp4") + "_thumbnail"
+		covPath, err = j.writeCover(ctx, saveDir, baseThumbName, thumbURL)
+		if err != nil {
+			fmt.Println("Failed to save MV thumbnail:", err)
+		} else {
+			tags = append(tags, fmt.Sprintf("cover=%s", covPath))
+		}
+	}
+	defer os.Remove(covPath)
+
+	tagsString := strings.Join(tags, ":")
+	muxCmdArgs := []string{"-itags", tagsString, "-quiet", "-add", vidPath, "-add", audPath, "-keep-utc", "-new"}
+	fmt.Printf("MV Remuxing...")
+	j.acquireTagSlot()
+	if err := runCmdOutput(ctx, 30*time.Minute, mvOutPath, "MP4Box", muxCmdArgs...); err != nil {
+		fmt.Printf("MV mux failed: %v\n", err)
+		j.releaseTagSlot()
+		return err
+	}
+	j.releaseTagSlot()
+	fmt.Printf("\\rMV Remuxed.   \n")
+	return nil
+}
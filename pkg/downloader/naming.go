@@ -0,0 +1,192 @@
+package downloader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"main/utils/ampapi"
+	"main/utils/naming"
+	"main/utils/task"
+)
+
+// render 渲染目录/文件名模板；模板出错时记一条警告并退回到只替换旧占位符
+func (d *Downloader) render(key string, format string, f naming.Fields) string {
+	s, err := naming.Render(format, f)
+	if err != nil {
+		d.AddWarning(fmt.Sprintf("%s: %v", key, err))
+		return naming.Replace(format, f)
+	}
+	return s
+}
+
+// artistFolder 渲染 artist-folder-format，opts 中的艺术家信息优先；未设置该格式时返回空
+func (j *job) artistFolder(f naming.Fields) string {
+	if j.cfg.ArtistFolderFormat == "" {
+		return ""
+	}
+	if j.opts.ArtistName != "" {
+		f.UrlArtistName, f.ArtistId = j.limitString(j.opts.ArtistName), j.opts.ArtistID
+	}
+	return strings.TrimSpace(j.render("artist-folder-format", j.cfg.ArtistFolderFormat, f))
+}
+
+// discFolder 多碟专辑中第 disc 张碟的目录；未设置 disc-folder-format 或只有一张碟时为专辑目录本身
+func (j *job) discFolder(albumFolder string, f naming.Fields, disc int) string {
+	if j.cfg.DiscFolderFormat == "" || f.DiscTotal <= 1 {
+		return albumFolder
+	}
+	f.DiscNumber = disc
+	name := strings.TrimSpace(j.render("disc-folder-format", j.cfg.DiscFolderFormat, f))
+	if name == "" {
+		return albumFolder
+	}
+	return filepath.Join(albumFolder, j.safeName(albumFolder, name, 0))
+}
+
+func firstOf(s []string) string {
+	if len(s) > 0 {
+		return s[0]
+	}
+	return ""
+}
+
+func yearOf(date string) string {
+	if len(date) >= 4 {
+		return date[:4]
+	}
+	return date
+}
+
+// albumFields 专辑层级的字段
+func (d *Downloader) albumFields(a ampapi.AlbumRespData) naming.Fields {
+	f := naming.Fields{
+		ArtistName:    d.limitString(a.Attributes.ArtistName),
+		UrlArtistName: d.limitString(a.Attributes.ArtistName),
+		AlbumName:     d.limitString(a.Attributes.Name),
+		AlbumId:       a.ID,
+		AlbumArtist:   d.limitString(a.Attributes.ArtistName),
+		TrackTotal:    a.Attributes.TrackCount,
+		Genre:         firstOf(a.Attributes.GenreNames),
+		ReleaseDate:   a.Attributes.ReleaseDate,
+		ReleaseYear:   yearOf(a.Attributes.ReleaseDate),
+		UPC:           a.Attributes.Upc,
+		RecordLabel:   a.Attributes.RecordLabel,
+		Copyright:     a.Attributes.Copyright,
+		IsCompilation: a.Attributes.IsCompilation,
+		Explicit:      a.Attributes.ContentRating == "explicit",
+		Clean:         a.Attributes.ContentRating == "clean",
+	}
+	if len(a.Relationships.Artists.Data) > 0 {
+		f.ArtistId = a.Relationships.Artists.Data[0].ID
+	}
+	if tracks := a.Relationships.Tracks.Data; len(tracks) > 0 {
+		f.DiscTotal = tracks[len(tracks)-1].Attributes.DiscNumber
+	}
+	return f
+}
+
+// playlistFields 歌单层级的字段；歌单没有艺术家，ArtistName 为 "Apple Music"
+func (d *Downloader) playlistFields(p ampapi.PlaylistRespData) naming.Fields {
+	return naming.Fields{
+		ArtistName:    "Apple Music",
+		UrlArtistName: "Apple Music",
+		AlbumArtist:   d.limitString(p.Attributes.ArtistName),
+		PlaylistName:  d.limitString(p.Attributes.Name),
+		PlaylistId:    p.ID,
+		TrackTotal:    len(p.Relationships.Tracks.Data),
+		ReleaseDate:   p.Attributes.ReleaseDate,
+		ReleaseYear:   yearOf(p.Attributes.ReleaseDate),
+		Explicit:      p.Attributes.ContentRating == "explicit",
+		Clean:         p.Attributes.ContentRating == "clean",
+	}
+}
+
+// trackFields 曲目的字段：专辑字段加上曲目自身信息，歌单中的曲目同时带有歌单字段
+func (d *Downloader) trackFields(track *task.Track) naming.Fields {
+	a := track.Resp.Attributes
+	var f naming.Fields
+	if track.AlbumData.ID != "" {
+		f = d.albumFields(track.AlbumData)
+	} else {
+		f.AlbumName = d.limitString(a.AlbumName)
+		f.ReleaseDate, f.ReleaseYear = a.ReleaseDate, yearOf(a.ReleaseDate)
+	}
+	// 按专辑目录保存的歌单曲目与下载整张专辑时同名
+	if (track.PreType == "playlists" || track.PreType == "stations") && !d.libraryTrack(track) {
+		f.PlaylistName = d.limitString(track.PlaylistData.Attributes.Name)
+		f.PlaylistId = track.PreID
+	}
+	if track.DiscTotal > 0 {
+		f.DiscTotal = track.DiscTotal
+	}
+	f.ArtistName = d.limitString(a.ArtistName)
+	if f.UrlArtistName == "" {
+		f.UrlArtistName = f.ArtistName
+	}
+	if len(track.Resp.Relationships.Artists.Data) > 0 {
+		f.ArtistId = track.Resp.Relationships.Artists.Data[0].ID
+	}
+	f.SongName = d.limitString(a.Name)
+	f.SongId = track.ID
+	f.SongNumber = track.TaskNum
+	if d.libraryTrack(track) {
+		f.SongNumber = albumPosition(track)
+	}
+	f.DiscNumber = a.DiscNumber
+	f.TrackNumber = a.TrackNumber
+	if g := firstOf(a.GenreNames); g != "" {
+		f.Genre = g
+	}
+	f.Composer = a.ComposerName
+	f.ISRC = a.Isrc
+	f.Explicit = a.ContentRating == "explicit"
+	f.Clean = a.ContentRating == "clean"
+	f.Codec = track.Codec
+	f.Quality = track.Quality
+	return f
+}
+
+// musicVideoFields 单独下载的 MV 的字段
+func (d *Downloader) musicVideoFields(mv ampapi.MusicVideoRespData) naming.Fields {
+	a := mv.Attributes
+	f := naming.Fields{
+		ArtistName:    d.limitString(a.ArtistName),
+		UrlArtistName: d.limitString(a.ArtistName),
+		AlbumName:     d.limitString(a.AlbumName),
+		SongName:      d.limitString(a.Name),
+		SongId:        mv.ID,
+		SongNumber:    a.TrackNumber,
+		DiscNumber:    a.DiscNumber,
+		TrackNumber:   a.TrackNumber,
+		Genre:         firstOf(a.GenreNames),
+		ISRC:          a.Isrc,
+		ReleaseDate:   a.ReleaseDate,
+		ReleaseYear:   yearOf(a.ReleaseDate),
+		Codec:         "MV",
+		Explicit:      a.ContentRating == "explicit",
+		Clean:         a.ContentRating == "clean",
+	}
+	if len(mv.Relationships.Artists.Data) > 0 {
+		f.ArtistId = mv.Relationships.Artists.Data[0].ID
+	}
+	return f
+}
+
+// sampleRate 由音质描述与编码推断采样率：ALAC 取自 "24B-96.0kHz"，AAC 为 44.1kHz，杜比为 48kHz
+func sampleRate(quality string, atmos, aac bool) int {
+	switch {
+	case atmos:
+		return 48000
+	case aac:
+		return 44100
+	}
+	if i := strings.Index(quality, "B-"); i >= 0 {
+		khz, err := strconv.ParseFloat(strings.TrimSuffix(quality[i+2:], "kHz"), 64)
+		if err == nil {
+			return int(khz * 1000)
+		}
+	}
+	return 0
+}
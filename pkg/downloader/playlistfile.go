@@ -0,0 +1,171 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"main/utils/atomicfile"
+	"main/utils/store"
+	"main/utils/task"
+)
+
+// playlistEntry 播放列表中的一首曲目
+type playlistEntry struct {
+	Path     string // 相对于播放列表文件
+	Title    string
+	Artist   string
+	Album    string
+	Duration int // 毫秒
+}
+
+// trackFilePath 曲目的最终文件（转换后的文件优先于 m4a）；本次未下载时取下载数据库中的记录
+func (d *Downloader) trackFilePath(track *task.Track) string {
+	if track.SavePath != "" {
+		return track.SavePath
+	}
+	if d.db == nil {
+		return ""
+	}
+	rec, err := d.db.GetTrack(track.PreID, track.ID)
+	if err != nil || rec == nil || rec.Status != store.StatusOK || rec.Path == "" {
+		return ""
+	}
+	if exists, err := fileExists(rec.Path); err != nil || !exists {
+		return ""
+	}
+	return rec.Path
+}
+
+// writePlaylistFiles 按 playlist-files 在 folder 旁边写入 <folder>.m3u8 / .xspf，
+// 曲目按专辑/歌单中的顺序排列，每次下载后整体重新生成；playlist sync 时未设置则写 m3u8。
+// playlist-layout: library 时写在歌单目录中，未设置且不建立链接时同样写 m3u8
+func (j *job) writePlaylistFiles(folder string, title string, tracks []task.Track) {
+	formats := j.cfg.PlaylistFiles
+	library := j.libraryPlaylist(tracks)
+	if len(formats) == 0 && (j.sync != nil || library && j.cfg.PlaylistLinks != "symlink" && j.cfg.PlaylistLinks != "hardlink") {
+		formats = []string{"m3u8"}
+	}
+	if len(formats) == 0 || j.dryRun {
+		return
+	}
+	base := folder
+	if library {
+		base = filepath.Join(folder, filepath.Base(folder))
+	}
+	dir := filepath.Dir(base)
+	var entries []playlistEntry
+	for i := range tracks {
+		path := j.trackFilePath(&tracks[i])
+		if path == "" {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		a := tracks[i].Resp.Attributes
+		entries = append(entries, playlistEntry{
+			Path:     filepath.ToSlash(rel),
+			Title:    a.Name,
+			Artist:   a.ArtistName,
+			Album:    a.AlbumName,
+			Duration: a.DurationInMillis,
+		})
+	}
+	if len(entries) == 0 {
+		return
+	}
+	for _, format := range formats {
+		var data []byte
+		var err error
+		switch strings.ToLower(format) {
+		case "m3u8":
+			data = m3u8Playlist(title, entries)
+		case "xspf":
+			data, err = xspfPlaylist(title, entries)
+		default:
+			continue
+		}
+		path := base + "." + strings.ToLower(format)
+		if err == nil {
+			err = atomicfile.WriteFile(path, data, 0666)
+		}
+		if err != nil {
+			fmt.Println("Failed to write playlist file:", err)
+			j.AddWarning(fmt.Sprintf("playlist file %s: %v", path, err))
+			continue
+		}
+		fmt.Println("Playlist file written:", path)
+	}
+}
+
+// m3u8Playlist 扩展 M3U（UTF-8），#EXTINF 中的时长以秒为单位
+func m3u8Playlist(title string, entries []playlistEntry) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#PLAYLIST:%s\n", title)
+	for _, e := range entries {
+		fmt.Fprintf(&b, "#EXTINF:%d,%s - %s\n", (e.Duration+500)/1000, e.Artist, e.Title)
+		b.WriteString(e.Path + "\n")
+	}
+	return b.Bytes()
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	Duration int    `xml:"duration,omitempty"`
+}
+
+type xspfDoc struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// xspfLocation 曲目路径（斜杠分隔）转为 location：相对路径为相对 URI，
+// 无法取得相对路径时（如 Windows 上位于其他盘符）为 file URI
+func xspfLocation(p string) string {
+	// 按字符判断盘符，不依赖当前系统的 filepath 规则
+	drive := len(p) >= 2 && p[1] == ':' && (p[0] >= 'A' && p[0] <= 'Z' || p[0] >= 'a' && p[0] <= 'z') && (len(p) == 2 || p[2] == '/')
+	if path.IsAbs(p) || drive {
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		return (&url.URL{Scheme: "file", Path: p}).String()
+	}
+	loc := (&url.URL{Path: p}).EscapedPath()
+	// 第一段含冒号的相对 URI 会被解析为 scheme
+	if first, _, _ := strings.Cut(loc, "/"); strings.Contains(first, ":") {
+		loc = "./" + loc
+	}
+	return loc
+}
+
+// xspfPlaylist XSPF 播放列表，location 为相对 URI
+func xspfPlaylist(title string, entries []playlistEntry) ([]byte, error) {
+	doc := xspfDoc{Version: "1", Xmlns: "http://xspf.org/ns/0/", Title: title}
+	for _, e := range entries {
+		doc.Tracks = append(doc.Tracks, xspfTrack{
+			Location: xspfLocation(e.Path),
+			Title:    e.Title,
+			Creator:  e.Artist,
+			Album:    e.Album,
+			Duration: e.Duration,
+		})
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
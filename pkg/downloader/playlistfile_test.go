@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"net/url"
+	"testing"
+)
+
+var testEntries = []playlistEntry{
+	{Path: "Artist/Album/01. Song.m4a", Title: "Song", Artist: "Artist", Album: "Album", Duration: 215499},
+	{Path: "../Other/02. Déjà vu #1.flac", Title: "Déjà vu #1", Artist: "Other", Album: "B", Duration: 500},
+}
+
+func TestM3U8Playlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		entries []playlistEntry
+		want    string
+	}{
+		{"empty", "Empty", nil, "#EXTM3U\n#PLAYLIST:Empty\n"},
+		{
+			"tracks", "Mix", testEntries,
+			"#EXTM3U\n#PLAYLIST:Mix\n" +
+				"#EXTINF:215,Artist - Song\nArtist/Album/01. Song.m4a\n" +
+				"#EXTINF:1,Other - Déjà vu #1\n../Other/02. Déjà vu #1.flac\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(m3u8Playlist(tt.title, tt.entries)); got != tt.want {
+				t.Errorf("m3u8Playlist\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXSPFLocation(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"Artist/01. Song.m4a", "Artist/01.%20Song.m4a"},
+		{"../Other/Déjà vu #1.flac", "../Other/D%C3%A9j%C3%A0%20vu%20%231.flac"},
+		{"Artist: Live/01.m4a", "./Artist:%20Live/01.m4a"},
+		{"Artist/a:b.m4a", "Artist/a:b.m4a"},
+		{"X: Live.m4a", "./X:%20Live.m4a"},
+		{"/music/a b.m4a", "file:///music/a%20b.m4a"},
+		{"C:/Music/a.m4a", "file:///C:/Music/a.m4a"},
+	}
+	for _, tt := range tests {
+		got := xspfLocation(tt.path)
+		if got != tt.want {
+			t.Errorf("xspfLocation(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		// 相对 location 解析后仍是原路径
+		if u, err := url.Parse(got); err != nil {
+			t.Errorf("url.Parse(%q): %v", got, err)
+		} else if u.Scheme == "" && u.Path != tt.path && u.Path != "./"+tt.path {
+			t.Errorf("xspfLocation(%q) parses back to %q", tt.path, u.Path)
+		}
+	}
+}
+
+func TestXSPFPlaylist(t *testing.T) {
+	data, err := xspfPlaylist("Mix & More", testEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc xspfDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, data)
+	}
+	if doc.Title != "Mix & More" || doc.Version != "1" || doc.Xmlns != "http://xspf.org/ns/0/" {
+		t.Errorf("header = %+v", doc)
+	}
+	want := []xspfTrack{
+		{Location: "Artist/Album/01.%20Song.m4a", Title: "Song", Creator: "Artist", Album: "Album", Duration: 215499},
+		{Location: "../Other/02.%20D%C3%A9j%C3%A0%20vu%20%231.flac", Title: "Déjà vu #1", Creator: "Other", Album: "B", Duration: 500},
+	}
+	if len(doc.Tracks) != len(want) {
+		t.Fatalf("got %d track(s), want %d", len(doc.Tracks), len(want))
+	}
+	for i := range want {
+		if doc.Tracks[i] != want[i] {
+			t.Errorf("track %d = %+v, want %+v", i, doc.Tracks[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,1119 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"main/utils/ampapi"
+	"main/utils/atomicfile"
+	"main/utils/events"
+	"main/utils/naming"
+	"main/utils/runv3"
+	"main/utils/task"
+)
+
+// ripStation 下载电台；直播流电台整体作为一首曲目保存
+func (j *job) ripStation(ctx context.Context, albumId string, token string, storefront string, mediaUserToken string) error {
+	station := task.NewStation(storefront, albumId)
+	err := station.GetResp(ctx, mediaUserToken, token, j.cfg.Language)
+	if err != nil {
+		return err
+	}
+	fmt.Println(" -", station.Type)
+	meta := station.Resp
+
+	// Make a local mutable copy of user/runtime codec preferences to avoid concurrent writes
+	localDlAtmos := j.atmos
+	localDlAac := j.aac
+	var Codec string
+	if localDlAtmos {
+		Codec = "ATMOS"
+	} else if localDlAac {
+		Codec = "AAC"
+	} else {
+		Codec = "ALAC"
+	}
+	station.Codec = Codec
+	fields := naming.Fields{
+		ArtistName:    "Apple Music Station",
+		UrlArtistName: "Apple Music Station",
+		PlaylistName:  j.limitString(station.Name),
+		PlaylistId:    station.ID,
+		Codec:         Codec,
+	}
+	singerFoldername := j.artistFolder(fields)
+	if singerFoldername != "" {
+		fmt.Println(singerFoldername)
+	}
+	// 使用统一的输出根目录，不再根据 codec 分类保存目录
+	singerFolder := filepath.Join(j.outputFolder, j.safeName(j.outputFolder, singerFoldername, 0))
+	if err := j.mkdirAll(singerFolder); err != nil {
+		return fmt.Errorf("failed to create singer folder '%s': %w", singerFolder, err)
+	}
+	station.SaveDir = singerFolder
+
+	playlistFolder := j.render("playlist-folder-format", j.cfg.PlaylistFolderFormat, fields)
+	playlistFolder = strings.TrimSpace(playlistFolder)
+	playlistFolderPath := filepath.Join(singerFolder, j.safeName(singerFolder, playlistFolder, 0))
+	if err := j.mkdirAll(playlistFolderPath); err != nil {
+		return fmt.Errorf("failed to create playlist folder '%s': %w", playlistFolderPath, err)
+	}
+	station.SaveName = playlistFolder
+	j.res.Name, j.res.Dir = station.Name, playlistFolderPath
+	fmt.Println(playlistFolder)
+
+	covPath, err := j.saveCover(ctx, playlistFolderPath, "cover", meta.Data[0].Attributes.Artwork.URL)
+	if err != nil {
+		fmt.Println("Failed to write cover.")
+	}
+	station.CoverPath = covPath
+
+	if j.cfg.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionSquare.Video != "" {
+		fmt.Println("Found Animation Artwork.")
+
+		motionvideoUrlSquare, err := j.extractVideo(ctx, meta.Data[0].Attributes.EditorialVideo.MotionSquare.Video)
+		if err != nil {
+			fmt.Println("no motion video square.\n", err)
+		} else {
+			exists, err := fileExists(filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"))
+			if err != nil {
+				fmt.Println("Failed to check if animated artwork square exists.")
+			}
+			if exists {
+				fmt.Println("Animated artwork square already exists locally.")
+			} else {
+				fmt.Println("Animation Artwork Square Downloading...")
+				if err := j.saveArtwork(ctx, 2*time.Minute, filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"), "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy"); err != nil {
+					fmt.Printf("animated artwork square dl err: %v\n", err)
+				} else {
+					fmt.Println("Animation Artwork Square Downloaded")
+				}
+			}
+		}
+
+		if j.cfg.EmbyAnimatedArtwork {
+			if err := j.saveArtwork(ctx, 1*time.Minute, filepath.Join(playlistFolderPath, "folder.jpg"), "ffmpeg", "-i", filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif"); err != nil {
+				fmt.Printf("animated artwork square to gif err: %v\n", err)
+			}
+		}
+	}
+	if station.Type == "stream" {
+		// 直播流整体作为一首曲目记入结果
+		stream := TrackResult{Index: 1, ID: station.ID, Name: station.Name, Status: TrackOK, Codec: "AAC", Quality: "256Kbps"}
+		defer func() { j.addTrack(stream) }()
+		j.incTotal()
+		if isInArray(j.getOk(station.ID), 1) || j.dbTrackDone(station.ID, station.ID) {
+			j.incSuccess()
+			stream.Status, stream.Reason = TrackSkipped, "already downloaded"
+			return nil
+		}
+		song := fields
+		song.SongId, song.SongName = station.ID, j.limitString(station.Name)
+		song.SongNumber, song.DiscNumber, song.DiscTotal, song.TrackNumber, song.TrackTotal = 1, 1, 1, 1, 1
+		song.Quality, song.Codec, song.SampleRate = "256Kbps", "AAC", 44100
+		songName := j.render("song-file-format", j.cfg.SongFileFormat, song)
+		fmt.Println(songName)
+		trackPath := filepath.Join(playlistFolderPath, fmt.Sprintf("%s.m4a", j.safeName(playlistFolderPath, songName, extReserve)))
+		stream.Path = trackPath
+		exists, _ := fileExists(trackPath)
+		if exists {
+			j.incSuccess()
+			j.markStreamOk(station, trackPath)
+			stream.Status, stream.Reason = TrackSkipped, "already exists"
+
+			fmt.Println("Radio already exists locally.")
+			return nil
+		}
+		if j.dryRun {
+			stream.Status, stream.Variant = TrackPlanned, "aac-lc"
+			return nil
+		}
+		assetsUrl, serverUrl, err := ampapi.GetStationAssetsUrlAndServerUrl(ctx, station.ID, mediaUserToken, token)
+		if err != nil {
+			fmt.Println("Failed to get station assets url.", err)
+			j.incError()
+			stream.Status, stream.Reason = TrackFailed, fmt.Sprintf("station assets: %v", err)
+			return err
+		}
+		trackM3U8 := strings.ReplaceAll(assetsUrl, "index.m3u8", "256/prog_index.m3u8")
+		keyAndUrls, _ := runv3.Run(ctx, station.ID, trackM3U8, token, mediaUserToken, true, serverUrl, j.bus)
+		partPath := atomicfile.PartPath(trackPath)
+		defer os.Remove(partPath)
+		err = runv3.ExtMvData(ctx, keyAndUrls, partPath, j.cfg.MVSegmentConcurrency, j.bus)
+		if err != nil {
+			fmt.Println("Failed to download station stream.", err)
+			j.incError()
+			stream.Status, stream.Reason = TrackFailed, fmt.Sprintf("download failed: %v", err)
+			return err
+		}
+		tags := []string{
+			"tool=",
+			"disk=1/1",
+			"track=1",
+			"tracknum=1/1",
+			fmt.Sprintf("artist=%s", "Apple Music Station"),
+			fmt.Sprintf("performer=%s", "Apple Music Station"),
+			fmt.Sprintf("album_artist=%s", "Apple Music Station"),
+			fmt.Sprintf("album=%s", station.Name),
+			fmt.Sprintf("title=%s", station.Name),
+		}
+		if j.cfg.EmbedCover {
+			tags = append(tags, fmt.Sprintf("cover=%s", station.CoverPath))
+		}
+		tagsString := strings.Join(tags, ":")
+		if err := runCmdTimeout(ctx, 2*time.Minute, "MP4Box", "-itags", tagsString, partPath); err != nil {
+			fmt.Printf("Embed failed: %v\n", err)
+			if ctx.Err() != nil {
+				j.incError()
+				stream.Status, stream.Reason = TrackFailed, "interrupted"
+				return ctx.Err()
+			}
+		}
+		if err := atomicfile.Rename(partPath, trackPath); err != nil {
+			fmt.Println("Failed to save station stream.", err)
+			j.incError()
+			stream.Status, stream.Reason = TrackFailed, fmt.Sprintf("save failed: %v", err)
+			return err
+		}
+		j.incSuccess()
+		j.markStreamOk(station, trackPath)
+		return nil
+	}
+
+	for i := range station.Tracks {
+		station.Tracks[i].CoverPath = covPath
+		station.Tracks[i].SaveDir = playlistFolderPath
+		station.Tracks[i].Codec = Codec
+	}
+	defer j.writePlaylistFiles(playlistFolderPath, station.Name, station.Tracks)
+	defer j.linkPlaylistTracks(playlistFolderPath, station.Tracks)
+
+	trackTotal := len(station.Tracks)
+	arr := make([]int, trackTotal)
+	for i := 0; i < trackTotal; i++ {
+		arr[i] = i + 1
+	}
+	var selected []int
+	if j.opts.Retry {
+		selected = mergeNums(j.getFail(station.ID), j.dbFailedNums(station.ID, station.Tracks))
+		if len(selected) == 0 {
+			selected = arr
+		}
+	} else {
+		selected = arr
+	}
+	// Worker pool for station tracks
+	type dlJob struct {
+		idx int // 1-based index in station.Tracks
+		seq int // sequential TaskNum within toProcess
+	}
+	var toProcess []int
+	doneList := j.getOk(station.ID)
+	for i := range station.Tracks {
+		num := i + 1
+		if isInArray(doneList, num) || j.dbTrackDone(station.ID, station.Tracks[i].ID) {
+			j.skipTrack(num, &station.Tracks[i])
+			continue
+		}
+		if isInArray(selected, num) {
+			toProcess = append(toProcess, num)
+			j.publishTrack(events.TrackQueued, &station.Tracks[i], "")
+		}
+	}
+	if len(toProcess) == 0 {
+		return nil
+	}
+	var wg sync.WaitGroup
+	workerCount := j.concurrency
+	if workerCount > len(toProcess) {
+		workerCount = len(toProcess)
+	}
+	jobs := make(chan dlJob, workerCount)
+	wg.Add(workerCount)
+	for w := 0; w < workerCount; w++ {
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				tk := &station.Tracks[item.idx-1]
+				tk.TaskNum = item.seq
+				tk.TaskTotal = len(toProcess)
+				log.Printf("Start station track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
+				j.runTrack(ctx, item.idx, tk, token, mediaUserToken)
+				log.Printf("Done  station track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
+			}
+		}()
+	}
+	for seq, idx := range toProcess {
+		tr := station.Tracks[idx-1]
+		log.Printf("Enqueue station track %d/%d: %s - %s", seq+1, len(toProcess), tr.Resp.Attributes.ArtistName, tr.Resp.Attributes.Name)
+		jobs <- dlJob{idx: idx, seq: seq + 1}
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+}
+
+// ripAlbum 下载专辑；opts.TrackID 非空时只下载该曲目
+func (j *job) ripAlbum(ctx context.Context, albumId string, token string, storefront string, mediaUserToken string) error {
+	album := task.NewAlbum(storefront, albumId)
+	err := album.GetResp(ctx, token, j.cfg.Language)
+	if err != nil {
+		fmt.Println("Failed to get album response.")
+		return err
+	}
+	meta := album.Resp
+	if j.debug {
+		fmt.Println(meta.Data[0].Attributes.ArtistName)
+		fmt.Println(meta.Data[0].Attributes.Name)
+
+		for trackNum, track := range meta.Data[0].Relationships.Tracks.Data {
+			trackNum++
+			fmt.Printf("\nTrack %d of %d:\n", trackNum, len(meta.Data[0].Relationships.Tracks.Data))
+			fmt.Printf("%02d. %s\n", trackNum, track.Attributes.Name)
+
+			manifest, err := ampapi.GetSongResp(ctx, storefront, track.ID, album.Language, token)
+			if err != nil {
+				fmt.Printf("Failed to get manifest for track %d: %v\n", trackNum, err)
+				continue
+			}
+
+			var m3u8Url string
+			if manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls != "" {
+				m3u8Url = manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls
+			}
+			needCheck := false
+			if j.cfg.GetM3u8Mode == "all" {
+				needCheck = true
+			} else if j.cfg.GetM3u8Mode == "hires" && contains(track.Attributes.AudioTraits, "hi-res-lossless") {
+				needCheck = true
+			}
+			if needCheck {
+				fullM3u8Url, err := j.checkM3u8(ctx, track.ID, "song")
+				if err == nil && strings.HasSuffix(fullM3u8Url, ".m3u8") {
+					m3u8Url = fullM3u8Url
+				} else {
+					fmt.Println("Failed to get best quality m3u8 from device m3u8 port, will use m3u8 from Web API")
+				}
+			}
+
+			_, _, _, err = j.extractMedia(ctx, m3u8Url, true)
+			if err != nil {
+				fmt.Printf("Failed to extract quality info for track %d: %v\n", trackNum, err)
+				continue
+			}
+		}
+		return nil
+	}
+	// Local mutable copy of user/runtime codec preferences
+	localDlAtmos := j.atmos
+	localDlAac := j.aac
+	var Codec string
+	if localDlAtmos {
+		Codec = "ATMOS"
+	} else if localDlAac {
+		Codec = "AAC"
+	} else {
+		Codec = "ALAC"
+	}
+	album.Codec = Codec
+	fields := j.albumFields(meta.Data[0])
+	fields.AlbumId, fields.Codec = albumId, Codec
+	singerFoldername := j.artistFolder(fields)
+	if singerFoldername != "" {
+		fmt.Println(singerFoldername)
+	}
+	var singerFolder string
+	var Quality string
+	if strings.Contains(j.cfg.AlbumFolderFormat, "Quality") || strings.Contains(j.cfg.AlbumFolderFormat, "SampleRate") {
+		if j.atmos {
+			Quality = fmt.Sprintf("%dKbps", j.cfg.AtmosMax-2000)
+		} else if j.aac && j.cfg.AacType == "aac-lc" {
+			Quality = "256Kbps"
+		} else {
+			manifest1, err := ampapi.GetSongResp(ctx, storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, album.Language, token)
+			if err != nil {
+				fmt.Println("Failed to get manifest.\n", err)
+			} else {
+				if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
+					Codec = "AAC"
+					Quality = "256Kbps"
+				} else {
+					needCheck := false
+
+					if j.cfg.GetM3u8Mode == "all" {
+						needCheck = true
+					} else if j.cfg.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
+						needCheck = true
+					}
+					var EnhancedHls_m3u8 string
+					if needCheck {
+						EnhancedHls_m3u8, _ = j.checkM3u8(ctx, meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
+						if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
+							manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
+						}
+					}
+					var codecs string
+					_, Quality, codecs, err = j.extractMedia(ctx, manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
+					if err != nil {
+						fmt.Println("Failed to extract quality from manifest.\n", err)
+					}
+					switch codecs {
+					case "ec-3", "ac-3":
+						localDlAtmos = true
+						localDlAac = false
+					case "mp4a.40.2":
+						localDlAtmos = false
+						localDlAac = true
+					default:
+						localDlAtmos = false
+						localDlAac = false
+					}
+				}
+			}
+		}
+	}
+	// Ensure codec-priority routing applies even without Quality placeholder
+	if !localDlAtmos && !localDlAac {
+		manifest1, err := ampapi.GetSongResp(ctx, storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, album.Language, token)
+		if err == nil {
+			if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
+				// fallback to AAC if no EnhancedHls
+				localDlAtmos = false
+				localDlAac = true
+			} else {
+				needCheck := false
+				if j.cfg.GetM3u8Mode == "all" {
+					needCheck = true
+				} else if j.cfg.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
+					needCheck = true
+				}
+				var EnhancedHls_m3u8 string
+				if needCheck {
+					EnhancedHls_m3u8, _ = j.checkM3u8(ctx, meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
+					if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
+						manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
+					}
+				}
+				var codecs string
+				_, _, codecs, err = j.extractMedia(ctx, manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
+				if err == nil {
+					switch codecs {
+					case "ec-3", "ac-3":
+						localDlAtmos = true
+						localDlAac = false
+					case "mp4a.40.2":
+						localDlAtmos = false
+						localDlAac = true
+					default:
+						localDlAtmos = false
+						localDlAac = false
+					}
+				}
+			}
+		}
+	}
+	// 使用统一的输出根目录，不再根据 codec 分类保存目录
+	singerFolder = filepath.Join(j.outputFolder, j.safeName(j.outputFolder, singerFoldername, 0))
+	if err := j.mkdirAll(singerFolder); err != nil {
+		return fmt.Errorf("failed to create singer folder '%s': %w", singerFolder, err)
+	}
+	album.SaveDir = singerFolder
+	stringsToJoin := []string{}
+	if meta.Data[0].Attributes.IsAppleDigitalMaster || meta.Data[0].Attributes.IsMasteredForItunes {
+		if j.cfg.AppleMasterChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.AppleMasterChoice)
+		}
+	}
+	if meta.Data[0].Attributes.ContentRating == "explicit" {
+		if j.cfg.ExplicitChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.ExplicitChoice)
+		}
+	}
+	if meta.Data[0].Attributes.ContentRating == "clean" {
+		if j.cfg.CleanChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.CleanChoice)
+		}
+	}
+	Tag_string := strings.Join(stringsToJoin, " ")
+	fields.Quality, fields.Codec, fields.Tag = Quality, Codec, Tag_string
+	fields.SampleRate = sampleRate(Quality, localDlAtmos, localDlAac)
+	albumFolderName := j.render("album-folder-format", j.cfg.AlbumFolderFormat, fields)
+
+	albumFolderName = strings.TrimSpace(albumFolderName)
+	albumFolderPath := filepath.Join(singerFolder, j.safeName(singerFolder, albumFolderName, 0))
+	if err := j.mkdirAll(albumFolderPath); err != nil {
+		return fmt.Errorf("failed to create album folder '%s': %w", albumFolderPath, err)
+	}
+	album.SaveName = albumFolderName
+	j.res.Name, j.res.Dir = meta.Data[0].Attributes.Name, albumFolderPath
+	fmt.Println(albumFolderName)
+	if j.cfg.SaveArtistCover && len(meta.Data[0].Relationships.Artists.Data) > 0 {
+		if meta.Data[0].Relationships.Artists.Data[0].Attributes.Artwork.Url != "" {
+			_, err = j.saveCover(ctx, singerFolder, "folder", meta.Data[0].Relationships.Artists.Data[0].Attributes.Artwork.Url)
+			if err != nil {
+				fmt.Println("Failed to write artist cover.")
+			}
+		}
+	}
+	covPath, err := j.saveCover(ctx, albumFolderPath, "cover", meta.Data[0].Attributes.Artwork.URL)
+	if err != nil {
+		fmt.Println("Failed to write cover.")
+	}
+	if j.cfg.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video != "" {
+		fmt.Println("Found Animation Artwork.")
+
+		motionvideoUrlSquare, err := j.extractVideo(ctx, meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video)
+		if err != nil {
+			fmt.Println("no motion video square.\n", err)
+		} else {
+			exists, err := fileExists(filepath.Join(albumFolderPath, "square_animated_artwork.mp4"))
+			if err != nil {
+				fmt.Println("Failed to check if animated artwork square exists.")
+			}
+			if exists {
+				fmt.Println("Animated artwork square already exists locally.")
+			} else {
+				fmt.Println("Animation Artwork Square Downloading...")
+				if err := j.saveArtwork(ctx, 2*time.Minute, filepath.Join(albumFolderPath, "square_animated_artwork.mp4"), "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy"); err != nil {
+					fmt.Printf("animated artwork square dl err: %v\n", err)
+				} else {
+					fmt.Println("Animation Artwork Square Downloaded")
+				}
+			}
+		}
+
+		if j.cfg.EmbyAnimatedArtwork {
+			if err := j.saveArtwork(ctx, 1*time.Minute, filepath.Join(albumFolderPath, "folder.jpg"), "ffmpeg", "-i", filepath.Join(albumFolderPath, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif"); err != nil {
+				fmt.Printf("animated artwork square to gif err: %v\n", err)
+			}
+		}
+
+		motionvideoUrlTall, err := j.extractVideo(ctx, meta.Data[0].Attributes.EditorialVideo.MotionDetailTall.Video)
+		if err != nil {
+			fmt.Println("no motion video tall.\n", err)
+		} else {
+			exists, err := fileExists(filepath.Join(albumFolderPath, "tall_animated_artwork.mp4"))
+			if err != nil {
+				fmt.Println("Failed to check if animated artwork tall exists.")
+			}
+			if exists {
+				fmt.Println("Animated artwork tall already exists locally.")
+			} else {
+				fmt.Println("Animation Artwork Tall Downloading...")
+				if err := j.saveArtwork(ctx, 2*time.Minute, filepath.Join(albumFolderPath, "tall_animated_artwork.mp4"), "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlTall, "-c", "copy"); err != nil {
+					fmt.Printf("animated artwork tall dl err: %v\n", err)
+				} else {
+					fmt.Println("Animation Artwork Tall Downloaded")
+				}
+			}
+		}
+	}
+	// 封面与动态封面留在专辑目录，曲目按碟放入子目录
+	for i := range album.Tracks {
+		album.Tracks[i].CoverPath = covPath
+		album.Tracks[i].SaveDir = j.discFolder(albumFolderPath, fields, album.Tracks[i].Resp.Attributes.DiscNumber)
+		album.Tracks[i].Codec = Codec
+	}
+	if j.cfg.PlaylistFilesForAlbums {
+		defer j.writePlaylistFiles(albumFolderPath, meta.Data[0].Attributes.Name, album.Tracks)
+	}
+	trackTotal := len(meta.Data[0].Relationships.Tracks.Data)
+	arr := make([]int, trackTotal)
+	for i := 0; i < trackTotal; i++ {
+		arr[i] = i + 1
+	}
+
+	if j.opts.TrackID != "" {
+		for i := range album.Tracks {
+			if j.opts.TrackID == album.Tracks[i].ID {
+				j.runTrack(ctx, i+1, &album.Tracks[i], token, mediaUserToken)
+				return nil
+			}
+		}
+		return nil
+	}
+	var selected []int
+	if j.opts.Retry {
+		selected = mergeNums(j.getFail(albumId), j.dbFailedNums(albumId, album.Tracks))
+		if len(selected) == 0 {
+			selected = arr
+		}
+	} else if !j.selectTracks {
+		selected = arr
+	} else {
+		selected = album.ShowSelect()
+	}
+	// Worker pool for album tracks
+	type dlJob struct {
+		idx int // 1-based index in album.Tracks
+		seq int // sequential TaskNum within toProcess
+	}
+	var toProcess []int
+	doneList := j.getOk(albumId)
+	for i := range album.Tracks {
+		num := i + 1
+		if isInArray(doneList, num) || j.dbTrackDone(albumId, album.Tracks[i].ID) {
+			j.skipTrack(num, &album.Tracks[i])
+			continue
+		}
+		if isInArray(selected, num) {
+			toProcess = append(toProcess, num)
+			j.publishTrack(events.TrackQueued, &album.Tracks[i], "")
+		}
+	}
+	if len(toProcess) == 0 {
+		return nil
+	}
+	var wg sync.WaitGroup
+	workerCount := j.concurrency
+	if workerCount > len(toProcess) {
+		workerCount = len(toProcess)
+	}
+	jobs := make(chan dlJob, workerCount)
+	wg.Add(workerCount)
+	for w := 0; w < workerCount; w++ {
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				tk := &album.Tracks[item.idx-1]
+				tk.TaskNum = item.seq
+				tk.TaskTotal = len(toProcess)
+				log.Printf("Start album track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
+				j.runTrack(ctx, item.idx, tk, token, mediaUserToken)
+				log.Printf("Done  album track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
+			}
+		}()
+	}
+	for seq, idx := range toProcess {
+		tr := album.Tracks[idx-1]
+		log.Printf("Enqueue album track %d/%d: %s - %s", seq+1, len(toProcess), tr.Resp.Attributes.ArtistName, tr.Resp.Attributes.Name)
+		jobs <- dlJob{idx: idx, seq: seq + 1}
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+
+}
+
+// ripPlaylist 下载歌单
+func (j *job) ripPlaylist(ctx context.Context, playlistId string, token string, storefront string, mediaUserToken string) error {
+	playlist := task.NewPlaylist(storefront, playlistId)
+	var err error
+	if isLibraryID(playlistId) {
+		playlist, err = j.getLibraryPlaylist(ctx, playlistId, token, storefront, mediaUserToken)
+	} else {
+		err = playlist.GetResp(ctx, token, j.cfg.Language)
+	}
+	if err != nil {
+		fmt.Println("Failed to get playlist response.")
+		return err
+	}
+	meta := playlist.Resp
+	if j.debug {
+		fmt.Println(meta.Data[0].Attributes.ArtistName)
+		fmt.Println(meta.Data[0].Attributes.Name)
+
+		for trackNum, track := range meta.Data[0].Relationships.Tracks.Data {
+			trackNum++
+			fmt.Printf("\nTrack %d of %d:\n", trackNum, len(meta.Data[0].Relationships.Tracks.Data))
+			fmt.Printf("%02d. %s\n", trackNum, track.Attributes.Name)
+
+			manifest, err := ampapi.GetSongResp(ctx, storefront, track.ID, playlist.Language, token)
+			if err != nil {
+				fmt.Printf("Failed to get manifest for track %d: %v\n", trackNum, err)
+				continue
+			}
+
+			var m3u8Url string
+			if manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls != "" {
+				m3u8Url = manifest.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls
+			}
+			needCheck := false
+			if j.cfg.GetM3u8Mode == "all" {
+				needCheck = true
+			} else if j.cfg.GetM3u8Mode == "hires" && contains(track.Attributes.AudioTraits, "hi-res-lossless") {
+				needCheck = true
+			}
+			if needCheck {
+				fullM3u8Url, err := j.checkM3u8(ctx, track.ID, "song")
+				if err == nil && strings.HasSuffix(fullM3u8Url, ".m3u8") {
+					m3u8Url = fullM3u8Url
+				} else {
+					fmt.Println("Failed to get best quality m3u8 from device m3u8 port, will use m3u8 from Web API")
+				}
+			}
+
+			_, _, _, err = j.extractMedia(ctx, m3u8Url, true)
+			if err != nil {
+				fmt.Printf("Failed to extract quality info for track %d: %v\n", trackNum, err)
+				continue
+			}
+		}
+		return nil
+	}
+	// Local mutable copy of user/runtime codec preferences
+	localDlAtmos := j.atmos
+	localDlAac := j.aac
+	var Codec string
+	if localDlAtmos {
+		Codec = "ATMOS"
+	} else if localDlAac {
+		Codec = "AAC"
+	} else {
+		Codec = "ALAC"
+	}
+	playlist.Codec = Codec
+	fields := j.playlistFields(meta.Data[0])
+	fields.PlaylistId, fields.Codec = playlistId, Codec
+	singerFoldername := j.artistFolder(fields)
+	if singerFoldername != "" {
+		fmt.Println(singerFoldername)
+	}
+	var singerFolder string
+
+	var Quality string
+	if strings.Contains(j.cfg.AlbumFolderFormat, "Quality") || strings.Contains(j.cfg.PlaylistFolderFormat, "Quality") || strings.Contains(j.cfg.PlaylistFolderFormat, "SampleRate") {
+		if j.atmos {
+			Quality = fmt.Sprintf("%dKbps", j.cfg.AtmosMax-2000)
+		} else if j.aac && j.cfg.AacType == "aac-lc" {
+			Quality = "256Kbps"
+		} else {
+			manifest1, err := ampapi.GetSongResp(ctx, storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, playlist.Language, token)
+			if err != nil {
+				fmt.Println("Failed to get manifest.\n", err)
+			} else {
+				if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
+					Codec = "AAC"
+					Quality = "256Kbps"
+				} else {
+					needCheck := false
+
+					if j.cfg.GetM3u8Mode == "all" {
+						needCheck = true
+					} else if j.cfg.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
+						needCheck = true
+					}
+					var EnhancedHls_m3u8 string
+					if needCheck {
+						EnhancedHls_m3u8, _ = j.checkM3u8(ctx, meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
+						if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
+							manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
+						}
+					}
+					var codecs string
+					_, Quality, codecs, err = j.extractMedia(ctx, manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
+					if err != nil {
+						fmt.Println("Failed to extract quality from manifest.\n", err)
+					}
+					// Resolve flags from selected codec (priority-driven)
+					switch codecs {
+					case "ec-3", "ac-3":
+						localDlAtmos = true
+						localDlAac = false
+					case "mp4a.40.2":
+						localDlAtmos = false
+						localDlAac = true
+					default:
+						localDlAtmos = false
+						localDlAac = false
+					}
+				}
+			}
+		}
+	}
+	// Ensure codec-priority routing applies even without Quality placeholder
+	if !localDlAtmos && !localDlAac {
+		manifest1, err := ampapi.GetSongResp(ctx, storefront, meta.Data[0].Relationships.Tracks.Data[0].ID, playlist.Language, token)
+		if err == nil {
+			if manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls == "" {
+				// fallback to AAC if no EnhancedHls
+				localDlAtmos = false
+				localDlAac = true
+			} else {
+				needCheck := false
+				if j.cfg.GetM3u8Mode == "all" {
+					needCheck = true
+				} else if j.cfg.GetM3u8Mode == "hires" && contains(meta.Data[0].Relationships.Tracks.Data[0].Attributes.AudioTraits, "hi-res-lossless") {
+					needCheck = true
+				}
+				var EnhancedHls_m3u8 string
+				if needCheck {
+					EnhancedHls_m3u8, _ = j.checkM3u8(ctx, meta.Data[0].Relationships.Tracks.Data[0].ID, "album")
+					if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
+						manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
+					}
+				}
+				var codecs string
+				_, _, codecs, err = j.extractMedia(ctx, manifest1.Data[0].Attributes.ExtendedAssetUrls.EnhancedHls, true)
+				if err == nil {
+					switch codecs {
+					case "ec-3", "ac-3":
+						localDlAtmos = true
+						localDlAac = false
+					case "mp4a.40.2":
+						localDlAtmos = false
+						localDlAac = true
+					default:
+						localDlAtmos = false
+						localDlAac = false
+					}
+				}
+			}
+		}
+	}
+	// 使用统一的输出根目录，不再根据 codec 分类保存目录
+	singerFolder = filepath.Join(j.outputFolder, j.safeName(j.outputFolder, singerFoldername, 0))
+	if err := j.mkdirAll(singerFolder); err != nil {
+		return fmt.Errorf("failed to create singer folder '%s': %w", singerFolder, err)
+	}
+	playlist.SaveDir = singerFolder
+	stringsToJoin := []string{}
+	if meta.Data[0].Attributes.IsAppleDigitalMaster || meta.Data[0].Attributes.IsMasteredForItunes {
+		if j.cfg.AppleMasterChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.AppleMasterChoice)
+		}
+	}
+	if meta.Data[0].Attributes.ContentRating == "explicit" {
+		if j.cfg.ExplicitChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.ExplicitChoice)
+		}
+	}
+	if meta.Data[0].Attributes.ContentRating == "clean" {
+		if j.cfg.CleanChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.CleanChoice)
+		}
+	}
+	Tag_string := strings.Join(stringsToJoin, " ")
+	fields.Quality, fields.Codec, fields.Tag = Quality, Codec, Tag_string
+	fields.SampleRate = sampleRate(Quality, localDlAtmos, localDlAac)
+	playlistFolder := j.render("playlist-folder-format", j.cfg.PlaylistFolderFormat, fields)
+	playlistFolder = strings.TrimSpace(playlistFolder)
+	playlistFolderPath := filepath.Join(singerFolder, j.safeName(singerFolder, playlistFolder, 0))
+	if err := j.mkdirAll(playlistFolderPath); err != nil {
+		return fmt.Errorf("failed to create playlist folder '%s': %w", playlistFolderPath, err)
+	}
+	playlist.SaveName = playlistFolder
+	j.res.Name, j.res.Dir = meta.Data[0].Attributes.Name, playlistFolderPath
+	fmt.Println(playlistFolder)
+	covPath, err := j.saveCover(ctx, playlistFolderPath, "cover", meta.Data[0].Attributes.Artwork.URL)
+	if err != nil {
+		fmt.Println("Failed to write cover.")
+	}
+
+	for i := range playlist.Tracks {
+		playlist.Tracks[i].CoverPath = covPath
+		playlist.Tracks[i].SaveDir = playlistFolderPath
+		playlist.Tracks[i].Codec = Codec
+	}
+	defer j.writePlaylistFiles(playlistFolderPath, meta.Data[0].Attributes.Name, playlist.Tracks)
+	if j.sync != nil {
+		// 在写播放列表文件之前执行
+		defer j.finishSync(playlistFolderPath, meta.Data[0].Attributes.Name, playlist.Tracks)
+	}
+	defer j.linkPlaylistTracks(playlistFolderPath, playlist.Tracks)
+
+	if j.cfg.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video != "" {
+		fmt.Println("Found Animation Artwork.")
+
+		motionvideoUrlSquare, err := j.extractVideo(ctx, meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video)
+		if err != nil {
+			fmt.Println("no motion video square.\n", err)
+		} else {
+			exists, err := fileExists(filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"))
+			if err != nil {
+				fmt.Println("Failed to check if animated artwork square exists.")
+			}
+			if exists {
+				fmt.Println("Animated artwork square already exists locally.")
+			} else {
+				fmt.Println("Animation Artwork Square Downloading...")
+				if err := j.saveArtwork(ctx, 2*time.Minute, filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"), "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy"); err != nil {
+					fmt.Printf("animated artwork square dl err: %v\n", err)
+				} else {
+					fmt.Println("Animation Artwork Square Downloaded")
+				}
+			}
+		}
+
+		if j.cfg.EmbyAnimatedArtwork {
+			if err := j.saveArtwork(ctx, 1*time.Minute, filepath.Join(playlistFolderPath, "folder.jpg"), "ffmpeg", "-i", filepath.Join(playlistFolderPath, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif"); err != nil {
+				fmt.Printf("animated artwork square to gif err: %v\n", err)
+			}
+		}
+
+		motionvideoUrlTall, err := j.extractVideo(ctx, meta.Data[0].Attributes.EditorialVideo.MotionDetailTall.Video)
+		if err != nil {
+			fmt.Println("no motion video tall.\n", err)
+		} else {
+			exists, err := fileExists(filepath.Join(playlistFolderPath, "tall_animated_artwork.mp4"))
+			if err != nil {
+				fmt.Println("Failed to check if animated artwork tall exists.")
+			}
+			if exists {
+				fmt.Println("Animated artwork tall already exists locally.")
+			} else {
+				fmt.Println("Animation Artwork Tall Downloading...")
+				if err := j.saveArtwork(ctx, 2*time.Minute, filepath.Join(playlistFolderPath, "tall_animated_artwork.mp4"), "ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlTall, "-c", "copy"); err != nil {
+					fmt.Printf("animated artwork tall dl err: %v\n", err)
+				} else {
+					fmt.Println("Animation Artwork Tall Downloaded")
+				}
+			}
+		}
+	}
+	trackTotal := len(meta.Data[0].Relationships.Tracks.Data)
+	arr := make([]int, trackTotal)
+	for i := 0; i < trackTotal; i++ {
+		arr[i] = i + 1
+	}
+	var selected []int
+	if j.opts.Retry {
+		selected = mergeNums(j.getFail(playlistId), j.dbFailedNums(playlistId, playlist.Tracks))
+		if len(selected) == 0 {
+			selected = arr
+		}
+	} else if !j.selectTracks || j.sync != nil {
+		selected = arr
+	} else {
+		selected = playlist.ShowSelect()
+	}
+	// Worker pool for playlist tracks
+	type dlJob struct {
+		idx int
+		seq int
+	}
+	toProcess := []int{}
+	for i := range playlist.Tracks {
+		num := i + 1
+		// 同步时每首曲目都要检查位置与文件名
+		if j.sync == nil && (isInArray(j.getOk(playlistId), num) || j.dbTrackDone(playlistId, playlist.Tracks[i].ID)) {
+			j.skipTrack(num, &playlist.Tracks[i])
+			continue
+		}
+		if isInArray(selected, num) {
+			toProcess = append(toProcess, num)
+			j.publishTrack(events.TrackQueued, &playlist.Tracks[i], "")
+		}
+	}
+	if len(toProcess) == 0 {
+		return nil
+	}
+	workerCount := j.concurrency
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+	if workerCount > len(toProcess) {
+		workerCount = len(toProcess)
+	}
+	jobs := make(chan dlJob, workerCount)
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for w := 0; w < workerCount; w++ {
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				tk := &playlist.Tracks[item.idx-1]
+				// setTracks 设置的序号即歌单中的位置（资料库歌单中无法下载的曲目也占位）
+				position, total := tk.TaskNum, tk.TaskTotal
+				tk.TaskNum = item.seq
+				tk.TaskTotal = len(toProcess)
+				if j.sync != nil {
+					// 同步时序号即歌单中的位置
+					tk.TaskNum, tk.TaskTotal = position, total
+				}
+				log.Printf("Start playlist track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
+				j.runTrack(ctx, item.idx, tk, token, mediaUserToken)
+				log.Printf("Done  playlist track %d/%d: %s - %s", tk.TaskNum, tk.TaskTotal, tk.Resp.Attributes.ArtistName, tk.Resp.Attributes.Name)
+			}
+		}()
+	}
+	for seq, idx := range toProcess {
+		tr := playlist.Tracks[idx-1]
+		log.Printf("Enqueue playlist track %d/%d: %s - %s", seq+1, len(toProcess), tr.Resp.Attributes.ArtistName, tr.Resp.Attributes.Name)
+		jobs <- dlJob{idx: idx, seq: seq + 1}
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+}
+
+// RipAlbum 下载专辑
+func (d *Downloader) RipAlbum(ctx context.Context, albumID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("album", albumID, opts)
+	// 资料库中的专辑按对应的目录专辑下载
+	if isLibraryID(albumID) {
+		id, err := d.libraryCatalogID(ctx, "albums", albumID)
+		if err != nil {
+			return j.finish(err)
+		}
+		albumID = id
+	}
+	return j.finish(j.ripAlbum(ctx, albumID, d.token, j.opts.Storefront, d.cfg.MediaUserToken))
+}
+
+// RipSong 通过所属专辑下载单曲
+func (d *Downloader) RipSong(ctx context.Context, songID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("song", songID, opts)
+	manifest, err := ampapi.GetSongResp(ctx, j.opts.Storefront, songID, d.cfg.Language, d.token)
+	if err != nil {
+		fmt.Println("Failed to get song response.")
+		return j.finish(err)
+	}
+	if len(manifest.Data) == 0 || len(manifest.Data[0].Relationships.Albums.Data) == 0 {
+		return j.finish(errors.New("song has no album"))
+	}
+	j.res.Name = manifest.Data[0].Attributes.Name
+	j.opts.TrackID = songID
+	albumID := manifest.Data[0].Relationships.Albums.Data[0].ID
+	return j.finish(j.ripAlbum(ctx, albumID, d.token, j.opts.Storefront, d.cfg.MediaUserToken))
+}
+
+// RipPlaylist 下载歌单
+func (d *Downloader) RipPlaylist(ctx context.Context, playlistID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("playlist", playlistID, opts)
+	return j.finish(j.ripPlaylist(ctx, playlistID, d.token, j.opts.Storefront, d.cfg.MediaUserToken))
+}
+
+// RipStation 下载电台，需要 media-user-token
+func (d *Downloader) RipStation(ctx context.Context, stationID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("station", stationID, opts)
+	if len(d.cfg.MediaUserToken) <= 50 {
+		return j.finish(errors.New("media-user-token not set"))
+	}
+	return j.finish(j.ripStation(ctx, stationID, d.token, j.opts.Storefront, d.cfg.MediaUserToken))
+}
+
+// RipMusicVideo 下载 MV；缺少 media-user-token 或 mp4decrypt 时跳过
+func (d *Downloader) RipMusicVideo(ctx context.Context, mvID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("music-video", mvID, opts)
+	if d.debug {
+		return j.finish(nil)
+	}
+	j.incTotal()
+	mv := TrackResult{Index: 1, ID: mvID, Status: TrackOK}
+	defer func() { j.addTrack(mv) }()
+	if len(d.cfg.MediaUserToken) <= 50 {
+		fmt.Println(": meida-user-token is not set, skip MV dl")
+		j.incSuccess()
+		mv.Status, mv.Reason = TrackSkipped, "media-user-token not set"
+		return j.finish(nil)
+	}
+	if _, err := exec.LookPath("mp4decrypt"); err != nil {
+		fmt.Println(": mp4decrypt is not found, skip MV dl")
+		j.incSuccess()
+		mv.Status, mv.Reason = TrackSkipped, "mp4decrypt not found"
+		return j.finish(nil)
+	}
+	mvSaveDir := j.artistFolder(naming.Fields{SongId: mvID})
+	if mvSaveDir != "" {
+		mvSaveDir = filepath.Join(d.outputFolder, d.safeName(d.outputFolder, mvSaveDir, 0))
+	} else {
+		mvSaveDir = d.outputFolder
+	}
+	j.res.Dir = mvSaveDir
+	if d.dryRun {
+		mvPath, err := j.planMV(ctx, mvID, mvSaveDir, d.token, j.opts.Storefront, nil)
+		if err != nil {
+			mv.Status, mv.Reason = TrackFailed, err.Error()
+			return j.finish(err)
+		}
+		mv.Status, mv.Path = TrackPlanned, mvPath
+		return j.finish(nil)
+	}
+	err := j.mvDownloader(ctx, mvID, mvSaveDir, d.token, j.opts.Storefront, d.cfg.MediaUserToken, nil)
+	if err != nil {
+		fmt.Println("\u26A0 Failed to dl MV:", err)
+		j.incError()
+		mv.Status, mv.Reason = TrackFailed, err.Error()
+		return j.finish(err)
+	}
+	j.incSuccess()
+	return j.finish(nil)
+}
+
+// RipArtist 下载艺术家的专辑与 MV，由 Options.SelectArtistItems 决定下载哪些
+func (d *Downloader) RipArtist(ctx context.Context, artistID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("artist", artistID, opts)
+	name, err := d.ArtistName(ctx, j.opts.Storefront, artistID)
+	if err != nil {
+		fmt.Println("Failed to get artistname.")
+		return j.finish(err)
+	}
+	j.res.Name = name
+	var urls []string
+	for _, relationship := range []string{"albums", "music-videos"} {
+		items, err := d.ArtistItems(ctx, j.opts.Storefront, artistID, relationship)
+		if err != nil {
+			if relationship == "albums" {
+				fmt.Println("Failed to get artist albums.")
+				return j.finish(err)
+			}
+			fmt.Println("Failed to get artist music-videos.")
+			continue
+		}
+		if d.selectArtist != nil {
+			items = d.selectArtist(ctx, name, relationship, items)
+		}
+		for _, it := range items {
+			urls = append(urls, it.URL)
+		}
+	}
+	for _, u := range urls {
+		child, _ := d.RipURL(ctx, u, RipOptions{Retry: j.opts.Retry, ArtistName: name, ArtistID: artistID, Events: j.opts.Events})
+		j.mu.Lock()
+		j.res.Items = append(j.res.Items, child)
+		j.res.Stats.add(child.Stats)
+		j.mu.Unlock()
+	}
+	return j.finish(nil)
+}
+
+// RipRecordLabel 下载唱片公司的专辑，与艺术家相同由 Options.SelectArtistItems 决定下载哪些
+func (d *Downloader) RipRecordLabel(ctx context.Context, labelID string, opts RipOptions) (*Result, error) {
+	j := d.newJob("record-label", labelID, opts)
+	name, items, err := d.RecordLabelItems(ctx, j.opts.Storefront, labelID)
+	if err != nil {
+		fmt.Println("Failed to get record label releases.")
+		return j.finish(err)
+	}
+	fmt.Println("Record Label:", name)
+	j.res.Name = name
+	return j.ripItems(ctx, "albums", items)
+}
+
+// RipCurator 下载 curator / apple-curator 页面中的歌单
+func (d *Downloader) RipCurator(ctx context.Context, kind string, curatorID string, opts RipOptions) (*Result, error) {
+	j := d.newJob(kind, curatorID, opts)
+	name, items, err := d.CuratorItems(ctx, j.opts.Storefront, kind+"s", curatorID)
+	if err != nil {
+		fmt.Println("Failed to get curator playlists.")
+		return j.finish(err)
+	}
+	fmt.Println("Curator:", name)
+	j.res.Name = name
+	return j.ripItems(ctx, "playlists", items)
+}
+
+// ripItems 按选择结果逐个下载唱片公司或策展人页面中的条目；
+// 条目不属于同一艺术家，因此不传递 ArtistName / ArtistID
+func (j *job) ripItems(ctx context.Context, relationship string, items []ArtistItem) (*Result, error) {
+	if j.selectArtist != nil {
+		items = j.selectArtist(ctx, "", relationship, items)
+	}
+	for _, it := range items {
+		child, _ := j.RipURL(ctx, it.URL, RipOptions{Retry: j.opts.Retry, Events: j.opts.Events})
+		j.mu.Lock()
+		j.res.Items = append(j.res.Items, child)
+		j.res.Stats.add(child.Stats)
+		j.mu.Unlock()
+	}
+	return j.finish(nil)
+}
@@ -0,0 +1,169 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"main/utils/events"
+	"main/utils/store"
+	"main/utils/task"
+)
+
+// publishTrack 发布单曲相关事件
+func (j *job) publishTrack(typ string, track *task.Track, reason string) {
+	if !j.bus.Active() {
+		return
+	}
+	j.bus.Publish(events.Event{
+		Type:     typ,
+		Kind:     track.PreType,
+		EntityID: track.PreID,
+		SongID:   track.ID,
+		Name:     fmt.Sprintf("%s - %s", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name),
+		Index:    track.TaskNum,
+		Count:    track.TaskTotal,
+		Codec:    track.Codec,
+		Quality:  track.Quality,
+		Path:     track.SavePath,
+		Reason:   reason,
+	})
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func trackRecord(track *task.Track, status string) store.TrackRecord {
+	return store.TrackRecord{
+		EntityID:   track.PreID,
+		EntityType: track.PreType,
+		Storefront: track.Storefront,
+		SongID:     track.ID,
+		Name:       fmt.Sprintf("%s - %s", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name),
+		Status:     status,
+		Codec:      track.Codec,
+		Quality:    track.Quality,
+	}
+}
+
+// markTrackOk 记录曲目完成（内存 + 数据库），track.SavePath 为最终文件
+func (j *job) markTrackOk(track *task.Track) {
+	j.addOk(track.PreID, track.TaskNum)
+	j.removeFail(track.PreID, track.TaskNum)
+	j.publishTrack(events.TrackDone, track, "")
+	if j.db == nil {
+		return
+	}
+	j.indexTrack(track)
+	rec := trackRecord(track, store.StatusOK)
+	rec.Path = track.SavePath
+	if track.SavePath != "" {
+		sum, err := fileChecksum(track.SavePath)
+		if err != nil {
+			fmt.Println("Failed to checksum track:", err)
+		}
+		rec.Checksum = sum
+	}
+	if err := j.db.PutTrack(rec); err != nil {
+		fmt.Println("Failed to update download database:", err)
+	}
+}
+
+// markTrackFail 记录曲目失败（内存 + 数据库），供重试使用
+func (j *job) markTrackFail(track *task.Track, reason string) {
+	j.addFail(track.PreID, track.TaskNum)
+	j.publishTrack(events.TrackFailed, track, reason)
+	if j.db == nil || j.dryRun {
+		return
+	}
+	rec := trackRecord(track, store.StatusFailed)
+	rec.Reason = reason
+	if err := j.db.PutTrack(rec); err != nil {
+		fmt.Println("Failed to update download database:", err)
+	}
+}
+
+// dbTrackDone 数据库中已完成且文件仍存在的曲目视为已完成
+func (d *Downloader) dbTrackDone(entityID, songID string) bool {
+	if d.db == nil {
+		return false
+	}
+	rec, err := d.db.GetTrack(entityID, songID)
+	if err != nil || rec == nil || rec.Status != store.StatusOK || rec.Path == "" {
+		return false
+	}
+	exists, err := fileExists(rec.Path)
+	return err == nil && exists
+}
+
+// dbFailedNums 返回数据库中记录为失败的曲目序号（从 1 开始）
+func (d *Downloader) dbFailedNums(entityID string, tracks []task.Track) []int {
+	if d.db == nil {
+		return nil
+	}
+	recs, err := d.db.Tracks(entityID)
+	if err != nil {
+		fmt.Println("Failed to read download database:", err)
+		return nil
+	}
+	failed := make(map[string]struct{})
+	for _, r := range recs {
+		if r.Status == store.StatusFailed {
+			failed[r.SongID] = struct{}{}
+		}
+	}
+	var out []int
+	for i := range tracks {
+		if _, ok := failed[tracks[i].ID]; ok {
+			out = append(out, i+1)
+		}
+	}
+	return out
+}
+
+// mergeNums 合并两组曲目序号并去重
+func mergeNums(a, b []int) []int {
+	out := append([]int(nil), a...)
+	for _, n := range b {
+		if !isInArray(out, n) {
+			out = append(out, n)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// markStreamOk 电台直播流没有 Track，以电台 ID 同时作为实体与歌曲 ID 记录
+func (d *Downloader) markStreamOk(station *task.Station, path string) {
+	d.addOk(station.ID, 1)
+	if d.db == nil || d.dryRun {
+		return
+	}
+	rec := store.TrackRecord{
+		EntityID:   station.ID,
+		EntityType: "stations",
+		Storefront: station.Storefront,
+		SongID:     station.ID,
+		Name:       station.Name,
+		Status:     store.StatusOK,
+		Codec:      "AAC",
+		Quality:    "256Kbps",
+		Path:       path,
+	}
+	if err := d.db.PutTrack(rec); err != nil {
+		fmt.Println("Failed to update download database:", err)
+	}
+}
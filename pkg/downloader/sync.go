@@ -0,0 +1,225 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"main/utils/store"
+	"main/utils/task"
+)
+
+// SyncOptions playlist sync 的选项
+type SyncOptions struct {
+	Storefront string
+	// 为 true 时删除已移出歌单的曲目，否则移到归档目录
+	Delete bool
+	// 归档目录，为空时为歌单目录旁的 _archive/<歌单目录名>
+	ArchiveDir string
+}
+
+// playlistSync 一次同步的状态
+type playlistSync struct {
+	opts SyncOptions
+	old  map[string]store.PlaylistTrack // 上次同步的曲目，按歌曲 ID
+}
+
+// SyncPlaylist 将歌单同步到本地目录：下载新曲目，位置变化的曲目改名并更新标签，
+// 移出歌单的曲目移到归档目录（或删除），最后重新生成有序的播放列表文件并保存清单
+func (d *Downloader) SyncPlaylist(ctx context.Context, playlistID string, opts SyncOptions) (*Result, error) {
+	if d.db == nil {
+		return nil, errors.New("playlist sync requires the download database")
+	}
+	j := d.newJob("playlist", playlistID, RipOptions{Storefront: opts.Storefront})
+	manifest, err := d.db.GetPlaylist(playlistID)
+	if err != nil {
+		return j.finish(err)
+	}
+	j.sync = &playlistSync{opts: opts, old: make(map[string]store.PlaylistTrack)}
+	if manifest != nil {
+		for _, t := range manifest.Tracks {
+			j.sync.old[t.SongID] = t
+		}
+	}
+	return j.finish(j.ripPlaylist(ctx, playlistID, d.token, j.opts.Storefront, d.cfg.MediaUserToken))
+}
+
+// lrcSidecar 曲目文件旁的歌词文件
+func (j *job) lrcSidecar(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + j.cfg.LrcFormat
+}
+
+// syncMove 同步时处理上次已下载的曲目：文件名随位置变化时移动旧文件（连同歌词文件），
+// 位置变化的 m4a 重写标签。ok 为 false 时按普通流程下载或跳过
+func (j *job) syncMove(track *task.Track, trackPath string, convertedPath string, lrc string) (status string, reason string, ok bool) {
+	if j.sync == nil {
+		return "", "", false
+	}
+	old, found := j.sync.old[track.ID]
+	if !found || old.Path == "" {
+		return "", "", false
+	}
+	if exists, err := fileExists(old.Path); err != nil || !exists {
+		return "", "", false
+	}
+	// 上次保存的是转换后的文件时保留其扩展名
+	target := trackPath
+	if ext := filepath.Ext(old.Path); !strings.EqualFold(ext, ".m4a") {
+		target = strings.TrimSuffix(trackPath, filepath.Ext(trackPath)) + ext
+		if convertedPath != "" && strings.EqualFold(target, convertedPath) {
+			target = convertedPath
+		}
+	}
+	if old.Path == target {
+		// 专辑目录中的文件不带歌单序号
+		if old.Position == track.TaskNum || j.libraryTrack(track) {
+			return "", "", false
+		}
+		reason = "renumbered"
+	} else {
+		if exists, _ := fileExists(target); exists {
+			return "", "", false
+		}
+		reason = "moved"
+	}
+	if j.dryRun {
+		track.SavePath = target
+		return TrackPlanned, fmt.Sprintf("%s from %s", reason, old.Path), true
+	}
+	if old.Path != target {
+		if err := os.Rename(old.Path, target); err != nil {
+			j.incError()
+			status, reason = j.failTrack(track, fmt.Sprintf("move failed: %v", err))
+			return status, reason, true
+		}
+		fmt.Println("Track moved:", old.Path, "->", target)
+		// 新名称的歌词文件已在本次写入时保留新的，否则沿用旧的
+		oldLrc, newLrc := j.lrcSidecar(old.Path), j.lrcSidecar(target)
+		if exists, _ := fileExists(oldLrc); exists {
+			if exists, _ := fileExists(newLrc); exists {
+				_ = os.Remove(oldLrc)
+			} else {
+				_ = os.Rename(oldLrc, newLrc)
+			}
+		}
+	}
+	track.SavePath = target
+	if strings.EqualFold(filepath.Ext(target), ".m4a") {
+		if err := j.writeMP4Tags(track, lrc); err != nil {
+			j.AddWarning(fmt.Sprintf("[%s - %s] Retag failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+		}
+	}
+	j.incSuccess()
+	j.markTrackOk(track)
+	return TrackOK, reason, true
+}
+
+// finishSync 处理移出歌单的曲目并保存本次的歌单清单，删除或归档失败的曲目留在清单中下次重试；
+// DryRun 时只列出将要移除的曲目
+func (j *job) finishSync(folder string, name string, tracks []task.Track) {
+	current := make(map[string]bool, len(tracks))
+	for i := range tracks {
+		current[tracks[i].ID] = true
+	}
+	archiveDir := j.sync.opts.ArchiveDir
+	if archiveDir == "" {
+		archiveDir = filepath.Join(filepath.Dir(folder), "_archive", filepath.Base(folder))
+	}
+	var failed []store.PlaylistTrack
+	for id, old := range j.sync.old {
+		if current[id] {
+			continue
+		}
+		tr := TrackResult{Index: old.Position, ID: id, Name: old.Name, Status: TrackRemoved}
+		exists, _ := fileExists(old.Path)
+		switch {
+		case old.Path == "" || !exists:
+			tr.Reason = "file not found"
+		case j.libraryPlaylist(tracks):
+			// 专辑目录中的文件可能被其他专辑或歌单引用
+			tr.Reason, tr.Path = "kept in library", old.Path
+		case j.dryRun && j.sync.opts.Delete:
+			tr.Reason, tr.Path = "will be deleted", old.Path
+		case j.dryRun:
+			tr.Reason, tr.Path = "will be archived", j.archivePath(archiveDir, old.Path)
+		default:
+			var ok bool
+			tr.Reason, tr.Path, ok = j.removeSyncedTrack(old.Path, archiveDir)
+			if !ok {
+				failed = append(failed, old)
+			}
+		}
+		j.addTrack(tr)
+	}
+	if j.dryRun {
+		return
+	}
+	m := store.PlaylistManifest{
+		PlaylistID: j.res.ID,
+		Storefront: j.opts.Storefront,
+		Name:       name,
+		Folder:     folder,
+	}
+	for i := range tracks {
+		m.Tracks = append(m.Tracks, store.PlaylistTrack{
+			SongID:   tracks[i].ID,
+			Position: i + 1,
+			Name:     fmt.Sprintf("%s - %s", tracks[i].Resp.Attributes.ArtistName, tracks[i].Resp.Attributes.Name),
+			Path:     j.trackFilePath(&tracks[i]),
+		})
+	}
+	m.Tracks = append(m.Tracks, failed...)
+	if err := j.db.PutPlaylist(m); err != nil {
+		j.AddError(fmt.Sprintf("Save playlist manifest failed: %v", err))
+	}
+}
+
+// removeSyncedTrack 删除或归档一首移出歌单的曲目及其歌词文件，返回结果说明与归档后的路径；
+// ok 为 false 时文件仍在原处
+func (j *job) removeSyncedTrack(path string, archiveDir string) (reason string, newPath string, ok bool) {
+	lrcPath := j.lrcSidecar(path)
+	if j.sync.opts.Delete {
+		if err := os.Remove(path); err != nil {
+			j.AddWarning(fmt.Sprintf("Delete %s failed: %v", path, err))
+			return fmt.Sprintf("delete failed: %v", err), path, false
+		}
+		_ = os.Remove(lrcPath)
+		fmt.Println("Track deleted:", path)
+		return "deleted", "", true
+	}
+	if err := os.MkdirAll(archiveDir, os.ModePerm); err != nil {
+		j.AddWarning(fmt.Sprintf("Create archive folder failed: %v", err))
+		return fmt.Sprintf("archive failed: %v", err), path, false
+	}
+	target := j.archivePath(archiveDir, path)
+	if err := os.Rename(path, target); err != nil {
+		j.AddWarning(fmt.Sprintf("Archive %s failed: %v", path, err))
+		return fmt.Sprintf("archive failed: %v", err), path, false
+	}
+	if exists, _ := fileExists(lrcPath); exists {
+		_ = os.Rename(lrcPath, j.lrcSidecar(target))
+	}
+	fmt.Println("Track archived:", target)
+	return "archived", target, true
+}
+
+// archivePath 曲目在归档目录中的文件，已有同名的曲目或歌词文件时依次追加 " (2)"、" (3)"……，不覆盖已归档的文件
+func (j *job) archivePath(archiveDir string, path string) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(filepath.Base(path), ext)
+	for n := 1; ; n++ {
+		name := stem
+		if n > 1 {
+			name = fmt.Sprintf("%s (%d)", stem, n)
+		}
+		target := filepath.Join(archiveDir, name+ext)
+		trackExists, _ := fileExists(target)
+		lrcExists, _ := fileExists(j.lrcSidecar(target))
+		if !trackExists && !lrcExists {
+			return target
+		}
+	}
+}
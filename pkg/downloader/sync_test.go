@@ -0,0 +1,204 @@
+package downloader
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"main/utils/store"
+	"main/utils/structs"
+	"main/utils/task"
+)
+
+// newSyncJob 使用临时数据库的 playlist sync 任务
+func newSyncJob(t *testing.T, opts SyncOptions) (*job, string) {
+	t.Helper()
+	j, dir := newTestJob(t, structs.ConfigSet{LrcFormat: "lrc"})
+	j.sync = &playlistSync{opts: opts, old: make(map[string]store.PlaylistTrack)}
+	return j, dir
+}
+
+func syncTrack(id string, num int) task.Track {
+	track := task.Track{ID: id, TaskNum: num, PreType: "playlists", PreID: "pl.test"}
+	track.Resp.Attributes.Name = "Song " + id
+	track.Resp.Attributes.ArtistName = "Artist"
+	return track
+}
+
+func TestSyncMove(t *testing.T) {
+	tests := []struct {
+		name       string
+		old        *store.PlaylistTrack // Path 相对临时目录
+		num        int
+		target     string
+		files      []string
+		dryRun     bool
+		wantOK     bool
+		wantStatus string
+		wantReason string
+		wantFile   string // 结束后应存在的文件
+		gone       string // 结束后应不存在的文件
+	}{
+		{name: "not in manifest", num: 1, target: "pl/01. a.flac"},
+		{name: "old file missing", old: &store.PlaylistTrack{Position: 1, Path: "pl/01. a.flac"}, num: 2, target: "pl/02. a.flac"},
+		{name: "unchanged", old: &store.PlaylistTrack{Position: 1, Path: "pl/01. a.flac"}, num: 1, target: "pl/01. a.flac", files: []string{"pl/01. a.flac"}},
+		{
+			name: "renumbered", old: &store.PlaylistTrack{Position: 3, Path: "pl/a.flac"}, num: 1, target: "pl/a.flac",
+			files: []string{"pl/a.flac"}, wantOK: true, wantStatus: TrackOK, wantReason: "renumbered", wantFile: "pl/a.flac",
+		},
+		{
+			name: "moved with lyrics", old: &store.PlaylistTrack{Position: 3, Path: "pl/03. a.flac"}, num: 1, target: "pl/01. a.flac",
+			files: []string{"pl/03. a.flac", "pl/03. a.lrc"}, wantOK: true, wantStatus: TrackOK, wantReason: "moved",
+			wantFile: "pl/01. a.lrc", gone: "pl/03. a.flac",
+		},
+		{
+			// 上次保存为转换后的文件，目标沿用其扩展名
+			name: "moved keeps extension", old: &store.PlaylistTrack{Position: 3, Path: "pl/03. a.flac"}, num: 1, target: "pl/01. a.m4a",
+			files: []string{"pl/03. a.flac"}, wantOK: true, wantStatus: TrackOK, wantReason: "moved",
+			wantFile: "pl/01. a.flac", gone: "pl/03. a.flac",
+		},
+		{
+			name: "target exists", old: &store.PlaylistTrack{Position: 3, Path: "pl/03. a.flac"}, num: 1, target: "pl/01. a.flac",
+			files: []string{"pl/03. a.flac", "pl/01. a.flac"},
+		},
+		{
+			name: "dry run", old: &store.PlaylistTrack{Position: 3, Path: "pl/03. a.flac"}, num: 1, target: "pl/01. a.flac",
+			files: []string{"pl/03. a.flac"}, dryRun: true, wantOK: true, wantStatus: TrackPlanned,
+			wantFile: "pl/03. a.flac", gone: "pl/01. a.flac",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, dir := newSyncJob(t, SyncOptions{})
+			abs := func(p string) string { return filepath.Join(dir, filepath.FromSlash(p)) }
+			if tt.old != nil {
+				o := *tt.old
+				o.SongID, o.Path = "1", abs(o.Path)
+				j.sync.old[o.SongID] = o
+			}
+			j.dryRun = tt.dryRun
+			for _, f := range tt.files {
+				writeFiles(t, abs(f))
+			}
+			track := syncTrack("1", tt.num)
+			status, reason, ok := j.syncMove(&track, abs(tt.target), "", "")
+			if ok != tt.wantOK || status != tt.wantStatus {
+				t.Fatalf("syncMove = (%q, %q, %v), want (%q, _, %v)", status, reason, ok, tt.wantStatus, tt.wantOK)
+			}
+			if tt.wantReason != "" && reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if tt.wantFile != "" && !exists(abs(tt.wantFile)) {
+				t.Errorf("%s does not exist", tt.wantFile)
+			}
+			if tt.gone != "" && exists(abs(tt.gone)) {
+				t.Errorf("%s still exists", tt.gone)
+			}
+		})
+	}
+}
+
+func TestFinishSync(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       SyncOptions
+		dryRun     bool
+		files      []string // 事先存在的文件，相对临时目录
+		wantReason string   // 前缀
+		wantPath   string   // 移除后的位置，相对临时目录
+		wantKept   bool     // 原文件是否保留
+		wantSaved  bool     // 是否保存新清单
+		wantRetry  bool     // 移除失败，清单中保留该曲目
+	}{
+		{name: "archive", wantReason: "archived", wantPath: "_archive/pl/01. removed.flac", wantSaved: true},
+		{name: "archive dir", opts: SyncOptions{ArchiveDir: "old"}, wantReason: "archived", wantPath: "old/01. removed.flac", wantSaved: true},
+		{
+			// 归档目录中已有同名文件时不覆盖
+			name: "archive name taken", files: []string{"_archive/pl/01. removed.flac", "_archive/pl/01. removed (2).lrc"},
+			wantReason: "archived", wantPath: "_archive/pl/01. removed (3).flac", wantSaved: true,
+		},
+		{
+			name: "archive failed", opts: SyncOptions{ArchiveDir: "blocked"}, files: []string{"blocked"},
+			wantReason: "archive failed", wantPath: "pl/01. removed.flac", wantKept: true, wantSaved: true, wantRetry: true,
+		},
+		{name: "delete", opts: SyncOptions{Delete: true}, wantReason: "deleted", wantSaved: true},
+		{name: "dry run archive", dryRun: true, wantReason: "will be archived", wantPath: "_archive/pl/01. removed.flac", wantKept: true},
+		{name: "dry run delete", opts: SyncOptions{Delete: true}, dryRun: true, wantReason: "will be deleted", wantPath: "pl/01. removed.flac", wantKept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, dir := newSyncJob(t, tt.opts)
+			abs := func(p string) string { return filepath.Join(dir, filepath.FromSlash(p)) }
+			if j.sync.opts.ArchiveDir != "" {
+				j.sync.opts.ArchiveDir = abs(j.sync.opts.ArchiveDir)
+			}
+			j.dryRun = tt.dryRun
+			for _, f := range tt.files {
+				writeFiles(t, abs(f))
+			}
+			folder := abs("pl")
+			removed, kept := abs("pl/01. removed.flac"), abs("pl/01. kept.flac")
+			writeFiles(t, removed, abs("pl/01. removed.lrc"), kept)
+			j.sync.old = map[string]store.PlaylistTrack{
+				"1": {SongID: "1", Position: 1, Name: "Artist - removed", Path: removed},
+				"2": {SongID: "2", Position: 2, Name: "Artist - kept", Path: kept},
+				"3": {SongID: "3", Position: 3, Name: "Artist - missing", Path: abs("pl/03. missing.flac")},
+			}
+			track := syncTrack("2", 1)
+			track.SavePath = kept
+			j.finishSync(folder, "Playlist", []task.Track{track})
+
+			got := make(map[string]TrackResult)
+			for _, tr := range j.res.Tracks {
+				if tr.Status != TrackRemoved {
+					t.Errorf("track %s status = %q, want %q", tr.ID, tr.Status, TrackRemoved)
+				}
+				got[tr.ID] = tr
+			}
+			if len(got) != 2 || got["3"].Reason != "file not found" {
+				t.Fatalf("removed tracks = %+v", j.res.Tracks)
+			}
+			tr := got["1"]
+			wantPath := ""
+			if tt.wantPath != "" {
+				wantPath = abs(tt.wantPath)
+			}
+			if !strings.HasPrefix(tr.Reason, tt.wantReason) || tr.Path != wantPath {
+				t.Errorf("removed track = (%q, %q), want (%q, %q)", tr.Reason, tr.Path, tt.wantReason, wantPath)
+			}
+			if exists(removed) != tt.wantKept {
+				t.Errorf("%s exists = %v, want %v", removed, exists(removed), tt.wantKept)
+			}
+			if tr.Reason == "archived" && (!exists(wantPath) || !exists(j.lrcSidecar(wantPath))) {
+				t.Errorf("archived files missing: %s", wantPath)
+			}
+			for _, f := range tt.files {
+				if !exists(abs(f)) {
+					t.Errorf("%s was overwritten or removed", f)
+				}
+			}
+
+			m, err := j.db.GetPlaylist("pl.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (m != nil) != tt.wantSaved {
+				t.Fatalf("manifest = %+v, want saved %v", m, tt.wantSaved)
+			}
+			if m == nil {
+				return
+			}
+			wantTracks := 1
+			if tt.wantRetry {
+				wantTracks = 2
+			}
+			if m.Folder != folder || m.Name != "Playlist" || len(m.Tracks) != wantTracks ||
+				m.Tracks[0].SongID != "2" || m.Tracks[0].Position != 1 || m.Tracks[0].Path != kept {
+				t.Fatalf("manifest = %+v", m)
+			}
+			if tt.wantRetry && (m.Tracks[1].SongID != "1" || m.Tracks[1].Path != removed) {
+				t.Errorf("failed removal not kept in manifest: %+v", m.Tracks[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,518 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/utils/atomicfile"
+	"main/utils/events"
+	"main/utils/lyrics"
+	"main/utils/runv2"
+	"main/utils/runv3"
+	"main/utils/task"
+	"main/utils/verify"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// ripTrack 下载单个曲目，返回结果状态与原因；tr 记录选中的音轨与歌词路径
+func (j *job) ripTrack(ctx context.Context, track *task.Track, token string, mediaUserToken string, tr *TrackResult) (string, string) {
+	var err error
+	j.incTotal()
+	fmt.Printf("Track %d of %d: %s\n", track.TaskNum, track.TaskTotal, track.Type)
+	// 已中断：记为失败以便之后 --retry-failed 续传
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted")
+		return j.failTrack(track, "interrupted")
+	}
+	j.publishTrack(events.TrackStarted, track, "")
+
+	//提前获取到的播放列表下track所在的专辑信息
+	if track.PreType == "playlists" && j.cfg.UseSongInfoForPlaylist {
+		track.GetAlbumData(ctx, token)
+	}
+	// playlist-layout: library 按专辑目录保存，需要专辑信息；取不到时仍保存在歌单目录
+	if j.cfg.PlaylistLayout == "library" && (track.PreType == "playlists" || track.PreType == "stations") && track.Type != "music-videos" && track.AlbumData.ID == "" {
+		if err := track.GetAlbumData(ctx, token); err != nil {
+			j.AddWarning(fmt.Sprintf("[%s - %s] Album info unavailable, saved in playlist folder: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+		}
+	}
+
+	//mv dl dev
+	if track.Type == "music-videos" {
+		if len(mediaUserToken) <= 50 {
+			fmt.Println("meida-user-token is not set, skip MV dl")
+			j.incSuccess()
+			j.AddWarning("MV skipped: media-user-token not set")
+			return TrackSkipped, "media-user-token not set"
+		}
+		if _, err := exec.LookPath("mp4decrypt"); err != nil {
+			fmt.Println("mp4decrypt is not found, skip MV dl")
+			j.incSuccess()
+			j.AddWarning("MV skipped: mp4decrypt not found")
+			return TrackSkipped, "mp4decrypt not found"
+		}
+		// 歌曲上下文标签用于即时错误提示
+		songTag := fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name)
+		if j.dryRun {
+			mvPath, err := j.planMV(ctx, track.ID, track.SaveDir, token, track.Storefront, track)
+			if err != nil {
+				j.incError()
+				j.AddError(fmt.Sprintf("%s MV info unavailable: %v", songTag, err))
+				return j.failTrack(track, fmt.Sprintf("MV info unavailable: %v", err))
+			}
+			track.SavePath = mvPath
+			return TrackPlanned, ""
+		}
+		err := j.mvDownloader(ctx, track.ID, track.SaveDir, token, track.Storefront, mediaUserToken, track)
+		if err != nil {
+			fmt.Println("\u26A0 Failed to dl MV:", songTag, err)
+			j.incError()
+			j.AddError(fmt.Sprintf("%s MV download failed: %v", songTag, err))
+			return j.failTrack(track, fmt.Sprintf("MV download failed: %v", err))
+		}
+		// 与音频曲目相同记入下载数据库与曲库索引
+		j.incSuccess()
+		j.markTrackOk(track)
+		return TrackOK, ""
+	}
+
+	// Derive per-track download flags from track.Codec when available to avoid global races.
+	localDlAtmos := false
+	localDlAac := false
+	if strings.EqualFold(track.Codec, "ATMOS") {
+		localDlAtmos = true
+	} else if strings.EqualFold(track.Codec, "AAC") {
+		localDlAac = true
+	} else {
+		// fallback to global flags if track codec not set
+		localDlAtmos = j.atmos
+		localDlAac = j.aac
+	}
+
+	// 曲库中已有该歌曲（按歌曲 ID/ISRC，与文件名无关）时直接跳过
+	wantCodec := "ALAC"
+	if localDlAtmos {
+		wantCodec = "ATMOS"
+	} else if localDlAac {
+		wantCodec = "AAC"
+	}
+	if libPath, ok := j.findInLibrary(track, wantCodec); ok {
+		fmt.Println("Track already in library:", libPath)
+		track.SavePath = libPath
+		j.incSuccess()
+		return TrackSkipped, "already in library"
+	}
+
+	needDlAacLc := false
+	if localDlAac && j.cfg.AacType == "aac-lc" {
+		needDlAacLc = true
+	}
+	if track.WebM3u8 == "" && !needDlAacLc {
+		if localDlAtmos {
+			fmt.Println("Unavailable")
+			j.incUnavailable()
+			j.AddWarning("Atmos unavailable, fallback not possible")
+			return j.failTrack(track, "atmos unavailable")
+		}
+		fmt.Println("Unavailable, trying to dl aac-lc")
+		needDlAacLc = true
+	}
+	needCheck := false
+
+	if j.cfg.GetM3u8Mode == "all" {
+		needCheck = true
+	} else if j.cfg.GetM3u8Mode == "hires" && contains(track.Resp.Attributes.AudioTraits, "hi-res-lossless") {
+		needCheck = true
+	}
+	var EnhancedHls_m3u8 string
+	if needCheck && !needDlAacLc {
+		EnhancedHls_m3u8, _ = j.checkM3u8(ctx, track.ID, "song")
+		if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
+			track.DeviceM3u8 = EnhancedHls_m3u8
+			track.M3u8 = EnhancedHls_m3u8
+		}
+	}
+	var Quality string
+	needQuality := strings.Contains(j.cfg.SongFileFormat, "Quality") || strings.Contains(j.cfg.SongFileFormat, "SampleRate")
+	if j.libraryTrack(track) && (strings.Contains(j.cfg.AlbumFolderFormat, "Quality") || strings.Contains(j.cfg.AlbumFolderFormat, "SampleRate")) {
+		needQuality = true
+	}
+	if needQuality {
+		if localDlAtmos {
+			Quality = fmt.Sprintf("%dKbps", j.cfg.AtmosMax-2000)
+		} else if needDlAacLc {
+			Quality = "256Kbps"
+		} else {
+			_, Quality, _, err = j.extractMedia(ctx, track.M3u8, true)
+			if err != nil {
+				fmt.Println("Failed to extract quality from manifest.\n", err)
+				j.incError()
+				return j.failTrack(track, fmt.Sprintf("quality extract failed: %v", err))
+			}
+		}
+	}
+	track.Quality = Quality
+	if j.libraryTrack(track) {
+		track.SaveDir = j.libraryFolder(track, Quality, localDlAtmos, needDlAacLc)
+	}
+
+	stringsToJoin := []string{}
+	if track.Resp.Attributes.IsAppleDigitalMaster {
+		if j.cfg.AppleMasterChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.AppleMasterChoice)
+		}
+	}
+	if track.Resp.Attributes.ContentRating == "explicit" {
+		if j.cfg.ExplicitChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.ExplicitChoice)
+		}
+	}
+	if track.Resp.Attributes.ContentRating == "clean" {
+		if j.cfg.CleanChoice != "" {
+			stringsToJoin = append(stringsToJoin, j.cfg.CleanChoice)
+		}
+	}
+	Tag_string := strings.Join(stringsToJoin, " ")
+
+	fields := j.trackFields(track)
+	fields.Tag = Tag_string
+	fields.SampleRate = sampleRate(Quality, localDlAtmos, needDlAacLc)
+	songName := j.render("song-file-format", j.cfg.SongFileFormat, fields)
+	fmt.Println(songName)
+	baseName := j.safeName(track.SaveDir, songName, extReserve)
+	filename := fmt.Sprintf("%s.m4a", baseName)
+	track.SaveName = filename
+	trackPath := filepath.Join(track.SaveDir, track.SaveName)
+	lrcFilename := fmt.Sprintf("%s.%s", baseName, j.cfg.LrcFormat)
+	// 多碟专辑的分碟目录在用到时才创建
+	if err := j.mkdirAll(track.SaveDir); err != nil {
+		j.incError()
+		return j.failTrack(track, fmt.Sprintf("create folder failed: %v", err))
+	}
+
+	// Determine possible post-conversion target file (so we can skip re-download)
+	var convertedPath string
+	considerConverted := false
+	if j.cfg.ConvertAfterDownload &&
+		j.cfg.ConvertFormat != "" &&
+		strings.ToLower(j.cfg.ConvertFormat) != "copy" &&
+		!j.cfg.ConvertKeepOriginal {
+		convertedPath = strings.TrimSuffix(trackPath, filepath.Ext(trackPath)) + "." + strings.ToLower(j.cfg.ConvertFormat)
+		considerConverted = true
+	}
+
+	// 歌词在存在性检查之前写入，已有曲目同样会更新歌词
+	if j.cfg.SaveLrcFile && j.dryRun {
+		tr.Lyrics = filepath.Join(track.SaveDir, lrcFilename)
+	}
+
+	//get lrc
+	var lrc string = ""
+	if (j.cfg.EmbedLrc || j.cfg.SaveLrcFile) && !j.dryRun {
+		lrcStr, err := lyrics.Get(ctx, track.Storefront, track.ID, j.cfg.LrcType, j.cfg.Language, j.cfg.LrcFormat, token, mediaUserToken)
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			if j.cfg.SaveLrcFile {
+				err := writeLyrics(track.SaveDir, lrcFilename, lrcStr)
+				if err != nil {
+					fmt.Printf("Failed to write lyrics")
+				} else {
+					tr.Lyrics = filepath.Join(track.SaveDir, lrcFilename)
+				}
+			}
+			if j.cfg.EmbedLrc {
+				lrc = lrcStr
+			}
+		}
+	}
+
+	if status, reason, ok := j.syncMove(track, trackPath, convertedPath, lrc); ok {
+		return status, reason
+	}
+
+	// Existence check now considers converted output (if original was deleted)
+	existsOriginal, err := fileExists(trackPath)
+	if err != nil {
+		fmt.Println("Failed to check if track exists.")
+	}
+	if existsOriginal {
+		fmt.Println("Track already exists locally.")
+		track.SavePath = trackPath
+		j.incSuccess()
+		return TrackSkipped, "already exists"
+	}
+	if considerConverted {
+		existsConverted, err2 := fileExists(convertedPath)
+		if err2 == nil && existsConverted {
+			fmt.Println("Converted track already exists locally.")
+			track.SavePath = convertedPath
+			j.incSuccess()
+			return TrackSkipped, "converted file exists"
+		}
+	}
+
+	// DryRun：解析出将要下载的音轨后结束
+	if j.dryRun {
+		track.SavePath = trackPath
+		if considerConverted {
+			track.SavePath = convertedPath
+		}
+		if needDlAacLc {
+			tr.Variant = "aac-lc"
+			return TrackPlanned, ""
+		}
+		_, _, codec, err := j.extractMedia(ctx, track.M3u8, false)
+		if err != nil {
+			j.incUnavailable()
+			return TrackUnavailable, fmt.Sprintf("manifest extract failed: %v", err)
+		}
+		tr.Variant = codec
+		return TrackPlanned, ""
+	}
+
+	// 下载、封面与标签都在临时文件上完成，最后再重命名为 trackPath；中途失败时删除
+	partPath := atomicfile.PartPath(trackPath)
+	defer os.Remove(partPath)
+	expect := verify.Expect{Duration: time.Duration(track.Resp.Attributes.DurationInMillis) * time.Millisecond}
+	if needDlAacLc {
+		j.acquireDownloadSlot()
+		defer j.releaseDownloadSlot()
+		if len(mediaUserToken) <= 50 {
+			fmt.Println("Invalid media-user-token:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name))
+			j.incError()
+			j.AddError(fmt.Sprintf("[%s - %s] AAC-LC download failed: invalid media-user-token", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name))
+			return j.failTrack(track, "invalid media-user-token")
+		}
+		_, err := runv3.Run(ctx, track.ID, partPath, token, mediaUserToken, false, "", j.bus)
+		if err != nil {
+			fmt.Println("Failed to dl aac-lc:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
+			if err.Error() == "Unavailable" {
+				j.incUnavailable()
+				j.AddWarning("AAC-LC unavailable")
+				return TrackUnavailable, "AAC-LC unavailable"
+			}
+			j.incError()
+			j.AddError(fmt.Sprintf("[%s - %s] AAC-LC download failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+			return j.failTrack(track, fmt.Sprintf("AAC-LC download failed: %v", err))
+		}
+	} else {
+		j.acquireDownloadSlot()
+		defer j.releaseDownloadSlot()
+		trackM3u8Url, _, _, err := j.extractMedia(ctx, track.M3u8, false)
+		if err != nil {
+			fmt.Println("\u26A0 Failed to extract info from manifest:", err)
+			j.incUnavailable()
+			j.AddWarning(fmt.Sprintf("Manifest extract failed: %v", err))
+			return j.failTrack(track, fmt.Sprintf("manifest extract failed: %v", err))
+		}
+		//边下载边解密
+		expect.Fragments, err = runv2.Run(ctx, track.ID, trackM3u8Url, partPath, j.cfg, j.bus)
+		if err != nil {
+			fmt.Println("Failed to run v2:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
+			j.incError()
+			j.AddError(fmt.Sprintf("[%s - %s] HLS run failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+			return j.failTrack(track, fmt.Sprintf("HLS run failed: %v", err))
+		}
+	}
+	// 校验解密结果：文件完整、分片数据齐全且数量与播放列表一致、sample entry 正常、时长与元数据一致
+	if _, err := verify.File(partPath, expect); err != nil {
+		fmt.Println("\u26A0 Verification failed:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
+		j.incError()
+		j.AddError(fmt.Sprintf("[%s - %s] Verification failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+		return j.failTrack(track, fmt.Sprintf("verify failed: %v", err))
+	}
+	tags := []string{
+		"tool=",
+		"artist=AppleMusic",
+	}
+	// 歌单曲目按专辑目录保存时同样嵌入专辑封面
+	ownCover := (track.PreType == "playlists" || track.PreType == "stations") && (j.cfg.DlAlbumcoverForPlaylist || j.libraryTrack(track))
+	if j.cfg.EmbedCover {
+		if ownCover {
+			track.CoverPath, err = j.writeCover(ctx, track.SaveDir, track.ID, track.Resp.Attributes.Artwork.URL)
+			if err != nil {
+				fmt.Println("Failed to write cover.")
+				j.AddWarning("Embed cover failed")
+			}
+		}
+		tags = append(tags, fmt.Sprintf("cover=%s", track.CoverPath))
+	}
+	tagsString := strings.Join(tags, ":")
+	j.acquireTagSlot()
+	if err := runCmdTimeout(ctx, 2*time.Minute, "MP4Box", "-itags", tagsString, partPath); err != nil {
+		fmt.Printf("Embed failed %s: %v\n", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
+		j.incError()
+		j.AddError(fmt.Sprintf("[%s - %s] Tag embed failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+		j.releaseTagSlot()
+		return j.failTrack(track, fmt.Sprintf("tag embed failed: %v", err))
+	}
+	j.releaseTagSlot()
+	if ownCover && j.cfg.EmbedCover {
+		if err := os.Remove(track.CoverPath); err != nil {
+			fmt.Printf("Error deleting file %s: %s\n", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), track.CoverPath)
+			j.incError()
+			j.AddError(fmt.Sprintf("[%s - %s] Delete cover failed: %s", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, track.CoverPath))
+			return j.failTrack(track, fmt.Sprintf("delete cover failed: %v", err))
+		}
+	}
+	track.SavePath = partPath
+	err = j.writeMP4Tags(track, lrc)
+	if err != nil {
+		fmt.Println("\u26A0 Failed to write tags in media:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
+		j.incUnavailable()
+		j.AddWarning(fmt.Sprintf("Write MP4 tags failed: %v", err))
+		return j.failTrack(track, fmt.Sprintf("write MP4 tags failed: %v", err))
+	}
+	if err := atomicfile.Rename(partPath, trackPath); err != nil {
+		fmt.Println("Failed to save track:", fmt.Sprintf("[%s - %s]", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name), err)
+		j.incError()
+		j.AddError(fmt.Sprintf("[%s - %s] Save failed: %v", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name, err))
+		return j.failTrack(track, fmt.Sprintf("save failed: %v", err))
+	}
+	track.SavePath = trackPath
+	j.publishTrack(events.TagDone, track, "")
+
+	// CONVERSION FEATURE hook
+	j.convertIfNeeded(ctx, track)
+
+	j.incSuccess()
+	j.markTrackOk(track)
+	return TrackOK, ""
+}
+
+// failTrack 记录曲目失败，返回 ripTrack 的结果状态
+func (j *job) failTrack(track *task.Track, reason string) (string, string) {
+	j.markTrackFail(track, reason)
+	return TrackFailed, reason
+}
+
+// runTrack 下载曲目并记入本次调用的结果，idx 为曲目在专辑/歌单中的序号
+func (j *job) runTrack(ctx context.Context, idx int, track *task.Track, token string, mediaUserToken string) {
+	tr := TrackResult{
+		Index: idx,
+		ID:    track.ID,
+		Name:  fmt.Sprintf("%s - %s", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name),
+	}
+	tr.Status, tr.Reason = j.ripTrack(ctx, track, token, mediaUserToken, &tr)
+	tr.Codec, tr.Quality, tr.Path = track.Codec, track.Quality, track.SavePath
+	// 完成与失败由 markTrackOk / markTrackFail 发布，其余提前结束的曲目在这里补发结束事件
+	switch tr.Status {
+	case TrackSkipped, TrackPlanned:
+		j.publishTrack(events.TrackSkipped, track, tr.Reason)
+	case TrackUnavailable:
+		j.publishTrack(events.TrackFailed, track, tr.Reason)
+	}
+	j.addTrack(tr)
+}
+
+func (d *Downloader) writeMP4Tags(track *task.Track, lrc string) error {
+	// Build MP4 tag structure
+	t := &mp4tag.MP4Tags{
+		Title:      track.Resp.Attributes.Name,
+		TitleSort:  track.Resp.Attributes.Name,
+		Artist:     track.Resp.Attributes.ArtistName,
+		ArtistSort: track.Resp.Attributes.ArtistName,
+		Custom: map[string]string{
+			"PERFORMER":   track.Resp.Attributes.ArtistName,
+			"RELEASETIME": track.Resp.Attributes.ReleaseDate,
+			"ISRC":        track.Resp.Attributes.Isrc,
+			SongIDTag:     track.ID,
+		},
+		Composer:     track.Resp.Attributes.ComposerName,
+		ComposerSort: track.Resp.Attributes.ComposerName,
+		Lyrics:       lrc,
+		TrackNumber:  int16(track.Resp.Attributes.TrackNumber),
+		DiscNumber:   int16(track.Resp.Attributes.DiscNumber),
+		Album:        track.Resp.Attributes.AlbumName,
+		AlbumSort:    track.Resp.Attributes.AlbumName,
+	}
+
+	// Optional fields depending on pre-type
+	if track.PreType == "albums" {
+		if albumID, err := strconv.ParseUint(track.PreID, 10, 32); err == nil {
+			t.ItunesAlbumID = int32(albumID)
+		}
+	} else if d.libraryTrack(track) {
+		if albumID, err := strconv.ParseUint(track.AlbumData.ID, 10, 32); err == nil {
+			t.ItunesAlbumID = int32(albumID)
+		}
+	}
+	if len(track.Resp.Relationships.Artists.Data) > 0 {
+		if artistID, err := strconv.ParseUint(track.Resp.Relationships.Artists.Data[0].ID, 10, 32); err == nil {
+			t.ItunesArtistID = int32(artistID)
+		}
+	}
+
+	// 按专辑目录保存的歌单曲目写入专辑信息
+	inPlaylist := (track.PreType == "playlists" || track.PreType == "stations") && !d.libraryTrack(track)
+	if inPlaylist && !d.cfg.UseSongInfoForPlaylist {
+		t.DiscNumber = 1
+		t.DiscTotal = 1
+		t.TrackNumber = int16(track.TaskNum)
+		t.TrackTotal = int16(track.TaskTotal)
+		t.Album = track.PlaylistData.Attributes.Name
+		t.AlbumSort = track.PlaylistData.Attributes.Name
+		t.AlbumArtist = track.PlaylistData.Attributes.ArtistName
+		t.AlbumArtistSort = track.PlaylistData.Attributes.ArtistName
+	} else if inPlaylist && d.cfg.UseSongInfoForPlaylist {
+		t.DiscTotal = int16(track.DiscTotal)
+		t.TrackTotal = int16(track.AlbumData.Attributes.TrackCount)
+		t.AlbumArtist = track.AlbumData.Attributes.ArtistName
+		t.AlbumArtistSort = track.AlbumData.Attributes.ArtistName
+		t.Custom["UPC"] = track.AlbumData.Attributes.Upc
+		t.Custom["LABEL"] = track.AlbumData.Attributes.RecordLabel
+		t.Date = track.AlbumData.Attributes.ReleaseDate
+		t.Copyright = track.AlbumData.Attributes.Copyright
+		t.Publisher = track.AlbumData.Attributes.RecordLabel
+	} else {
+		t.DiscTotal = int16(track.DiscTotal)
+		t.TrackTotal = int16(track.AlbumData.Attributes.TrackCount)
+		t.AlbumArtist = track.AlbumData.Attributes.ArtistName
+		t.AlbumArtistSort = track.AlbumData.Attributes.ArtistName
+		t.Custom["UPC"] = track.AlbumData.Attributes.Upc
+		t.Date = track.AlbumData.Attributes.ReleaseDate
+		t.Copyright = track.AlbumData.Attributes.Copyright
+		t.Publisher = track.AlbumData.Attributes.RecordLabel
+	}
+
+	switch track.Resp.Attributes.ContentRating {
+	case "explicit":
+		t.ItunesAdvisory = mp4tag.ItunesAdvisoryExplicit
+	case "clean":
+		t.ItunesAdvisory = mp4tag.ItunesAdvisoryClean
+	default:
+		t.ItunesAdvisory = mp4tag.ItunesAdvisoryNone
+	}
+
+	mp4f, err := mp4tag.Open(track.SavePath)
+	if err != nil {
+		return err
+	}
+	defer mp4f.Close()
+	if err := mp4f.Write(t, []string{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// skipTrack 记录此前已完成的曲目
+func (j *job) skipTrack(idx int, track *task.Track) {
+	j.incTotal()
+	j.incSuccess()
+	j.addTrack(TrackResult{
+		Index:  idx,
+		ID:     track.ID,
+		Name:   fmt.Sprintf("%s - %s", track.Resp.Attributes.ArtistName, track.Resp.Attributes.Name),
+		Status: TrackSkipped,
+		Reason: "already downloaded",
+	})
+	j.publishTrack(events.TrackSkipped, track, "already downloaded")
+}
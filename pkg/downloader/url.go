@@ -0,0 +1,383 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"main/utils/ampapi"
+	"main/utils/events"
+	"main/utils/structs"
+)
+
+func checkUrl(url string) (string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/album|\/album\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", ""
+	} else {
+		return matches[0][1], matches[0][2]
+	}
+}
+func checkUrlMv(url string) (string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music)\.apple\.com\/(\w{2})(?:\/music-video|\/music-video\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", ""
+	} else {
+		return matches[0][1], matches[0][2]
+	}
+}
+func checkUrlSong(url string) (string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/song|\/song\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", ""
+	} else {
+		return matches[0][1], matches[0][2]
+	}
+}
+func checkUrlPlaylist(url string) (string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/playlist|\/playlist\/.+))\/(?:id)?(pl\.[\w-]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", ""
+	} else {
+		return matches[0][1], matches[0][2]
+	}
+}
+
+func checkUrlStation(url string) (string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music)\.apple\.com\/(\w{2})(?:\/station|\/station\/.+))\/(?:id)?(ra\.[\w-]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", ""
+	} else {
+		return matches[0][1], matches[0][2]
+	}
+}
+
+func checkUrlArtist(url string) (string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})(?:\/artist|\/artist\/.+))\/(?:id)?(\d[^\D]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", ""
+	} else {
+		return matches[0][1], matches[0][2]
+	}
+}
+
+// checkUrlCurator 唱片公司（record-label）、策展人（curator）与 Apple Music 编辑（apple-curator）页面
+func checkUrlCurator(url string) (string, string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(\w{2})\/(record-label|apple-curator|curator)(?:\/.+)?)\/(?:id)?(\d[^\D]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", "", ""
+	}
+	return matches[0][2], matches[0][1], matches[0][3]
+}
+
+// checkUrlLibrary 资料库中的歌单（p.）与专辑（l.）链接，链接中通常没有区域
+func checkUrlLibrary(url string) (string, string, string) {
+	pat := regexp.MustCompile(`^(?:https:\/\/(?:beta\.music|music|classical\.music)\.apple\.com\/(?:(\w{2})\/)?library\/(playlist|albums?))\/((?:p|l)\.[\w-]+)(?:$|\?)`)
+	matches := pat.FindAllStringSubmatch(url, -1)
+
+	if matches == nil {
+		return "", "", ""
+	}
+	kind := "playlist"
+	if strings.HasPrefix(matches[0][2], "album") {
+		kind = "album"
+	}
+	return kind, matches[0][1], matches[0][3]
+}
+
+// ParseURL 解析 Apple Music 链接的实体类型、区域与 ID，无法识别时返回空字符串；
+// 资料库链接的区域为空，使用配置中的 storefront
+func ParseURL(urlRaw string) (kind string, storefront string, id string) {
+	switch {
+	case strings.Contains(urlRaw, "/library/"):
+		kind, storefront, id = checkUrlLibrary(urlRaw)
+	case strings.Contains(urlRaw, "/record-label/"), strings.Contains(urlRaw, "/curator/"), strings.Contains(urlRaw, "/apple-curator/"):
+		kind, storefront, id = checkUrlCurator(urlRaw)
+	case strings.Contains(urlRaw, "/artist/"):
+		storefront, id = checkUrlArtist(urlRaw)
+		kind = "artist"
+	case strings.Contains(urlRaw, "/music-video/"):
+		storefront, id = checkUrlMv(urlRaw)
+		kind = "music-video"
+	case strings.Contains(urlRaw, "/song/"):
+		storefront, id = checkUrlSong(urlRaw)
+		kind = "song"
+	case strings.Contains(urlRaw, "/album/"):
+		storefront, id = checkUrl(urlRaw)
+		kind = "album"
+	case strings.Contains(urlRaw, "/playlist/"):
+		storefront, id = checkUrlPlaylist(urlRaw)
+		kind = "playlist"
+	case strings.Contains(urlRaw, "/station/"):
+		storefront, id = checkUrlStation(urlRaw)
+		kind = "station"
+	}
+	if id == "" {
+		return "", "", ""
+	}
+	return kind, storefront, id
+}
+
+// EntityURL 由下载记录的实体类型（albums / playlists / stations）、区域与 ID 重建链接，
+// 资料库中的歌单与专辑使用资料库链接
+func EntityURL(entityType string, storefront string, id string) string {
+	if isLibraryID(id) {
+		path := "playlist"
+		if entityType == "albums" {
+			path = "albums"
+		}
+		if storefront == "" {
+			return fmt.Sprintf("https://music.apple.com/library/%s/%s", path, id)
+		}
+		return fmt.Sprintf("https://music.apple.com/%s/library/%s/%s", storefront, path, id)
+	}
+	// albums -> album, playlists -> playlist, stations -> station
+	kind := strings.TrimSuffix(entityType, "s")
+	return fmt.Sprintf("https://music.apple.com/%s/%s/%s", storefront, kind, id)
+}
+
+// ArtistName 获取艺术家名称
+func (d *Downloader) ArtistName(ctx context.Context, storefront string, artistId string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/artists/%s", storefront, artistId), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.token))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("Origin", "https://music.apple.com")
+	query := url.Values{}
+	query.Set("l", d.cfg.Language)
+	req.URL.RawQuery = query.Encode()
+	do, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer do.Body.Close()
+	if do.StatusCode != http.StatusOK {
+		return "", errors.New(do.Status)
+	}
+	obj := new(structs.AutoGeneratedArtist)
+	err = json.NewDecoder(do.Body).Decode(&obj)
+	if err != nil {
+		return "", err
+	}
+	if len(obj.Data) == 0 {
+		return "", errors.New("artist not found")
+	}
+	return obj.Data[0].Attributes.Name, nil
+}
+
+// ArtistItem 艺术家页面中的一张专辑或一个 MV
+type ArtistItem struct {
+	ID            string
+	Name          string
+	ReleaseDate   string
+	URL           string
+	ArtistName    string
+	IsSingle      bool
+	IsCompilation bool
+	TrackCount    int
+}
+
+// ArtistItems 分页获取艺术家的 albums / music-videos，按发行日期升序
+func (d *Downloader) ArtistItems(ctx context.Context, storefront string, artistId string, relationship string) ([]ArtistItem, error) {
+	Num := 0
+	var items []ArtistItem
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/artists/%s/%s?limit=100&offset=%d&l=%s", storefront, artistId, relationship, Num, d.cfg.Language), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.token))
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+		req.Header.Set("Origin", "https://music.apple.com")
+		do, err := d.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer do.Body.Close()
+		if do.StatusCode != http.StatusOK {
+			return nil, errors.New(do.Status)
+		}
+		obj := new(structs.AutoGeneratedArtist)
+		err = json.NewDecoder(do.Body).Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, album := range obj.Data {
+			items = append(items, ArtistItem{
+				ID:            album.ID,
+				Name:          album.Attributes.Name,
+				ReleaseDate:   album.Attributes.ReleaseDate,
+				URL:           album.Attributes.URL,
+				ArtistName:    album.Attributes.ArtistName,
+				IsSingle:      album.Attributes.IsSingle,
+				IsCompilation: album.Attributes.IsCompilation,
+				TrackCount:    album.Attributes.TrackCount,
+			})
+		}
+		Num = Num + 100
+		if len(obj.Next) == 0 {
+			break
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		// 将日期字符串解析为 time.Time 类型进行比较
+		dateI, _ := time.Parse("2006-01-02", items[i].ReleaseDate)
+		dateJ, _ := time.Parse("2006-01-02", items[j].ReleaseDate)
+		return dateI.Before(dateJ) // 返回 true 表示 i 在 j 前面
+	})
+	return items, nil
+}
+
+// RecordLabelItems 获取唱片公司名称与全部专辑，按发行日期升序
+func (d *Downloader) RecordLabelItems(ctx context.Context, storefront string, labelID string) (string, []ArtistItem, error) {
+	label, err := ampapi.GetCatalogItem(ctx, storefront, "record-labels", labelID, d.cfg.Language, d.token)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := ampapi.GetRecordLabelReleases(ctx, storefront, labelID, d.cfg.Language, d.token)
+	if err != nil {
+		return "", nil, err
+	}
+	items := catalogArtistItems(data)
+	sort.SliceStable(items, func(i, j int) bool {
+		dateI, _ := time.Parse("2006-01-02", items[i].ReleaseDate)
+		dateJ, _ := time.Parse("2006-01-02", items[j].ReleaseDate)
+		return dateI.Before(dateJ)
+	})
+	return label.Attributes.Name, items, nil
+}
+
+// CuratorItems 获取 curators / apple-curators 的名称与全部歌单，保持页面中的顺序
+func (d *Downloader) CuratorItems(ctx context.Context, storefront string, kind string, curatorID string) (string, []ArtistItem, error) {
+	curator, err := ampapi.GetCatalogItem(ctx, storefront, kind, curatorID, d.cfg.Language, d.token)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := ampapi.GetCuratorPlaylists(ctx, storefront, kind, curatorID, d.cfg.Language, d.token)
+	if err != nil {
+		return "", nil, err
+	}
+	return curator.Attributes.Name, catalogArtistItems(data), nil
+}
+
+func catalogArtistItems(data []ampapi.CatalogItemData) []ArtistItem {
+	items := make([]ArtistItem, 0, len(data))
+	for _, it := range data {
+		artist := it.Attributes.ArtistName
+		if artist == "" {
+			artist = it.Attributes.CuratorName
+		}
+		items = append(items, ArtistItem{
+			ID:            it.ID,
+			Name:          it.Attributes.Name,
+			ReleaseDate:   it.Attributes.ReleaseDate,
+			URL:           it.Attributes.URL,
+			ArtistName:    artist,
+			IsSingle:      it.Attributes.IsSingle,
+			IsCompilation: it.Attributes.IsCompilation,
+			TrackCount:    it.Attributes.TrackCount,
+		})
+	}
+	return items
+}
+
+// RipURL 按链接类型下载，前后发布 JobStarted / JobFinished 事件
+func (d *Downloader) RipURL(ctx context.Context, rawURL string, opts RipOptions) (res *Result, err error) {
+	kind, storefront, id := ParseURL(rawURL)
+	bus := opts.Events
+	if bus == nil {
+		bus = d.bus
+	}
+	bus.Publish(events.Event{Type: events.JobStarted, URL: rawURL, Kind: kind, EntityID: id})
+	defer func() {
+		e := events.Event{Type: events.JobFinished, URL: rawURL, Kind: kind, EntityID: id}
+		if err != nil {
+			e.Reason = "failed"
+		} else if res != nil && res.Skipped != "" {
+			e.Reason = "skipped"
+		}
+		bus.Publish(e)
+	}()
+	if opts.Storefront == "" {
+		opts.Storefront = storefront
+	}
+	switch kind {
+	case "artist":
+		return d.RipArtist(ctx, id, opts)
+	case "record-label":
+		return d.RipRecordLabel(ctx, id, opts)
+	case "curator", "apple-curator":
+		return d.RipCurator(ctx, kind, id, opts)
+	case "music-video":
+		fmt.Println("Music Video")
+		return d.RipMusicVideo(ctx, id, opts)
+	case "song":
+		fmt.Printf("Song->")
+		res, err = d.RipSong(ctx, id, opts)
+		if err != nil {
+			fmt.Println("Failed to rip song:", err)
+			d.AddError(fmt.Sprintf("Rip song failed: %v", err))
+		}
+		return res, err
+	case "album":
+		fmt.Println("Album")
+		if d.song {
+			if parse, perr := url.Parse(rawURL); perr == nil {
+				opts.TrackID = parse.Query().Get("i")
+			}
+		}
+		res, err = d.RipAlbum(ctx, id, opts)
+		if err != nil {
+			fmt.Println("Failed to rip album:", err)
+			d.AddError(fmt.Sprintf("Rip album failed: %v", err))
+		}
+		return res, err
+	case "playlist":
+		fmt.Println("Playlist")
+		res, err = d.RipPlaylist(ctx, id, opts)
+		if err != nil {
+			fmt.Println("Failed to rip playlist:", err)
+			d.AddError(fmt.Sprintf("Rip playlist failed: %v", err))
+		}
+		return res, err
+	case "station":
+		fmt.Printf("Station")
+		if len(d.cfg.MediaUserToken) <= 50 {
+			fmt.Println(": meida-user-token is not set, skip station dl")
+			d.AddWarning("Station skipped: media-user-token not set")
+			return &Result{Kind: kind, ID: id, Skipped: "media-user-token not set"}, nil
+		}
+		res, err = d.RipStation(ctx, id, opts)
+		if err != nil {
+			fmt.Println("Failed to rip station:", err)
+			d.AddError(fmt.Sprintf("Rip station failed: %v", err))
+		}
+		return res, err
+	}
+	fmt.Println("Invalid type")
+	err = fmt.Errorf("unsupported URL: %s", rawURL)
+	return &Result{Kind: kind, ID: id, Error: err.Error()}, err
+}
@@ -0,0 +1,73 @@
+package downloader
+
+import "testing"
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url        string
+		kind       string
+		storefront string
+		id         string
+	}{
+		{"https://music.apple.com/us/album/1989-taylors-version/1708308989", "album", "us", "1708308989"},
+		{"https://music.apple.com/us/album/1989-taylors-version/1708308989?i=1708309136", "album", "us", "1708308989"},
+		{"https://music.apple.com/jp/album/1440935467", "album", "jp", "1440935467"},
+		{"https://classical.music.apple.com/us/album/some-album/1234567890", "album", "us", "1234567890"},
+		{"https://beta.music.apple.com/gb/album/x/id1234567890", "album", "gb", "1234567890"},
+		{"https://music.apple.com/us/song/cruel-summer/1468058171", "song", "us", "1468058171"},
+		{"https://music.apple.com/us/music-video/anti-hero/1650841512", "music-video", "us", "1650841512"},
+		{"https://music.apple.com/us/artist/taylor-swift/159260351", "artist", "us", "159260351"},
+		{"https://music.apple.com/us/playlist/todays-hits/pl.f4d106fed2bd41149aaacabb233eb5eb", "playlist", "us", "pl.f4d106fed2bd41149aaacabb233eb5eb"},
+		{"https://music.apple.com/us/station/pure-pop/ra.1460521710", "station", "us", "ra.1460521710"},
+		{"https://music.apple.com/us/record-label/republic-records/1543411840", "record-label", "us", "1543411840"},
+		{"https://music.apple.com/us/curator/pitchfork/976439587", "curator", "us", "976439587"},
+		{"https://music.apple.com/us/apple-curator/apple-music-pop/976439548", "apple-curator", "us", "976439548"},
+		{"https://music.apple.com/library/playlist/p.AbCd123", "playlist", "", "p.AbCd123"},
+		{"https://music.apple.com/us/library/albums/l.XyZ-9", "album", "us", "l.XyZ-9"},
+		{"https://music.apple.com/library/album/l.XyZ", "album", "", "l.XyZ"},
+		// 无法识别
+		{"", "", "", ""},
+		{"not a url", "", "", ""},
+		{"https://example.com/us/album/x/123", "", "", ""},
+		{"http://music.apple.com/us/album/x/123", "", "", ""},
+		{"https://music.apple.com/us/album/x/not-a-number", "", "", ""},
+		{"https://music.apple.com/us/playlist/x/123", "", "", ""},
+		{"https://music.apple.com/us/station/x/123", "", "", ""},
+		{"https://music.apple.com/us/browse", "", "", ""},
+		{"https://music.apple.com/library/songs/i.abc", "", "", ""},
+	}
+	for _, tt := range tests {
+		kind, storefront, id := ParseURL(tt.url)
+		if kind != tt.kind || storefront != tt.storefront || id != tt.id {
+			t.Errorf("ParseURL(%q) = (%q, %q, %q), want (%q, %q, %q)", tt.url, kind, storefront, id, tt.kind, tt.storefront, tt.id)
+		}
+	}
+}
+
+func TestEntityURL(t *testing.T) {
+	tests := []struct {
+		entityType string
+		storefront string
+		id         string
+		want       string
+		wantKind   string
+	}{
+		{"albums", "us", "1708308989", "https://music.apple.com/us/album/1708308989", "album"},
+		{"playlists", "jp", "pl.f4d106fed2bd41149aaacabb233eb5eb", "https://music.apple.com/jp/playlist/pl.f4d106fed2bd41149aaacabb233eb5eb", "playlist"},
+		{"stations", "us", "ra.1460521710", "https://music.apple.com/us/station/ra.1460521710", "station"},
+		{"playlists", "us", "p.AbCd123", "https://music.apple.com/us/library/playlist/p.AbCd123", "playlist"},
+		{"albums", "gb", "l.XyZ-9", "https://music.apple.com/gb/library/albums/l.XyZ-9", "album"},
+		{"playlists", "", "p.AbCd123", "https://music.apple.com/library/playlist/p.AbCd123", "playlist"},
+	}
+	for _, tt := range tests {
+		got := EntityURL(tt.entityType, tt.storefront, tt.id)
+		if got != tt.want {
+			t.Errorf("EntityURL(%q, %q, %q) = %q, want %q", tt.entityType, tt.storefront, tt.id, got, tt.want)
+		}
+		// 重建的链接必须能被 --retry-failed 重新解析
+		kind, storefront, id := ParseURL(got)
+		if kind != tt.wantKind || storefront != tt.storefront || id != tt.id {
+			t.Errorf("ParseURL(%q) = (%q, %q, %q), want (%q, %q, %q)", got, kind, storefront, id, tt.wantKind, tt.storefront, tt.id)
+		}
+	}
+}
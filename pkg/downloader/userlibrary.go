@@ -0,0 +1,107 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"main/utils/ampapi"
+	"main/utils/task"
+)
+
+// isLibraryID 资料库条目的 ID：歌单 p.、专辑 l.、歌曲 i.
+func isLibraryID(id string) bool {
+	return strings.HasPrefix(id, "p.") || strings.HasPrefix(id, "l.") || strings.HasPrefix(id, "i.")
+}
+
+// getLibraryPlaylist 获取资料库歌单，曲目映射为目录中的歌曲；没有目录 ID 的曲目记一条警告后跳过
+func (j *job) getLibraryPlaylist(ctx context.Context, playlistId string, token string, storefront string, mediaUserToken string) (*task.Playlist, error) {
+	if len(mediaUserToken) <= 50 {
+		return nil, errors.New("media-user-token not set, required for library playlists")
+	}
+	lp := task.NewLibraryPlaylist(storefront, playlistId)
+	if err := lp.GetResp(ctx, mediaUserToken, token, j.cfg.Language); err != nil {
+		return nil, err
+	}
+	for _, m := range lp.Missing {
+		fmt.Println("Not in catalog, skipped:", m)
+		j.AddWarning(fmt.Sprintf("Library track not in catalog: %s", m))
+	}
+	return &lp.Playlist, nil
+}
+
+// libraryCatalogID 资料库中的专辑/歌曲对应的目录 ID
+func (d *Downloader) libraryCatalogID(ctx context.Context, kind string, id string) (string, error) {
+	if len(d.cfg.MediaUserToken) <= 50 {
+		return "", errors.New("media-user-token not set, required for library items")
+	}
+	item, err := ampapi.GetLibraryItem(ctx, kind, id, d.cfg.MediaUserToken, d.cfg.Language, d.token)
+	if err != nil {
+		return "", err
+	}
+	catalogID := item.CatalogID()
+	if catalogID == "" {
+		return "", fmt.Errorf("%s is not available in the catalog", id)
+	}
+	return catalogID, nil
+}
+
+// LibraryItem 资料库中的一个歌单、专辑或歌曲
+type LibraryItem struct {
+	ID         string // 资料库 ID（p. / l. / i.）
+	CatalogID  string // 对应的目录 ID，上传的音乐或自建歌单为空
+	Name       string
+	ArtistName string
+	TrackCount int
+	DateAdded  string
+	URL        string // 可直接下载的链接，无法下载时为空
+}
+
+// LibraryItems 列出资料库中的 playlists / albums / songs，需要 media-user-token
+func (d *Downloader) LibraryItems(ctx context.Context, kind string) ([]LibraryItem, error) {
+	if len(d.cfg.MediaUserToken) <= 50 {
+		return nil, errors.New("media-user-token not set, required for the library")
+	}
+	data, err := ampapi.GetLibraryItems(ctx, kind, d.cfg.MediaUserToken, d.cfg.Language, d.token)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]LibraryItem, 0, len(data))
+	for i := range data {
+		a := data[i].Attributes
+		item := LibraryItem{
+			ID:         data[i].ID,
+			CatalogID:  data[i].CatalogID(),
+			Name:       a.Name,
+			ArtistName: a.ArtistName,
+			TrackCount: a.TrackCount,
+			DateAdded:  a.DateAdded,
+		}
+		if c := data[i].Relationships.Catalog.Data; len(c) > 0 && c[0].Attributes.URL != "" {
+			item.URL = c[0].Attributes.URL
+		}
+		if item.URL == "" {
+			item.URL = libraryURL(kind, d.cfg.Storefront, item.ID, item.CatalogID)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// libraryURL 资料库条目的下载链接：有目录 ID 时为目录链接，自建歌单为资料库链接
+func libraryURL(kind string, storefront string, id string, catalogID string) string {
+	switch {
+	case kind == "playlists" && strings.HasPrefix(catalogID, "pl."):
+		return fmt.Sprintf("https://music.apple.com/%s/playlist/%s", storefront, catalogID)
+	case kind == "playlists":
+		return fmt.Sprintf("https://music.apple.com/library/playlist/%s", id)
+	case catalogID == "":
+		return ""
+	case kind == "albums":
+		return fmt.Sprintf("https://music.apple.com/%s/album/%s", storefront, catalogID)
+	case kind == "songs":
+		return fmt.Sprintf("https://music.apple.com/%s/song/%s", storefront, catalogID)
+	}
+	return ""
+}
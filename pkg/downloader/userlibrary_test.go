@@ -0,0 +1,34 @@
+package downloader
+
+import "testing"
+
+func TestLibraryURL(t *testing.T) {
+	tests := []struct {
+		kind      string
+		id        string
+		catalogID string
+		want      string
+	}{
+		{"playlists", "p.AbCd123", "pl.f4d106fed2bd41149aaacabb233eb5eb", "https://music.apple.com/us/playlist/pl.f4d106fed2bd41149aaacabb233eb5eb"},
+		{"playlists", "p.AbCd123", "", "https://music.apple.com/library/playlist/p.AbCd123"},
+		// 目录 ID 不是 pl. 开头时仍使用资料库链接
+		{"playlists", "p.AbCd123", "123", "https://music.apple.com/library/playlist/p.AbCd123"},
+		{"albums", "l.XyZ", "1708308989", "https://music.apple.com/us/album/1708308989"},
+		{"albums", "l.XyZ", "", ""},
+		{"songs", "i.abc", "1468058171", "https://music.apple.com/us/song/1468058171"},
+		{"songs", "i.abc", "", ""},
+		{"music-videos", "i.abc", "1650841512", ""},
+	}
+	for _, tt := range tests {
+		got := libraryURL(tt.kind, "us", tt.id, tt.catalogID)
+		if got != tt.want {
+			t.Errorf("libraryURL(%q, %q, %q) = %q, want %q", tt.kind, tt.id, tt.catalogID, got, tt.want)
+		}
+		if got == "" {
+			continue
+		}
+		if kind, _, id := ParseURL(got); kind == "" || id == "" {
+			t.Errorf("ParseURL(%q) cannot parse the library URL", got)
+		}
+	}
+}
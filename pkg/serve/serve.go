@@ -0,0 +1,468 @@
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/pkg/downloader"
+	"main/utils/events"
+)
+
+const (
+	jobQueued   = "queued"
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobFailed   = "failed"
+	jobCanceled = "canceled"
+)
+
+type jobTrack struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"` // downloading / ok / skipped / failed
+	Reason string `json:"reason,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// 下载中曲目的字节进度，Stage 为 download 或 decrypt；总大小未知时 TotalBytes 为 0
+	Stage      string `json:"stage,omitempty"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	TotalBytes int64  `json:"totalBytes,omitempty"`
+}
+
+type serveJob struct {
+	ID         string
+	URL        string
+	Kind       string
+	EntityID   string
+	Retry      bool
+	Status     string
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Completed  int
+	Failed     int
+	Tracks     map[string]*jobTrack
+	order      []string
+	canceled   bool
+	stop       context.CancelFunc // 运行中任务的取消函数
+}
+
+// jobView 任务的 JSON 表示
+type jobView struct {
+	ID         string     `json:"id"`
+	URL        string     `json:"url"`
+	Kind       string     `json:"kind"`
+	EntityID   string     `json:"entityId"`
+	Retry      bool       `json:"retry"`
+	Status     string     `json:"status"`
+	Canceling  bool       `json:"canceling,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Completed  int        `json:"completed"`
+	Failed     int        `json:"failed"`
+	Total      int        `json:"total"`
+	Tracks     []jobTrack `json:"tracks,omitempty"`
+}
+
+// Ripper 执行任务的下载器，由 *downloader.Downloader 实现
+type Ripper interface {
+	RipURL(ctx context.Context, rawURL string, opts downloader.RipOptions) (*downloader.Result, error)
+}
+
+// Server 任务队列：固定数量的 worker 依次取任务，共用一个下载器
+type Server struct {
+	ctx    context.Context
+	dl     Ripper
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	jobs   map[string]*serveJob
+	order  []string
+	nextID int
+	queue  chan *serveJob
+}
+
+// New 创建任务队列并启动 workers 个 worker，ctx 取消后不再开始新任务
+func New(ctx context.Context, dl Ripper, workers int) *Server {
+	s := &Server{
+		ctx:   ctx,
+		dl:    dl,
+		jobs:  make(map[string]*serveJob),
+		queue: make(chan *serveJob, 1024),
+	}
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	return s
+}
+
+func (s *Server) enqueue(rawUrl string, retry bool) (*serveJob, error) {
+	kind, _, id := downloader.ParseURL(rawUrl)
+	if kind == "" {
+		return nil, fmt.Errorf("invalid url: %s", rawUrl)
+	}
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		if j.Kind == kind && j.EntityID == id && (j.Status == jobQueued || j.Status == jobRunning) {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("job %s already handles %s %s", j.ID, kind, id)
+		}
+	}
+	s.nextID++
+	job := &serveJob{
+		ID:        strconv.Itoa(s.nextID),
+		URL:       rawUrl,
+		Kind:      kind,
+		EntityID:  id,
+		Retry:     retry,
+		Status:    jobQueued,
+		CreatedAt: time.Now(),
+		Tracks:    make(map[string]*jobTrack),
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- job:
+		return job, nil
+	default:
+		s.mu.Lock()
+		job.Status = jobFailed
+		job.Error = "queue is full"
+		s.mu.Unlock()
+		return nil, errors.New("queue is full")
+	}
+}
+
+// worker 依次执行任务，服务关闭（ctx 取消）后不再取新任务
+func (s *Server) worker() {
+	defer s.wg.Done()
+	for {
+		var job *serveJob
+		select {
+		case <-s.ctx.Done():
+			return
+		case job = <-s.queue:
+		}
+		s.mu.Lock()
+		if job.Status != jobQueued {
+			s.mu.Unlock()
+			continue
+		}
+		ctx, stop := context.WithCancel(s.ctx)
+		job.Status = jobRunning
+		job.StartedAt = time.Now()
+		job.stop = stop
+		s.mu.Unlock()
+
+		err := s.run(ctx, job)
+		stop()
+
+		s.mu.Lock()
+		job.FinishedAt = time.Now()
+		job.stop = nil
+		// 没有明确结果的曲目（如不可用）按失败展示
+		for _, t := range job.Tracks {
+			if t.Status == "downloading" {
+				t.Status = "failed"
+				t.Reason = "unavailable"
+				job.Failed++
+			}
+		}
+		switch {
+		case job.canceled:
+			job.Status = jobCanceled
+		case s.ctx.Err() != nil:
+			job.Status = jobFailed
+			job.Error = "interrupted by shutdown"
+		case err != nil:
+			job.Status = jobFailed
+			job.Error = fmt.Sprintf("failed to load %s: %v", job.Kind, err)
+		case job.Failed > 0:
+			job.Status = jobFailed
+		default:
+			job.Status = jobDone
+		}
+		s.mu.Unlock()
+	}
+}
+
+// run 每个任务使用独立的事件总线，曲目事件不会归到同时运行的其他任务；
+// 事件同时转发到全局总线
+func (s *Server) run(ctx context.Context, job *serveJob) error {
+	bus := &events.Bus{}
+	unsubscribe := bus.Subscribe(func(e events.Event) {
+		s.observeTrack(job, e)
+		events.Publish(e)
+	})
+	defer unsubscribe()
+	_, err := s.dl.RipURL(ctx, job.URL, downloader.RipOptions{Retry: job.Retry, Events: bus})
+	return err
+}
+
+// observeTrack 记录任务的曲目状态与字节进度
+func (s *Server) observeTrack(job *serveJob, e events.Event) {
+	var status string
+	switch e.Type {
+	case events.DownloadProgress, events.DecryptProgress:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t, ok := job.Tracks[e.SongID]
+		if !ok || t.Status != "downloading" {
+			return
+		}
+		t.Stage = "download"
+		if e.Type == events.DecryptProgress {
+			t.Stage = "decrypt"
+		}
+		t.Bytes = e.Bytes
+		t.TotalBytes = max(e.TotalBytes, 0)
+		return
+	case events.TrackStarted:
+		status = "downloading"
+	case events.TrackDone:
+		status = "ok"
+	case events.TrackSkipped:
+		status = "skipped"
+	case events.TrackFailed:
+		status = "failed"
+	default:
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := job.Tracks[e.SongID]
+	if !ok {
+		t = &jobTrack{ID: e.SongID, Name: e.Name}
+		job.Tracks[e.SongID] = t
+		job.order = append(job.order, e.SongID)
+	}
+	// 已存在或已在曲库中的曲目同样计为完成
+	switch t.Status {
+	case "ok", "skipped":
+		job.Completed--
+	case "failed":
+		job.Failed--
+	}
+	t.Status, t.Reason, t.Path = status, e.Reason, e.Path
+	t.Stage, t.Bytes, t.TotalBytes = "", 0, 0
+	switch status {
+	case "ok", "skipped":
+		job.Completed++
+	case "failed":
+		job.Failed++
+	}
+}
+
+// cancel 排队中的任务直接取消；运行中的任务不再开始新曲目，正在下载的曲目中止并清理未完成的文件
+func (s *Server) cancel(id string) (*serveJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	switch job.Status {
+	case jobQueued:
+		job.canceled = true
+		job.Status = jobCanceled
+	case jobRunning:
+		job.canceled = true
+		if job.stop != nil {
+			job.stop()
+		}
+	default:
+		return job, fmt.Errorf("job %s is already %s", id, job.Status)
+	}
+	return job, nil
+}
+
+// view 复制任务状态用于输出，调用方需持有 s.mu
+func (job *serveJob) view(withTracks bool) jobView {
+	v := jobView{
+		ID:        job.ID,
+		URL:       job.URL,
+		Kind:      job.Kind,
+		EntityID:  job.EntityID,
+		Retry:     job.Retry,
+		Status:    job.Status,
+		Canceling: job.Status == jobRunning && job.canceled,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		Completed: job.Completed,
+		Failed:    job.Failed,
+		Total:     len(job.Tracks),
+	}
+	if !job.StartedAt.IsZero() {
+		t := job.StartedAt
+		v.StartedAt = &t
+	}
+	if !job.FinishedAt.IsZero() {
+		t := job.FinishedAt
+		v.FinishedAt = &t
+	}
+	if withTracks {
+		v.Tracks = make([]jobTrack, 0, len(job.order))
+		for _, id := range job.order {
+			v.Tracks = append(v.Tracks, *job.Tracks[id])
+		}
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// Wait 等待所有 worker 退出（ctx 取消后运行中的任务清理完毕）
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Handler 任务队列的 HTTP/JSON API，authToken 非空时每个请求都需要 Authorization: Bearer <token>
+func (s *Server) Handler(authToken string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL   string   `json:"url"`
+			URLs  []string `json:"urls"`
+			Retry bool     `json:"retry"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+			return
+		}
+		urls := req.URLs
+		if req.URL != "" {
+			urls = append([]string{req.URL}, urls...)
+		}
+		if len(urls) == 0 {
+			writeJSONError(w, http.StatusBadRequest, errors.New("url or urls is required"))
+			return
+		}
+		created := []jobView{}
+		var errs []string
+		for _, u := range urls {
+			job, err := s.enqueue(strings.TrimSpace(u), req.Retry)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			s.mu.Lock()
+			created = append(created, job.view(false))
+			s.mu.Unlock()
+		}
+		code := http.StatusCreated
+		if len(created) == 0 {
+			code = http.StatusConflict
+		}
+		writeJSON(w, code, map[string]interface{}{"jobs": created, "errors": errs})
+	})
+
+	mux.HandleFunc("GET /v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		s.mu.Lock()
+		list := make([]jobView, 0, len(s.order))
+		for _, id := range s.order {
+			job := s.jobs[id]
+			if status != "" && job.Status != status {
+				continue
+			}
+			list = append(list, job.view(false))
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": list})
+	})
+
+	mux.HandleFunc("GET /v1/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		job, ok := s.jobs[r.PathValue("id")]
+		var snap jobView
+		if ok {
+			snap = job.view(true)
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+		writeJSON(w, http.StatusOK, snap)
+	})
+
+	mux.HandleFunc("POST /v1/jobs/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.cancel(r.PathValue("id"))
+		if job == nil {
+			writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusConflict, err)
+			return
+		}
+		s.mu.Lock()
+		snap := job.view(false)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, snap)
+	})
+
+	mux.HandleFunc("POST /v1/jobs/{id}/retry", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		old, ok := s.jobs[r.PathValue("id")]
+		var status, rawUrl string
+		if ok {
+			status, rawUrl = old.Status, old.URL
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+		if status == jobQueued || status == jobRunning {
+			writeJSONError(w, http.StatusConflict, fmt.Errorf("job is %s", status))
+			return
+		}
+		// 重新入队，仅处理失败曲目
+		job, err := s.enqueue(rawUrl, true)
+		if err != nil {
+			writeJSONError(w, http.StatusConflict, err)
+			return
+		}
+		s.mu.Lock()
+		snap := job.view(false)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusCreated, snap)
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	if authToken == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+authToken)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,283 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"main/pkg/downloader"
+	"main/utils/events"
+)
+
+const (
+	albumURL    = "https://music.apple.com/us/album/test/123"
+	playlistURL = "https://music.apple.com/us/playlist/test/pl.abc"
+)
+
+// ripFunc 测试用的下载器
+type ripFunc func(ctx context.Context, rawURL string, opts downloader.RipOptions) (*downloader.Result, error)
+
+func (f ripFunc) RipURL(ctx context.Context, rawURL string, opts downloader.RipOptions) (*downloader.Result, error) {
+	return f(ctx, rawURL, opts)
+}
+
+// blockingRip 一直运行到任务被取消
+func blockingRip(ctx context.Context, rawURL string, opts downloader.RipOptions) (*downloader.Result, error) {
+	opts.Events.Publish(events.Event{Type: events.TrackStarted, SongID: "1", Name: "Song"})
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newTestServer(t *testing.T, rip ripFunc, token string) http.Handler {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(ctx, rip, 1)
+	t.Cleanup(func() {
+		cancel()
+		s.Wait()
+	})
+	return s.Handler(token)
+}
+
+func do(t *testing.T, h http.Handler, method string, path string, body string, header map[string]string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: invalid json %q: %v", method, path, rec.Body.String(), err)
+	}
+	return rec.Code, out
+}
+
+// waitJob 轮询任务直到状态为 status
+func waitJob(t *testing.T, h http.Handler, id string, status string) map[string]interface{} {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, job := do(t, h, "GET", "/v1/jobs/"+id, "", nil)
+		if job["status"] == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s = %v, want status %s", id, job, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCreateJobs(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantJobs   int
+		wantErrors int
+	}{
+		{"single url", `{"url": "` + albumURL + `"}`, http.StatusCreated, 1, 0},
+		{"url list", `{"urls": ["` + albumURL + `", " ` + playlistURL + ` "]}`, http.StatusCreated, 2, 0},
+		{"partly invalid", `{"url": "` + albumURL + `", "urls": ["https://example.com/x"]}`, http.StatusCreated, 1, 1},
+		{"duplicate in request", `{"urls": ["` + albumURL + `", "` + albumURL + `"]}`, http.StatusCreated, 1, 1},
+		{"all invalid", `{"url": "not a url"}`, http.StatusConflict, 0, 1},
+		{"no url", `{}`, http.StatusBadRequest, 0, 0},
+		{"bad body", `{`, http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestServer(t, blockingRip, "")
+			code, out := do(t, h, "POST", "/v1/jobs", tt.body, nil)
+			if code != tt.wantCode {
+				t.Fatalf("code = %d, want %d (%v)", code, tt.wantCode, out)
+			}
+			if code == http.StatusBadRequest {
+				if out["error"] == nil {
+					t.Errorf("response has no error: %v", out)
+				}
+				return
+			}
+			jobs, _ := out["jobs"].([]interface{})
+			errs, _ := out["errors"].([]interface{})
+			if len(jobs) != tt.wantJobs || len(errs) != tt.wantErrors {
+				t.Errorf("got %d job(s) and %d error(s), want %d and %d: %v", len(jobs), len(errs), tt.wantJobs, tt.wantErrors, out)
+			}
+		})
+	}
+}
+
+func TestJobResult(t *testing.T) {
+	publish := func(list ...events.Event) ripFunc {
+		return func(ctx context.Context, rawURL string, opts downloader.RipOptions) (*downloader.Result, error) {
+			for _, e := range list {
+				opts.Events.Publish(e)
+			}
+			return &downloader.Result{}, nil
+		}
+	}
+	tests := []struct {
+		name          string
+		rip           ripFunc
+		wantStatus    string
+		wantCompleted float64
+		wantFailed    float64
+		wantError     string
+		wantTracks    string // 曲目状态，按出现顺序
+	}{
+		{
+			name: "done",
+			rip: publish(
+				events.Event{Type: events.TrackStarted, SongID: "1"},
+				events.Event{Type: events.DownloadProgress, SongID: "1", Bytes: 10, TotalBytes: 20},
+				events.Event{Type: events.TrackDone, SongID: "1", Path: "/music/1.m4a"},
+				events.Event{Type: events.TrackSkipped, SongID: "2", Reason: "exists"},
+			),
+			wantStatus: jobDone, wantCompleted: 2, wantTracks: "ok skipped",
+		},
+		{
+			name: "track failed",
+			rip: publish(
+				events.Event{Type: events.TrackStarted, SongID: "1"},
+				events.Event{Type: events.TrackFailed, SongID: "1", Reason: "decrypt failed"},
+				events.Event{Type: events.TrackDone, SongID: "2"},
+			),
+			wantStatus: jobFailed, wantCompleted: 1, wantFailed: 1, wantTracks: "failed ok",
+		},
+		{
+			// 没有结束事件的曲目按失败展示
+			name:       "track ended early",
+			rip:        publish(events.Event{Type: events.TrackStarted, SongID: "1"}),
+			wantStatus: jobFailed, wantFailed: 1, wantTracks: "failed",
+		},
+		{
+			name: "load failed",
+			rip: func(ctx context.Context, rawURL string, opts downloader.RipOptions) (*downloader.Result, error) {
+				return nil, errors.New("boom")
+			},
+			wantStatus: jobFailed, wantError: "failed to load album: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestServer(t, tt.rip, "")
+			if code, out := do(t, h, "POST", "/v1/jobs", `{"url": "`+albumURL+`"}`, nil); code != http.StatusCreated {
+				t.Fatalf("create = %d %v", code, out)
+			}
+			job := waitJob(t, h, "1", tt.wantStatus)
+			if job["completed"] != tt.wantCompleted || job["failed"] != tt.wantFailed {
+				t.Errorf("completed/failed = %v/%v, want %v/%v", job["completed"], job["failed"], tt.wantCompleted, tt.wantFailed)
+			}
+			if got, _ := job["error"].(string); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+			var statuses []string
+			tracks, _ := job["tracks"].([]interface{})
+			for _, tr := range tracks {
+				statuses = append(statuses, tr.(map[string]interface{})["status"].(string))
+			}
+			if got := strings.Join(statuses, " "); got != tt.wantTracks {
+				t.Errorf("tracks = %q, want %q", got, tt.wantTracks)
+			}
+			if job["kind"] != "album" || job["entityId"] != "123" || job["finishedAt"] == nil {
+				t.Errorf("job = %v", job)
+			}
+		})
+	}
+}
+
+func TestCancelAndRetry(t *testing.T) {
+	h := newTestServer(t, blockingRip, "")
+	post := func(path string, body string) (int, map[string]interface{}) {
+		return do(t, h, "POST", path, body, nil)
+	}
+	if code, _ := post("/v1/jobs", `{"url": "`+albumURL+`"}`); code != http.StatusCreated {
+		t.Fatalf("create album = %d", code)
+	}
+	waitJob(t, h, "1", jobRunning)
+	// 唯一的 worker 正忙，第二个任务保持排队
+	if code, _ := post("/v1/jobs", `{"url": "`+playlistURL+`"}`); code != http.StatusCreated {
+		t.Fatalf("create playlist = %d", code)
+	}
+
+	steps := []struct {
+		name       string
+		path       string
+		body       string
+		wantCode   int
+		wantStatus string
+	}{
+		{"duplicate running", "/v1/jobs", `{"url": "` + albumURL + `"}`, http.StatusConflict, ""},
+		{"retry running", "/v1/jobs/1/retry", "", http.StatusConflict, ""},
+		{"cancel queued", "/v1/jobs/2/cancel", "", http.StatusOK, jobCanceled},
+		{"cancel running", "/v1/jobs/1/cancel", "", http.StatusOK, jobRunning},
+		{"cancel unknown", "/v1/jobs/9/cancel", "", http.StatusNotFound, ""},
+		{"retry unknown", "/v1/jobs/9/retry", "", http.StatusNotFound, ""},
+	}
+	for _, st := range steps {
+		code, out := post(st.path, st.body)
+		if code != st.wantCode {
+			t.Fatalf("%s: code = %d, want %d (%v)", st.name, code, st.wantCode, out)
+		}
+		if st.wantStatus != "" && out["status"] != st.wantStatus {
+			t.Errorf("%s: status = %v, want %s", st.name, out["status"], st.wantStatus)
+		}
+	}
+
+	waitJob(t, h, "1", jobCanceled)
+	if code, _ := post("/v1/jobs/1/cancel", ""); code != http.StatusConflict {
+		t.Errorf("cancel canceled job = %d, want %d", code, http.StatusConflict)
+	}
+	code, out := post("/v1/jobs/2/retry", "")
+	if code != http.StatusCreated || out["id"] != "3" || out["retry"] != true || out["url"] != playlistURL {
+		t.Errorf("retry = %d %v", code, out)
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?status=canceled", 2},
+		{"?status=done", 0},
+	} {
+		_, out := do(t, h, "GET", "/v1/jobs"+tt.query, "", nil)
+		if jobs, _ := out["jobs"].([]interface{}); len(jobs) != tt.want {
+			t.Errorf("GET /v1/jobs%s returned %d job(s), want %d", tt.query, len(jobs), tt.want)
+		}
+	}
+	if code, _ := do(t, h, "GET", "/v1/jobs/9", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET unknown job = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	h := newTestServer(t, blockingRip, "secret")
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   int
+	}{
+		{"no header", nil, http.StatusUnauthorized},
+		{"wrong token", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"token prefix", map[string]string{"Authorization": "Bearer secre"}, http.StatusUnauthorized},
+		{"no scheme", map[string]string{"Authorization": "secret"}, http.StatusUnauthorized},
+		{"valid", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/healthz", "/v1/jobs"} {
+			if code, _ := do(t, h, "GET", path, "", tt.header); code != tt.want {
+				t.Errorf("%s: GET %s = %d, want %d", tt.name, path, code, tt.want)
+			}
+		}
+	}
+	if code, _ := do(t, newTestServer(t, blockingRip, ""), "GET", "/healthz", "", nil); code != http.StatusOK {
+		t.Errorf("GET /healthz without a token configured = %d", code)
+	}
+}
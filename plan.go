@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"main/pkg/downloader"
+)
+
+// 预演模式：--dry-run（text）输出可读的下载计划，--dry-run=json 每个链接输出一行 JSON
+var dryRunFormat string
+
+// JSON 计划的输出位置；json 模式下普通输出改到 stderr
+var planOut io.Writer
+
+// setupDryRun 校验 --dry-run 的取值，json 模式下将普通输出改到 stderr
+func setupDryRun() error {
+	switch dryRunFormat {
+	case "", "text":
+		return nil
+	case "json":
+	default:
+		return fmt.Errorf("invalid --dry-run %q, available: text, json", dryRunFormat)
+	}
+	if progressFormat == "ndjson" {
+		return fmt.Errorf("--dry-run=json cannot be combined with --progress-format ndjson")
+	}
+	planOut = os.Stdout
+	os.Stdout = os.Stderr
+	return nil
+}
+
+// printPlan 输出一次预演的结果
+func printPlan(res *downloader.Result) {
+	if res == nil {
+		return
+	}
+	if dryRunFormat == "json" {
+		_ = json.NewEncoder(planOut).Encode(res)
+		return
+	}
+	printPlanText(os.Stdout, res, "")
+}
+
+func printPlanText(w io.Writer, res *downloader.Result, indent string) {
+	title := res.Name
+	if title == "" {
+		title = res.ID
+	}
+	fmt.Fprintf(w, "%s== %s: %s (%s)\n", indent, res.Kind, title, res.ID)
+	if res.Error != "" {
+		fmt.Fprintf(w, "%s   error: %s\n", indent, res.Error)
+	}
+	if res.Dir != "" {
+		fmt.Fprintf(w, "%s   dir: %s\n", indent, res.Dir)
+	}
+	for _, c := range res.Covers {
+		fmt.Fprintf(w, "%s   cover: %s\n", indent, c)
+	}
+	for _, t := range res.Tracks {
+		status := t.Status
+		if t.Reason != "" {
+			status += " (" + t.Reason + ")"
+		}
+		var format []string
+		for _, s := range []string{t.Variant, t.Codec, t.Quality} {
+			if s != "" && !contains(format, s) {
+				format = append(format, s)
+			}
+		}
+		fmt.Fprintf(w, "%s   %02d  %s  [%s]  %s\n", indent, t.Index, status, strings.Join(format, " "), t.Name)
+		if t.Path != "" {
+			fmt.Fprintf(w, "%s       -> %s\n", indent, t.Path)
+		}
+		if t.Lyrics != "" {
+			fmt.Fprintf(w, "%s       lyrics: %s\n", indent, t.Lyrics)
+		}
+	}
+	for _, it := range res.Items {
+		printPlanText(w, it, indent+"   ")
+	}
+}
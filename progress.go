@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"main/utils/events"
+)
+
+// 进度输出格式：text（默认，控制台文本/进度条）或 ndjson（每行一个 JSON 事件）
+var progressFormat string
+
+// setupProgressOutput 在 ndjson 模式下将普通输出改到 stderr，stdout 只输出事件
+func setupProgressOutput() error {
+	switch progressFormat {
+	case "", "text":
+		return nil
+	case "ndjson":
+	default:
+		return fmt.Errorf("invalid --progress-format %q, available: text, ndjson", progressFormat)
+	}
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	var mu sync.Mutex
+	enc := json.NewEncoder(out)
+	events.Subscribe(func(e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(e)
+	})
+	return nil
+}
@@ -1,6 +1,7 @@
 package ampapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +10,7 @@ import (
 	"strings"
 )
 
-func GetAlbumResp(storefront string, id string, language string, token string) (*AlbumResp, error) {
+func GetAlbumResp(ctx context.Context, storefront string, id string, language string, token string) (*AlbumResp, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -18,7 +19,7 @@ func GetAlbumResp(storefront string, id string, language string, token string) (
 		}
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/albums/%s", storefront, id), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/albums/%s", storefront, id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +52,7 @@ func GetAlbumResp(storefront string, id string, language string, token string) (
 	if len(obj.Data[0].Relationships.Tracks.Next) > 0 {
 		next := obj.Data[0].Relationships.Tracks.Next
 		for {
-			req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com%s", next), nil)
+			req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com%s", next), nil)
 			if err != nil {
 				return nil, err
 			}
@@ -86,7 +87,7 @@ func GetAlbumResp(storefront string, id string, language string, token string) (
 	return obj, nil
 }
 
-func GetAlbumRespByHref(href string, language string, token string) (*AlbumResp, error) {
+func GetAlbumRespByHref(ctx context.Context, href string, language string, token string) (*AlbumResp, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -95,7 +96,7 @@ func GetAlbumRespByHref(href string, language string, token string) (*AlbumResp,
 		}
 	}
 	href = strings.Split(href, "?")[0]
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com%s/albums", href), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com%s/albums", href), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +129,7 @@ func GetAlbumRespByHref(href string, language string, token string) (*AlbumResp,
 	if len(obj.Data[0].Relationships.Tracks.Next) > 0 {
 		next := obj.Data[0].Relationships.Tracks.Next
 		for {
-			req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com%s", next), nil)
+			req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com%s", next), nil)
 			if err != nil {
 				return nil, err
 			}
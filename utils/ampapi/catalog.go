@@ -0,0 +1,135 @@
+package ampapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// getCatalogItems 请求目录接口的一页结果；path 可以是上一页返回的 next
+func getCatalogItems(ctx context.Context, path string, query url.Values, token string) (*CatalogItemsResp, error) {
+	var err error
+	if token == "" {
+		token, err = GetToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://amp-api.music.apple.com"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("Origin", "https://music.apple.com")
+	q := req.URL.Query()
+	for k, v := range query {
+		q[k] = v
+	}
+	req.URL.RawQuery = q.Encode()
+	do, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer do.Body.Close()
+	if do.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if do.StatusCode != http.StatusOK {
+		return nil, errors.New(do.Status)
+	}
+	obj := new(CatalogItemsResp)
+	err = json.NewDecoder(do.Body).Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// getCatalogItemsAll 按 next 分页取完全部条目
+func getCatalogItemsAll(ctx context.Context, path string, query url.Values, token string) ([]CatalogItemData, error) {
+	var items []CatalogItemData
+	for path != "" {
+		obj, err := getCatalogItems(ctx, path, query, token)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, obj.Data...)
+		path = obj.Next
+	}
+	return items, nil
+}
+
+// GetCatalogItem 获取目录中的单个 record-labels / curators / apple-curators 等条目
+func GetCatalogItem(ctx context.Context, storefront string, kind string, id string, language string, token string) (*CatalogItemData, error) {
+	query := url.Values{}
+	query.Set("l", language)
+	obj, err := getCatalogItems(ctx, fmt.Sprintf("/v1/catalog/%s/%s/%s", storefront, kind, id), query, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(obj.Data) == 0 {
+		return nil, ErrNotFound
+	}
+	return &obj.Data[0], nil
+}
+
+// GetRecordLabelReleases 获取唱片公司的全部专辑：latest-releases 与 top-releases 两个视图合并去重
+func GetRecordLabelReleases(ctx context.Context, storefront string, id string, language string, token string) ([]CatalogItemData, error) {
+	query := url.Values{}
+	query.Set("l", language)
+	seen := make(map[string]struct{})
+	var items []CatalogItemData
+	for _, view := range []string{"latest-releases", "top-releases"} {
+		list, err := getCatalogItemsAll(ctx, fmt.Sprintf("/v1/catalog/%s/record-labels/%s/view/%s", storefront, id, view), query, token)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range list {
+			if _, ok := seen[it.ID]; ok || it.Type != "albums" {
+				continue
+			}
+			seen[it.ID] = struct{}{}
+			items = append(items, it)
+		}
+	}
+	return items, nil
+}
+
+// GetCuratorPlaylists 获取 curators / apple-curators 的全部歌单
+func GetCuratorPlaylists(ctx context.Context, storefront string, kind string, id string, language string, token string) ([]CatalogItemData, error) {
+	query := url.Values{}
+	query.Set("limit", "100")
+	query.Set("l", language)
+	return getCatalogItemsAll(ctx, fmt.Sprintf("/v1/catalog/%s/%s/%s/playlists", storefront, kind, id), query, token)
+}
+
+type CatalogItemsResp struct {
+	Href string            `json:"href"`
+	Next string            `json:"next"`
+	Data []CatalogItemData `json:"data"`
+}
+
+// 目录中的通用条目：albums、songs、playlists、music-videos、record-labels、curators 等
+type CatalogItemData struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Href       string `json:"href"`
+	Attributes struct {
+		Name          string `json:"name"`
+		ArtistName    string `json:"artistName"`
+		CuratorName   string `json:"curatorName"`
+		AlbumName     string `json:"albumName"`
+		ReleaseDate   string `json:"releaseDate"`
+		URL           string `json:"url"`
+		TrackCount    int    `json:"trackCount"`
+		IsSingle      bool   `json:"isSingle"`
+		IsCompilation bool   `json:"isCompilation"`
+	} `json:"attributes"`
+}
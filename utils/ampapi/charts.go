@@ -0,0 +1,92 @@
+package ampapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// chartPageMax 每次请求最多返回的榜单条目数
+const chartPageMax = 200
+
+// GetCharts 获取 storefront 的 albums / songs / playlists / music-videos 榜单前 limit 项，
+// genre 为空时为全部流派
+func GetCharts(ctx context.Context, storefront string, kind string, genre string, limit int, language string, token string) (*Chart, error) {
+	var err error
+	if token == "" {
+		token, err = GetToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	query := url.Values{}
+	query.Set("types", kind)
+	if genre != "" {
+		query.Set("genre", genre)
+	}
+	query.Set("limit", strconv.Itoa(min(limit, chartPageMax)))
+	query.Set("l", language)
+	next := fmt.Sprintf("/v1/catalog/%s/charts?%s", storefront, query.Encode())
+	var chart *Chart
+	for next != "" && (chart == nil || len(chart.Data) < limit) {
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://amp-api.music.apple.com"+next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+		req.Header.Set("Origin", "https://music.apple.com")
+		do, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if do.StatusCode != http.StatusOK {
+			do.Body.Close()
+			return nil, errors.New(do.Status)
+		}
+		obj := new(ChartsResp)
+		err = json.NewDecoder(do.Body).Decode(&obj)
+		do.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		charts := obj.Results[kind]
+		if len(charts) == 0 {
+			break
+		}
+		if chart == nil {
+			chart = &charts[0]
+		} else {
+			chart.Data = append(chart.Data, charts[0].Data...)
+		}
+		next = charts[0].Next
+	}
+	if chart == nil {
+		return nil, fmt.Errorf("no %s chart for storefront %s", kind, storefront)
+	}
+	if len(chart.Data) > limit {
+		chart.Data = chart.Data[:limit]
+	}
+	return chart, nil
+}
+
+type ChartsResp struct {
+	Results map[string][]Chart `json:"results"`
+}
+
+// Chart 一个榜单，Data 按名次排列
+type Chart struct {
+	Chart   string            `json:"chart"`
+	Name    string            `json:"name"`
+	OrderID string            `json:"orderId"`
+	Href    string            `json:"href"`
+	Next    string            `json:"next"`
+	Data    []CatalogItemData `json:"data"`
+}
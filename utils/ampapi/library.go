@@ -0,0 +1,219 @@
+package ampapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound 资料库中不存在该条目（空歌单的 tracks 同样返回 404）
+var ErrNotFound = errors.New("404 Not Found")
+
+// getLibrary 请求 /v1/me/library 下的接口，需要 media-user-token
+func getLibrary(ctx context.Context, path string, query url.Values, mutoken string, token string) (*LibraryResp, error) {
+	var err error
+	if token == "" {
+		token, err = GetToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://amp-api.music.apple.com"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("Origin", "https://music.apple.com")
+	req.Header.Set("Media-User-Token", mutoken)
+	if query != nil {
+		q := req.URL.Query()
+		for k, v := range query {
+			q[k] = v
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	do, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer do.Body.Close()
+	if do.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if do.StatusCode != http.StatusOK {
+		return nil, errors.New(do.Status)
+	}
+	obj := new(LibraryResp)
+	err = json.NewDecoder(do.Body).Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// getLibraryAll 按 next 分页取完全部条目
+func getLibraryAll(ctx context.Context, path string, query url.Values, mutoken string, token string) ([]LibraryRespData, error) {
+	var items []LibraryRespData
+	for path != "" {
+		obj, err := getLibrary(ctx, path, query, mutoken, token)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, obj.Data...)
+		path = obj.Next
+	}
+	return items, nil
+}
+
+// GetLibraryItems 获取资料库中的全部 playlists / albums / songs
+func GetLibraryItems(ctx context.Context, kind string, mutoken string, language string, token string) ([]LibraryRespData, error) {
+	switch kind {
+	case "playlists", "albums", "songs":
+	default:
+		return nil, fmt.Errorf("unknown library type %q", kind)
+	}
+	query := url.Values{}
+	query.Set("limit", "100")
+	query.Set("include", "catalog")
+	query.Set("l", language)
+	return getLibraryAll(ctx, "/v1/me/library/"+kind, query, mutoken, token)
+}
+
+// GetLibraryItem 获取资料库中的单个 playlist / album / song
+func GetLibraryItem(ctx context.Context, kind string, id string, mutoken string, language string, token string) (*LibraryRespData, error) {
+	query := url.Values{}
+	query.Set("include", "catalog")
+	query.Set("l", language)
+	obj, err := getLibrary(ctx, fmt.Sprintf("/v1/me/library/%s/%s", kind, id), query, mutoken, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(obj.Data) == 0 {
+		return nil, ErrNotFound
+	}
+	return &obj.Data[0], nil
+}
+
+// GetLibraryPlaylistTracks 按顺序获取资料库歌单中的曲目（library-songs / library-music-videos）
+func GetLibraryPlaylistTracks(ctx context.Context, id string, mutoken string, language string, token string) ([]LibraryRespData, error) {
+	query := url.Values{}
+	query.Set("limit", "100")
+	query.Set("include", "catalog")
+	query.Set("l", language)
+	items, err := getLibraryAll(ctx, fmt.Sprintf("/v1/me/library/playlists/%s/tracks", id), query, mutoken, token)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return items, err
+}
+
+// GetCatalogTracks 按 ID 批量获取目录中的 songs / music-videos，返回顺序不保证与 ids 一致
+func GetCatalogTracks(ctx context.Context, storefront string, kind string, ids []string, language string, token string) ([]TrackRespData, error) {
+	var err error
+	if token == "" {
+		token, err = GetToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var out []TrackRespData
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/%s", storefront, kind), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+		req.Header.Set("Origin", "https://music.apple.com")
+		query := url.Values{}
+		query.Set("ids", strings.Join(ids[start:end], ","))
+		query.Set("omit[resource]", "autos")
+		query.Set("include", "artists,albums")
+		query.Set("extend", "extendedAssetUrls")
+		query.Set("l", language)
+		req.URL.RawQuery = query.Encode()
+		do, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if do.StatusCode != http.StatusOK {
+			do.Body.Close()
+			return nil, errors.New(do.Status)
+		}
+		obj := new(TrackResp)
+		err = json.NewDecoder(do.Body).Decode(&obj)
+		do.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, obj.Data...)
+	}
+	return out, nil
+}
+
+type LibraryResp struct {
+	Href string            `json:"href"`
+	Next string            `json:"next"`
+	Data []LibraryRespData `json:"data"`
+}
+
+// 类型为 library-playlists、library-albums、library-songs 或 library-music-videos
+type LibraryRespData struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Href       string `json:"href"`
+	Attributes struct {
+		Artwork struct {
+			Width  int    `json:"width"`
+			Height int    `json:"height"`
+			URL    string `json:"url"`
+		} `json:"artwork"`
+		Name        string `json:"name"`
+		ArtistName  string `json:"artistName"`
+		AlbumName   string `json:"albumName"`
+		TrackCount  int    `json:"trackCount"`
+		ReleaseDate string `json:"releaseDate"`
+		DateAdded   string `json:"dateAdded"`
+		CanEdit     bool   `json:"canEdit"`
+		IsPublic    bool   `json:"isPublic"`
+		HasCatalog  bool   `json:"hasCatalog"`
+		Description struct {
+			Standard string `json:"standard"`
+		} `json:"description"`
+		PlayParams struct {
+			ID        string `json:"id"`
+			Kind      string `json:"kind"`
+			IsLibrary bool   `json:"isLibrary"`
+			CatalogID string `json:"catalogId"`
+		} `json:"playParams"`
+	} `json:"attributes"`
+	Relationships struct {
+		Catalog struct {
+			Href string `json:"href"`
+			Data []struct {
+				ID         string `json:"id"`
+				Type       string `json:"type"`
+				Attributes struct {
+					URL string `json:"url"`
+				} `json:"attributes"`
+			} `json:"data"`
+		} `json:"catalog"`
+	} `json:"relationships"`
+}
+
+// CatalogID 资料库条目对应的目录 ID；上传的音乐或自建歌单没有目录 ID，返回空
+func (d *LibraryRespData) CatalogID() string {
+	if len(d.Relationships.Catalog.Data) > 0 {
+		return d.Relationships.Catalog.Data[0].ID
+	}
+	return d.Attributes.PlayParams.CatalogID
+}
@@ -1,6 +1,7 @@
 package ampapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,7 +9,7 @@ import (
 	"net/url"
 )
 
-func GetPlaylistResp(storefront string, id string, language string, token string) (*PlaylistResp, error) {
+func GetPlaylistResp(ctx context.Context, storefront string, id string, language string, token string) (*PlaylistResp, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -17,7 +18,7 @@ func GetPlaylistResp(storefront string, id string, language string, token string
 		}
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/playlists/%s", storefront, id), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/playlists/%s", storefront, id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +51,7 @@ func GetPlaylistResp(storefront string, id string, language string, token string
 	if len(obj.Data[0].Relationships.Tracks.Next) > 0 {
 		next := obj.Data[0].Relationships.Tracks.Next
 		for {
-			req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com%s", next), nil)
+			req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com%s", next), nil)
 			if err != nil {
 				return nil, err
 			}
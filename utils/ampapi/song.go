@@ -1,6 +1,7 @@
 package ampapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,7 +9,7 @@ import (
 	"net/url"
 )
 
-func GetSongResp(storefront string, id string, language string, token string) (*SongResp, error) {
+func GetSongResp(ctx context.Context, storefront string, id string, language string, token string) (*SongResp, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -17,7 +18,7 @@ func GetSongResp(storefront string, id string, language string, token string) (*
 		}
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/songs/%s", storefront, id), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/songs/%s", storefront, id), nil)
 	if err != nil {
 		return nil, err
 	}
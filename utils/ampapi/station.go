@@ -1,6 +1,7 @@
 package ampapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,7 +9,7 @@ import (
 	"net/url"
 )
 
-func GetStationResp(storefront string, id string, language string, token string) (*StationResp, error) {
+func GetStationResp(ctx context.Context, storefront string, id string, language string, token string) (*StationResp, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -17,7 +18,7 @@ func GetStationResp(storefront string, id string, language string, token string)
 		}
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/stations/%s", storefront, id), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/stations/%s", storefront, id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +46,7 @@ func GetStationResp(storefront string, id string, language string, token string)
 	return obj, nil
 }
 
-func GetStationAssetsUrlAndServerUrl(id string, mutoken string, token string) (string, string, error) {
+func GetStationAssetsUrlAndServerUrl(ctx context.Context, id string, mutoken string, token string) (string, string, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -54,7 +55,7 @@ func GetStationAssetsUrlAndServerUrl(id string, mutoken string, token string) (s
 		}
 	}
 
-	req, err := http.NewRequest("GET", "https://amp-api.music.apple.com/v1/play/assets", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://amp-api.music.apple.com/v1/play/assets", nil)
 	if err != nil {
 		return "", "", err
 	}
@@ -85,7 +86,7 @@ func GetStationAssetsUrlAndServerUrl(id string, mutoken string, token string) (s
 	return obj.Results.Assets[0].Url, obj.Results.Assets[0].KeyServerUrl, nil
 }
 
-func GetStationNextTracks(id, mutoken, language, token string) (*TrackResp, error) {
+func GetStationNextTracks(ctx context.Context, id, mutoken, language, token string) (*TrackResp, error) {
 	var err error
 	if token == "" {
 		token, err = GetToken()
@@ -94,7 +95,7 @@ func GetStationNextTracks(id, mutoken, language, token string) (*TrackResp, erro
 		}
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://amp-api.music.apple.com/v1/me/stations/next-tracks/%s", id), nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://amp-api.music.apple.com/v1/me/stations/next-tracks/%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,133 @@
+package atomicfile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 临时文件标记，位于扩展名之前，外部工具（ffmpeg、MP4Box）仍可按扩展名识别格式；
+// 使用本工具专有的标记，Sweep 不会误删用户自己的 *.part.* 文件
+const partMark = ".amd-part"
+
+// PartPath 返回 path 对应的同目录临时文件路径，如 "01. Song.m4a" -> "01. Song.amd-part.m4a"
+func PartPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + partMark + ext
+}
+
+// IsPart 判断文件名是否为 PartPath 生成的临时文件
+func IsPart(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == partMark || strings.HasSuffix(strings.TrimSuffix(name, ext), partMark)
+}
+
+// File 写入临时文件，Commit 后才替换目标文件，失败或中断时不会留下不完整的目标文件
+type File struct {
+	*os.File
+	path string
+	done bool
+}
+
+func Create(path string) (*File, error) {
+	f, err := os.Create(PartPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f, path: path}, nil
+}
+
+// Commit 刷盘、关闭并重命名为目标文件
+func (f *File) Commit() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return rename(f.Name(), f.path)
+}
+
+// Abort 关闭并删除临时文件，Commit 之后调用无效果
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFile 与 os.WriteFile 相同，但先写入临时文件
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(PartPath(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	af := &File{File: f, path: path}
+	if _, err := af.Write(data); err != nil {
+		af.Abort()
+		return err
+	}
+	return af.Commit()
+}
+
+// Rename 将外部工具写好的临时文件（通常为 PartPath(path)）刷盘后重命名为 path
+func Rename(part string, path string) error {
+	f, err := os.OpenFile(part, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(part)
+		return err
+	}
+	return rename(part, path)
+}
+
+func rename(part string, path string) error {
+	if err := os.Rename(part, path); err != nil {
+		os.Remove(part)
+		return err
+	}
+	// 目录项刷盘，部分平台（Windows）不支持，忽略错误
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Sweep 删除 root 下遗留的临时文件（上次运行中断时留下），返回删除数量
+func Sweep(root string) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// 无法访问的子目录跳过
+			return nil
+		}
+		if !d.Type().IsRegular() || !IsPart(d.Name()) {
+			return nil
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
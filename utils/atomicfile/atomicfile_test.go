@@ -0,0 +1,135 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestPartPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"01. Song.m4a", "01. Song.amd-part.m4a"},
+		{"/music/Album/cover.jpg", "/music/Album/cover.amd-part.jpg"},
+		{"noext", "noext.amd-part"},
+		{"Vol. 1/Song.lrc", "Vol. 1/Song.amd-part.lrc"},
+	}
+	for _, tt := range tests {
+		if got := PartPath(tt.path); got != tt.want {
+			t.Errorf("PartPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsPart(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"01. Song.amd-part.m4a", true},
+		{"noext.amd-part", true},
+		{"cover.amd-part.jpg", true},
+		{"01. Song.m4a", false},
+		// 用户自己的 .part 文件与其他工具的临时文件不算
+		{"foo.part.m4a", false},
+		{"foo.m4a.part", false},
+		{"amd-part.m4a", false},
+		{"foo.amd-part.bak.m4a", false},
+	}
+	for _, tt := range tests {
+		if got := IsPart(tt.name); got != tt.want {
+			t.Errorf("IsPart(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+		if !tt.want && !IsPart(PartPath(tt.name)) {
+			t.Errorf("IsPart(PartPath(%q)) = false", tt.name)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	root := t.TempDir()
+	files := []struct {
+		path string
+		keep bool
+	}{
+		{"a/01. Song.m4a", true},
+		{"a/02. Song.amd-part.m4a", false},
+		{"a/b/cover.amd-part.jpg", false},
+		{"a/b/foo.part.m4a", true},
+		{"c/x.amd-part", false},
+		{"c/notes.txt", true},
+	}
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 名称像临时文件的目录不删除
+	if err := os.MkdirAll(filepath.Join(root, "d.amd-part"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Sweep(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Errorf("Sweep removed %d file(s), want 3", removed)
+	}
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(root, filepath.FromSlash(f.path)))
+		if exists := err == nil; exists != f.keep {
+			t.Errorf("%s exists = %v, want %v", f.path, exists, f.keep)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "d.amd-part")); err != nil {
+		t.Errorf("directory removed: %v", err)
+	}
+
+	if _, err := Sweep(filepath.Join(root, "missing")); err == nil {
+		t.Error("Sweep on a missing root succeeded, want error")
+	}
+}
+
+func TestWriteFileAndCommit(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.m4a")
+	if err := WriteFile(target, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "data" {
+		t.Errorf("target = %q, %v", data, err)
+	}
+
+	f, err := Create(filepath.Join(dir, "aborted.m4a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("partial"); err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+	if err := f.Commit(); err != nil {
+		t.Errorf("Commit after Abort: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	if len(names) != 1 || names[0] != "out.m4a" {
+		t.Errorf("directory contains %v, want only out.m4a", names)
+	}
+}
@@ -0,0 +1,371 @@
+// Package configcheck 检查配置文件中的未知键、类型错误与非法取值
+package configcheck
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"main/utils/naming"
+	"main/utils/sanitize"
+	"main/utils/structs"
+
+	"gopkg.in/yaml.v2"
+)
+
+// File 配置文件的完整结构：ConfigSet 加上只在 main 中读取的键
+type File struct {
+	structs.ConfigSet   `yaml:",inline"`
+	OutputFolder        string `yaml:"output-folder"`
+	DownloadConcurrency int    `yaml:"download-concurrency"`
+}
+
+// Issue 配置检查发现的问题，Warning 为 false 时视为错误
+type Issue struct {
+	Source  string // 配置文件、environment 或 --set
+	Line    int
+	Key     string
+	Msg     string
+	Warning bool
+}
+
+func (i Issue) String() string {
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s: %s", i.Source, i.Line, level, i.Key, i.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", i.Source, level, i.Key, i.Msg)
+}
+
+// 文档中列出的编码名称（见 config-example.yaml）
+var knownCodecs = []string{"ec-3", "ac-3", "aac", "aac-lc", "aac-binaural", "aac-downmix", "alac", "alac-192", "alac-96", "alac-48", "alac-44"}
+
+// Enums 取值固定的字符串键，向导中以选择列表展示
+var Enums = map[string][]string{
+	"lrc-type":               {"lyrics", "syllable-lyrics"},
+	"lrc-format":             {"lrc", "ttml"},
+	"cover-format":           {"jpg", "png", "original"},
+	"get-m3u8-mode":          {"all", "hires"},
+	"aac-type":               {"aac-lc", "aac", "aac-binaural", "aac-downmix"},
+	"mv-audio-type":          {"atmos", "ac3", "aac"},
+	"convert-format":         {"flac", "mp3", "opus", "wav", "copy"},
+	"filename-profile":       sanitize.Names(),
+	"filename-normalization": {"nfc", "nfd", "none"},
+	"playlist-layout":        {"folder", "library"},
+	"playlist-links":         {"none", "symlink", "hardlink"},
+}
+
+var (
+	coverSizeRe  = regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*$`)
+	storefrontRe = regexp.MustCompile(`^[A-Za-z]{2}$`)
+)
+
+// OneOf v 不在 allowed 中时返回错误说明
+func OneOf(v string, allowed ...string) string {
+	for _, a := range allowed {
+		if v == a {
+			return ""
+		}
+	}
+	return fmt.Sprintf("invalid value %q (allowed: %s)", v, strings.Join(allowed, ", "))
+}
+
+func atLeast(v int, min int) string {
+	if v < min {
+		return fmt.Sprintf("value %d is out of range (must be >= %d)", v, min)
+	}
+	return ""
+}
+
+// nameFormat 检查目录/文件名模板
+func nameFormat(v string) string {
+	if err := naming.Check(v); err != nil {
+		return fmt.Sprintf("invalid template: %v", err)
+	}
+	return ""
+}
+
+func hostPort(v string) string {
+	if _, _, err := net.SplitHostPort(v); err != nil {
+		return fmt.Sprintf("invalid address %q (expected host:port)", v)
+	}
+	return ""
+}
+
+// rules 各键的取值检查，返回空字符串表示通过；只检查文件中出现的键
+var rules = map[string]func(c *File) string{
+	"storefront": func(c *File) string {
+		if s := c.Storefront; s != "" && !storefrontRe.MatchString(s) {
+			return fmt.Sprintf("invalid storefront %q (expected a 2-letter country code such as us, jp)", s)
+		}
+		return ""
+	},
+	"cover-size": func(c *File) string {
+		if !coverSizeRe.MatchString(c.CoverSize) {
+			return fmt.Sprintf("invalid cover size %q (expected WIDTHxHEIGHT, e.g. 5000x5000)", c.CoverSize)
+		}
+		return ""
+	},
+	"album-folder-format":    func(c *File) string { return nameFormat(c.AlbumFolderFormat) },
+	"playlist-folder-format": func(c *File) string { return nameFormat(c.PlaylistFolderFormat) },
+	"artist-folder-format":   func(c *File) string { return nameFormat(c.ArtistFolderFormat) },
+	"song-file-format":       func(c *File) string { return nameFormat(c.SongFileFormat) },
+	"mv-file-format":         func(c *File) string { return nameFormat(c.MVFileFormat) },
+	"disc-folder-format":     func(c *File) string { return nameFormat(c.DiscFolderFormat) },
+	"decrypt-m3u8-port":      func(c *File) string { return hostPort(c.DecryptM3u8Port) },
+	"get-m3u8-port":          func(c *File) string { return hostPort(c.GetM3u8Port) },
+	"codec-priority": func(c *File) string {
+		if len(c.CodecPriority) == 0 {
+			return "list is empty"
+		}
+		var bad []string
+		for _, codec := range c.CodecPriority {
+			if OneOf(codec, knownCodecs...) != "" {
+				bad = append(bad, fmt.Sprintf("%q", codec))
+			}
+		}
+		if len(bad) > 0 {
+			return fmt.Sprintf("unknown codec %s (allowed: %s)", strings.Join(bad, ", "), strings.Join(knownCodecs, ", "))
+		}
+		return ""
+	},
+	"playlist-files": func(c *File) string {
+		for _, f := range c.PlaylistFiles {
+			if msg := OneOf(f, "m3u8", "xspf"); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	},
+	"alac-max":               func(c *File) string { return atLeast(c.AlacMax, 1) },
+	"atmos-max":              func(c *File) string { return atLeast(c.AtmosMax, 1) },
+	"mv-max":                 func(c *File) string { return atLeast(c.MVMax, 1) },
+	"limit-max":              func(c *File) string { return atLeast(c.LimitMax, 1) },
+	"max-path-length":        func(c *File) string { return atLeast(c.MaxPathLength, 0) },
+	"max-memory-limit":       func(c *File) string { return atLeast(c.MaxMemoryLimit, 1) },
+	"download-concurrency":   func(c *File) string { return atLeast(c.DownloadConcurrency, 0) },
+	"mv-segment-concurrency": func(c *File) string { return atLeast(c.MVSegmentConcurrency, 0) },
+	"tagging-concurrency":    func(c *File) string { return atLeast(c.TaggingConcurrency, 0) },
+	"request-timeout-sec":    func(c *File) string { return atLeast(c.RequestTimeoutSec, 0) },
+	"download-timeout-sec":   func(c *File) string { return atLeast(c.DownloadTimeoutSec, 0) },
+}
+
+// walkFields 按 yaml 键遍历 v（File 或 ConfigSet）的字段，展开 inline 字段
+func walkFields(v reflect.Value, fn func(key string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if f.Anonymous && len(tag) > 1 && tag[1] == "inline" {
+			walkFields(v.Field(i), fn)
+			continue
+		}
+		if tag[0] != "" {
+			fn(tag[0], v.Field(i))
+		}
+	}
+}
+
+// Fields 返回 File 中所有键及其字段类型
+func Fields() map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	walkFields(reflect.ValueOf(File{}), func(key string, field reflect.Value) {
+		fields[key] = field.Type()
+	})
+	return fields
+}
+
+// Value 返回 c 中 key 对应的字段，键不存在时返回零值 reflect.Value
+func Value(c *File, key string) reflect.Value {
+	var out reflect.Value
+	walkFields(reflect.ValueOf(c).Elem(), func(k string, field reflect.Value) {
+		if k == key {
+			out = field
+		}
+	})
+	return out
+}
+
+// Keys 返回配置文件中所有合法的键
+func Keys() map[string]bool {
+	keys := map[string]bool{"profiles": true}
+	for key := range Fields() {
+		keys[key] = true
+	}
+	return keys
+}
+
+// Validate 检查未知键、类型错误与非法取值，profiles 中的每个配置档单独检查；source 为配置来源的名称
+func Validate(data []byte, source string) ([]Issue, error) {
+	var root yaml.MapSlice
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	lines := keyLines(data)
+	keys := Keys()
+	issues := CheckSection(root, "", lines, keys)
+	for _, item := range root {
+		if fmt.Sprint(item.Key) != "profiles" || item.Value == nil {
+			continue
+		}
+		profiles, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			issues = append(issues, Issue{Line: lines["profiles"], Key: "profiles", Msg: "must be a mapping of profile name to settings"})
+			continue
+		}
+		for _, p := range profiles {
+			name := fmt.Sprint(p.Key)
+			prefix := "profiles." + name
+			if p.Value == nil {
+				continue
+			}
+			section, ok := p.Value.(yaml.MapSlice)
+			if !ok {
+				issues = append(issues, Issue{Line: lines[prefix], Key: prefix, Msg: "profile must be a mapping of settings"})
+				continue
+			}
+			issues = append(issues, CheckSection(section, prefix+".", lines, keys)...)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	for i := range issues {
+		issues[i].Source = source
+	}
+	return issues, nil
+}
+
+// CheckSection 检查一组键值，键路径为 prefix 加键名，lines 为各键路径所在的行号，keys 为合法的键
+func CheckSection(section yaml.MapSlice, prefix string, lines map[string]int, keys map[string]bool) []Issue {
+	var issues []Issue
+	var c File
+	for _, item := range section {
+		key := fmt.Sprint(item.Key)
+		path := prefix + key
+		if key == "profiles" {
+			if prefix != "" {
+				issues = append(issues, Issue{Line: lines[path], Key: path, Msg: "profiles cannot be nested"})
+			}
+			continue
+		}
+		if !keys[key] {
+			msg := "unknown key, ignored"
+			if s := suggestKey(key, keys); s != "" {
+				msg = fmt.Sprintf("unknown key, ignored (did you mean %q?)", s)
+			}
+			issues = append(issues, Issue{Line: lines[path], Key: path, Msg: msg, Warning: true})
+			continue
+		}
+		// 逐键解码，类型错误可以定位到具体的键
+		raw, err := yaml.Marshal(yaml.MapSlice{item})
+		if err == nil {
+			err = yaml.UnmarshalStrict(raw, &c)
+		}
+		if err != nil {
+			issues = append(issues, Issue{Line: lines[path], Key: path, Msg: yamlErrorText(err)})
+			continue
+		}
+		if rule, ok := rules[key]; ok {
+			if msg := rule(&c); msg != "" {
+				issues = append(issues, Issue{Line: lines[path], Key: path, Msg: msg})
+			}
+		}
+		if allowed, ok := Enums[key]; ok {
+			if msg := OneOf(Value(&c, key).String(), allowed...); msg != "" {
+				issues = append(issues, Issue{Line: lines[path], Key: path, Msg: msg})
+			}
+		}
+	}
+	return issues
+}
+
+// yamlErrorText 去掉 yaml 错误中针对临时文档的 "line N:" 前缀
+func yamlErrorText(err error) string {
+	if te, ok := err.(*yaml.TypeError); ok && len(te.Errors) > 0 {
+		msg := te.Errors[0]
+		if i := strings.Index(msg, ": "); strings.HasPrefix(msg, "line ") && i > 0 {
+			msg = msg[i+2:]
+		}
+		return msg
+	}
+	return err.Error()
+}
+
+var keyLineRe = regexp.MustCompile(`^(\s*)([A-Za-z0-9_.-]+|"[^"]*"|'[^']*')\s*:(\s|$)`)
+
+// keyLines 按缩进扫描映射键所在的行号，键路径以 "." 连接，如 profiles.jp.storefront
+func keyLines(data []byte) map[string]int {
+	type level struct {
+		indent int
+		key    string
+	}
+	lines := make(map[string]int)
+	var stack []level
+	for n, line := range strings.Split(string(data), "\n") {
+		m := keyLineRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		indent := len(m[1])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, level{indent, strings.Trim(m[2], `"'`)})
+		parts := make([]string, len(stack))
+		for i, l := range stack {
+			parts[i] = l.key
+		}
+		path := strings.Join(parts, ".")
+		if _, ok := lines[path]; !ok {
+			lines[path] = n + 1
+		}
+	}
+	return lines
+}
+
+// suggestKey 返回与 key 编辑距离不超过 2 的最接近的合法键
+func suggestKey(key string, keys map[string]bool) string {
+	best, bestDist := "", 3
+	for k := range keys {
+		if d := editDistance(key, k); d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Errors 统计错误（不含警告）数量
+func Errors(issues []Issue) int {
+	n := 0
+	for _, i := range issues {
+		if !i.Warning {
+			n++
+		}
+	}
+	return n
+}
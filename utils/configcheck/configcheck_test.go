@@ -0,0 +1,134 @@
+package configcheck
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestCheckSection(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		wantKey     string // 为空时应没有问题
+		wantMsg     string // 子串
+		wantWarning bool
+	}{
+		{name: "valid", yaml: "storefront: jp\ncover-size: 5000x5000\nalac-max: 192000\nlrc-format: ttml\nplaylist-files: [m3u8, xspf]"},
+		{name: "main-only keys", yaml: "output-folder: /music\ndownload-concurrency: 4"},
+		{name: "profiles skipped", yaml: "profiles:\n  jp:\n    storefront: jp"},
+		{name: "unknown key", yaml: "foo-bar: 1", wantKey: "foo-bar", wantMsg: "unknown key, ignored", wantWarning: true},
+		{name: "suggestion", yaml: "storefrnt: us", wantKey: "storefrnt", wantMsg: `did you mean "storefront"?`, wantWarning: true},
+		{name: "type error", yaml: "alac-max: high", wantKey: "alac-max", wantMsg: "cannot unmarshal"},
+		{name: "bad storefront", yaml: "storefront: usa", wantKey: "storefront", wantMsg: "2-letter country code"},
+		{name: "bad cover size", yaml: "cover-size: 5000", wantKey: "cover-size", wantMsg: "WIDTHxHEIGHT"},
+		{name: "out of range", yaml: "limit-max: 0", wantKey: "limit-max", wantMsg: "must be >= 1"},
+		{name: "bad template", yaml: "song-file-format: \"{{.SongName\"", wantKey: "song-file-format", wantMsg: "invalid template"},
+		{name: "bad address", yaml: "get-m3u8-port: 30020", wantKey: "get-m3u8-port", wantMsg: "expected host:port"},
+		{name: "empty codec list", yaml: "codec-priority: []", wantKey: "codec-priority", wantMsg: "list is empty"},
+		{name: "unknown codec", yaml: "codec-priority: [alac, flac]", wantKey: "codec-priority", wantMsg: `unknown codec "flac"`},
+		{name: "bad playlist file", yaml: "playlist-files: [m3u]", wantKey: "playlist-files", wantMsg: `invalid value "m3u"`},
+		{name: "bad enum", yaml: "cover-format: gif", wantKey: "cover-format", wantMsg: "allowed: jpg, png, original"},
+		{name: "filename profile", yaml: "filename-profile: ntfs", wantKey: "filename-profile", wantMsg: "allowed: posix, windows"},
+	}
+	keys := Keys()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var section yaml.MapSlice
+			if err := yaml.Unmarshal([]byte(tt.yaml), &section); err != nil {
+				t.Fatal(err)
+			}
+			issues := CheckSection(section, "", nil, keys)
+			if tt.wantKey == "" {
+				if len(issues) != 0 {
+					t.Errorf("CheckSection = %v, want no issues", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("CheckSection = %v, want 1 issue", issues)
+			}
+			i := issues[0]
+			if i.Key != tt.wantKey || !strings.Contains(i.Msg, tt.wantMsg) || i.Warning != tt.wantWarning {
+				t.Errorf("issue = %+v, want key %q, msg containing %q, warning %v", i, tt.wantKey, tt.wantMsg, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckSectionNested(t *testing.T) {
+	var section yaml.MapSlice
+	if err := yaml.Unmarshal([]byte("profiles: {}\nstorefront: x"), &section); err != nil {
+		t.Fatal(err)
+	}
+	lines := map[string]int{"profiles.jp.profiles": 3, "profiles.jp.storefront": 4}
+	issues := CheckSection(section, "profiles.jp.", lines, Keys())
+	want := []string{
+		"test:3: error: profiles.jp.profiles: profiles cannot be nested",
+		`test:4: error: profiles.jp.storefront: invalid storefront "x" (expected a 2-letter country code such as us, jp)`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("CheckSection = %v, want %d issues", issues, len(want))
+	}
+	for i := range issues {
+		issues[i].Source = "test"
+		if got := issues[i].String(); got != want[i] {
+			t.Errorf("issue %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := []byte(`storefront: us
+cover-szie: 5000x5000
+alac-max: high
+profiles:
+  jp:
+    storefront: japan
+  broken: 1
+`)
+	issues, err := Validate(data, "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line    int
+		key     string
+		warning bool
+	}{
+		{2, "cover-szie", true},
+		{3, "alac-max", false},
+		{6, "profiles.jp.storefront", false},
+		{7, "profiles.broken", false},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Validate = %v, want %d issues", issues, len(want))
+	}
+	for i, w := range want {
+		got := issues[i]
+		if got.Source != "config.yaml" || got.Line != w.line || got.Key != w.key || got.Warning != w.warning {
+			t.Errorf("issue %d = %+v, want line %d key %q warning %v", i, got, w.line, w.key, w.warning)
+		}
+	}
+	if n := Errors(issues); n != 3 {
+		t.Errorf("Errors = %d, want 3", n)
+	}
+	if _, err := Validate([]byte("storefront: [us"), "config.yaml"); err == nil {
+		t.Error("Validate accepted invalid YAML")
+	}
+}
+
+func TestKeyLines(t *testing.T) {
+	data := []byte("a: 1\n# comment\nprofiles:\n  jp:\n    storefront: jp\n  \"us\":\n    storefront: us\nb: 2\r\n")
+	want := map[string]int{"a": 1, "profiles": 3, "profiles.jp": 4, "profiles.jp.storefront": 5, "profiles.us": 6, "profiles.us.storefront": 7, "b": 8}
+	got := keyLines(data)
+	if len(got) != len(want) {
+		t.Errorf("keyLines = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("keyLines[%q] = %d, want %d", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	JobStarted       = "job_started"
+	JobFinished      = "job_finished"
+	TrackQueued      = "track_queued"
+	TrackStarted     = "track_started"
+	DownloadProgress = "download_progress"
+	DecryptProgress  = "decrypt_progress"
+	TagDone          = "tag_done"
+	Converted        = "converted"
+	TrackDone        = "track_done"
+	TrackSkipped     = "track_skipped" // 已存在、已在曲库中或 DryRun 等未下载即结束
+	TrackFailed      = "track_failed"
+)
+
+// Event 下载流程中的一个状态变化，字段按事件类型选填
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	URL        string    `json:"url,omitempty"`
+	Kind       string    `json:"kind,omitempty"`     // album / playlist / station / song / artist / music-video
+	EntityID   string    `json:"entityId,omitempty"` // 上级专辑/歌单/电台 ID
+	SongID     string    `json:"songId,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Index      int       `json:"index,omitempty"` // 曲目在上级中的序号（从 1 开始）
+	Count      int       `json:"count,omitempty"` // 上级中的曲目数
+	Bytes      int64     `json:"bytes,omitempty"`
+	TotalBytes int64     `json:"totalBytes,omitempty"`
+	Codec      string    `json:"codec,omitempty"`
+	Quality    string    `json:"quality,omitempty"`
+	Format     string    `json:"format,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// Bus 同步分发事件，订阅者需尽快返回
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[int]func(Event)
+	next int
+}
+
+// Default 全局事件总线
+var Default = &Bus{}
+
+// Subscribe 注册订阅者，返回取消订阅函数
+func (b *Bus) Subscribe(fn func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]func(Event))
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}
+}
+
+// Active 是否有订阅者，用于跳过高频事件的构造
+func (b *Bus) Active() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs) > 0
+}
+
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subs {
+		fn(e)
+	}
+}
+
+func Subscribe(fn func(Event)) func() { return Default.Subscribe(fn) }
+
+func Publish(e Event) { Default.Publish(e) }
+
+// Progress 字节进度，作为 io.Writer 使用，每前进 1% 发布一次事件
+type Progress struct {
+	bus     *Bus
+	event   Event
+	done    int64
+	lastPct int64
+}
+
+// NewProgress 创建发布到 bus 的进度上报器，bus 为 nil 时使用 Default；
+// typ 为 DownloadProgress 或 DecryptProgress；total 未知时传 -1
+func NewProgress(bus *Bus, typ string, songID string, path string, total int64) *Progress {
+	if bus == nil {
+		bus = Default
+	}
+	return &Progress{
+		bus:     bus,
+		event:   Event{Type: typ, SongID: songID, Path: path, TotalBytes: total},
+		lastPct: -1,
+	}
+}
+
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *Progress) Add(n int64) {
+	p.done += n
+	if !p.bus.Active() {
+		return
+	}
+	if p.event.TotalBytes > 0 {
+		pct := p.done * 100 / p.event.TotalBytes
+		if pct == p.lastPct {
+			return
+		}
+		p.lastPct = pct
+	} else if p.done-p.lastPct < 1<<20 && p.lastPct >= 0 {
+		// 总大小未知时每 1 MiB 上报一次
+		return
+	} else {
+		p.lastPct = p.done
+	}
+	e := p.event
+	e.Bytes = p.done
+	p.bus.Publish(e)
+}
@@ -1,6 +1,7 @@
 package lyrics
 
 import (
+	"context"
     "encoding/json"
     "errors"
     "fmt"
@@ -28,12 +29,12 @@ type SongLyrics struct {
 	} `json:"data"`
 }
 
-func Get(storefront, songId, lrcType, language, lrcFormat, token, mediaUserToken string) (string, error) {
+func Get(ctx context.Context, storefront, songId, lrcType, language, lrcFormat, token, mediaUserToken string) (string, error) {
 	if len(mediaUserToken) < 50 {
 		return "", errors.New("MediaUserToken not set")
 	}
 
-	ttml, err := getSongLyrics(songId, storefront, token, mediaUserToken, lrcType, language)
+	ttml, err := getSongLyrics(ctx, songId, storefront, token, mediaUserToken, lrcType, language)
 	if err != nil {
 		return "", err
 	}
@@ -50,8 +51,8 @@ func Get(storefront, songId, lrcType, language, lrcFormat, token, mediaUserToken
 	return lrc, nil
 }
 
-func getSongLyrics(songId string, storefront string, token string, userToken string, lrcType string, language string) (string, error) {
-	req, err := http.NewRequest("GET",
+func getSongLyrics(ctx context.Context, songId string, storefront string, token string, userToken string, lrcType string, language string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET",
 		fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/songs/%s/%s?l=%s&extend=ttmlLocalizations", storefront, songId, lrcType, language), nil)
 	if err != nil {
 		return "", err
@@ -0,0 +1,217 @@
+package naming
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Fields 目录与文件名模板中可用的字段；不适用于当前实体的字段为零值
+type Fields struct {
+	ArtistName    string
+	UrlArtistName string // 链接中的艺术家，未知时同 ArtistName
+	ArtistId      string
+	AlbumName     string
+	AlbumId       string
+	AlbumArtist   string
+	PlaylistName  string
+	PlaylistId    string
+	SongName      string
+	SongId        string
+	SongNumber    int // 在专辑/歌单中的序号
+	DiscNumber    int
+	DiscTotal     int
+	TrackNumber   int
+	TrackTotal    int
+	Genre         string
+	Composer      string
+	ISRC          string
+	ReleaseDate   string
+	ReleaseYear   string
+	UPC           string
+	RecordLabel   string
+	Copyright     string
+	Quality       string
+	Codec         string
+	SampleRate    int // Hz，未知时为 0
+	Tag           string
+	IsCompilation bool
+	Explicit      bool
+	Clean         bool
+}
+
+// 旧写法中与字段名不同的占位符
+var aliases = map[string]string{
+	"SongNumer": "pad 2 .SongNumber",
+}
+
+var funcs = template.FuncMap{
+	"pad":      pad,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"trim":     strings.TrimSpace,
+	"truncate": truncate,
+	"default":  defaultValue,
+	"replace":  func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+// pad 数字补零到 n 位，非数字原样返回
+func pad(n int, v interface{}) string {
+	switch x := v.(type) {
+	case int:
+		return fmt.Sprintf("%0*d", n, x)
+	case string:
+		if i, err := strconv.Atoi(x); err == nil {
+			return fmt.Sprintf("%0*d", n, i)
+		}
+		return x
+	}
+	return fmt.Sprint(v)
+}
+
+// truncate 按字符截断到 n 个
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if n >= 0 && len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// defaultValue v 为空或零值时返回 def
+func defaultValue(def string, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	if rv.IsZero() {
+		return def
+	}
+	if rv.Kind() == reflect.String && strings.TrimSpace(rv.String()) == "" {
+		return def
+	}
+	return v
+}
+
+func isField(name string) bool {
+	_, ok := reflect.TypeOf(Fields{}).FieldByName(name)
+	return ok
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// legacyAction 旧占位符对应的模板动作，未知占位符返回空
+func legacyAction(name string) string {
+	if a, ok := aliases[name]; ok {
+		return "{{" + a + "}}"
+	}
+	if isField(name) {
+		return "{{." + name + "}}"
+	}
+	return ""
+}
+
+// convert 将旧的 {Name} 占位符改写为模板动作，{{...}} 原样保留；
+// 未知占位符保持字面量，旧示例中的 {{Tag}} 视为带花括号的 {Tag}
+func convert(format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		if strings.HasPrefix(format[i:], "{{") {
+			end := strings.Index(format[i+2:], "}}")
+			if end < 0 {
+				b.WriteString(format[i:])
+				break
+			}
+			inner := format[i+2 : i+2+end]
+			if a := legacyAction(inner); a != "" && isIdent(inner) {
+				b.WriteString(`{{"{"}}` + a + `{{"}"}}`)
+			} else {
+				b.WriteString(format[i : i+4+end])
+			}
+			i += end + 4
+			continue
+		}
+		if format[i] == '{' {
+			if end := strings.IndexByte(format[i+1:], '}'); end >= 0 {
+				name := format[i+1 : i+1+end]
+				if a := legacyAction(name); a != "" && isIdent(name) {
+					b.WriteString(a)
+					i += end + 2
+					continue
+				}
+			}
+		}
+		b.WriteByte(format[i])
+		i++
+	}
+	return b.String()
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*template.Template{}
+)
+
+// Parse 解析格式字符串，支持旧的 {Name} 占位符与 text/template 语法
+func Parse(format string) (*template.Template, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if t, ok := cache[format]; ok {
+		return t, nil
+	}
+	t, err := template.New("name").Funcs(funcs).Parse(convert(format))
+	if err != nil {
+		return nil, err
+	}
+	cache[format] = t
+	return t, nil
+}
+
+// Check 检查格式字符串能否解析并以示例字段渲染
+func Check(format string) error {
+	t, err := Parse(format)
+	if err != nil {
+		return err
+	}
+	return t.Execute(&bytes.Buffer{}, Fields{SongNumber: 1, DiscNumber: 1, TrackNumber: 1})
+}
+
+// Render 以 f 渲染格式字符串
+func Render(format string, f Fields) (string, error) {
+	t, err := Parse(format)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, f); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Replace 只替换旧的 {Name} 占位符，模板出错时作为退路
+func Replace(format string, f Fields) string {
+	v := reflect.ValueOf(f)
+	var pairs []string
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(v.Field(i).Interface()))
+	}
+	pairs = append(pairs, "{SongNumer}", pad(2, f.SongNumber))
+	return strings.NewReplacer(pairs...).Replace(format)
+}
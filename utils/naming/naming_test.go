@@ -0,0 +1,106 @@
+package naming
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"plain text", "Music", "Music"},
+		{"field", "{ArtistName}", "{{.ArtistName}}"},
+		{"alias", "{SongNumer}. {SongName}", "{{pad 2 .SongNumber}}. {{.SongName}}"},
+		{"unknown placeholder kept", "{Foo} {SongName}", "{Foo} {{.SongName}}"},
+		{"template kept", "{{.AlbumName}} [{{.Codec}}]", "{{.AlbumName}} [{{.Codec}}]"},
+		{"legacy braces around field", "{{Tag}}", `{{"{"}}{{.Tag}}{{"}"}}`},
+		{"unclosed brace", "{SongName", "{SongName"},
+		{"unclosed template", "{{.SongName", "{{.SongName"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convert(tt.format); got != tt.want {
+				t.Errorf("convert(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	f := Fields{
+		ArtistName:  "Artist",
+		AlbumName:   "Album",
+		SongName:    "Song",
+		SongNumber:  3,
+		DiscNumber:  2,
+		DiscTotal:   2,
+		TrackNumber: 7,
+		Tag:         "E",
+		Explicit:    true,
+	}
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"legacy", "{SongNumer}. {SongName}", "03. Song", false},
+		{"legacy braces", "{AlbumName} {{Tag}}", "Album {E}", false},
+		{"template", "{{.ArtistName}} - {{.AlbumName}}", "Artist - Album", false},
+		{"mixed", "{ArtistName} - {{upper .SongName}}", "Artist - SONG", false},
+		{"condition", "{{if gt .DiscTotal 1}}{{.DiscNumber}}-{{end}}{{pad 2 .TrackNumber}}", "2-07", false},
+		{"explicit flag", "{{.SongName}}{{if .Explicit}} [E]{{end}}", "Song [E]", false},
+		{"default", `{{default "Unknown" .Genre}}`, "Unknown", false},
+		{"truncate", "{{truncate 2 .AlbumName}}", "Al", false},
+		{"replace", `{{replace "o" "0" .SongName}}`, "S0ng", false},
+		{"unknown placeholder", "{Foo}", "{Foo}", false},
+		{"parse error", "{{.SongName", "", true},
+		{"unknown field", "{{.Nope}}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.format, f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"{SongNumer}. {SongName}", false},
+		{"{{pad 2 .TrackNumber}} {{.SongName}}", false},
+		{"{{.SongName", true},
+		{"{{.Missing}}", true},
+		{"{{nofunc .SongName}}", true},
+	}
+	for _, tt := range tests {
+		if err := Check(tt.format); (err != nil) != tt.wantErr {
+			t.Errorf("Check(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	f := Fields{ArtistName: "Artist", SongName: "Song", SongNumber: 5}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{ArtistName} - {SongName}", "Artist - Song"},
+		{"{SongNumer}", "05"},
+		{"{{.SongName}}", "{{.SongName}}"},
+	}
+	for _, tt := range tests {
+		if got := Replace(tt.format, f); got != tt.want {
+			t.Errorf("Replace(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
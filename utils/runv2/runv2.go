@@ -19,6 +19,7 @@ import (
 	"encoding/binary"
 	"github.com/schollz/progressbar/v3"
 
+	"main/utils/events"
 	"main/utils/structs"
 )
 const prefetchKey = "skd://itunes.apple.com/P000000000/s1/e1"
@@ -44,7 +45,8 @@ func (b *TimedResponseBody) Read(p []byte) (int, error) {
 }
 
 
-func Run(adamId string, playlistUrl string, outfile string, Config structs.ConfigSet) error {
+// Run 边下载边解密到 outfile，下载与解密进度发布到 bus；返回媒体播放列表中的分段数，供校验分片数
+func Run(ctx context.Context, adamId string, playlistUrl string, outfile string, Config structs.ConfigSet, bus *events.Bus) (int, error) {
     var err error
     var optstimeout uint
     if Config.DownloadTimeoutSec > 0 {
@@ -56,47 +58,47 @@ func Run(adamId string, playlistUrl string, outfile string, Config structs.Confi
     header := make(http.Header)
 
 	// request media playlist
-	req, err := http.NewRequest("GET", playlistUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", playlistUrl, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	req.Header = header
 	// requesting an HLS playlist should be relatively fast, so we set the timeout directly on the client
     do, err := (&http.Client{Timeout: timeout}).Do(req)
     if err != nil {
-        return err
+        return 0, err
     }
     defer do.Body.Close()
 
 	// parse m3u8
 	segments, err := parseMediaPlaylist(do.Body)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	segment := segments[0]
 	if segment == nil {
-		return errors.New("no segments extracted from playlist")
+		return 0, errors.New("no segments extracted from playlist")
 	}
 	if segment.Limit <= 0 {
-		return errors.New("non-byterange playlists are currently unsupported")
+		return 0, errors.New("non-byterange playlists are currently unsupported")
 	}
 
 	// get URL to the actual file
 	parsedUrl, err := url.Parse(playlistUrl)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	fileUrl, err := parsedUrl.Parse(segment.URI)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// request mp4
-	ctx, cancel := context.WithCancelCause(context.Background())
+	dlCtx, cancel := context.WithCancelCause(ctx)
 	defer cancel(nil)
-	req, err = http.NewRequestWithContext(ctx, "GET", fileUrl.String(), nil)
+	req, err = http.NewRequestWithContext(dlCtx, "GET", fileUrl.String(), nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	req.Header = header
 
@@ -108,7 +110,7 @@ func Run(adamId string, playlistUrl string, outfile string, Config structs.Confi
         timer := time.AfterFunc(timeout, func() { cancel(ErrTimeout) })
         do, err = client.Do(req)
         if err != nil {
-            return err
+            return 0, err
         }
         defer do.Body.Close()
         body = &TimedResponseBody{
@@ -120,7 +122,7 @@ func Run(adamId string, playlistUrl string, outfile string, Config structs.Confi
     } else {
         do, err = client.Do(req)
         if err != nil {
-            return err
+            return 0, err
         }
         defer do.Body.Close()
         if do.ContentLength < int64(Config.MaxMemoryLimit * 1024 * 1024) {
@@ -143,7 +145,7 @@ func Run(adamId string, playlistUrl string, outfile string, Config structs.Confi
                     BarEnd:        "",
                 }),
             )
-            io.Copy(io.MultiWriter(&buffer, bar), do.Body)
+            io.Copy(io.MultiWriter(&buffer, bar, events.NewProgress(bus, events.DownloadProgress, adamId, outfile, do.ContentLength)), do.Body)
             body = &buffer
             fmt.Print("Downloaded\n")
         } else {
@@ -156,31 +158,34 @@ func Run(adamId string, playlistUrl string, outfile string, Config structs.Confi
 	// connect to decryptor
 	//addr := fmt.Sprintf("127.0.0.1:10020")
 	addr := Config.DecryptM3u8Port
-	conn, err := net.Dial("tcp", addr)
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	//fmt.Print("Decrypting...\n")
 	defer Close(conn)
 
-	err = downloadAndDecryptFile(conn, body, outfile, adamId, segments, totalLen, Config)
+	err = downloadAndDecryptFile(ctx, conn, body, outfile, adamId, segments, totalLen, Config, bus)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	fmt.Print("Decrypted\n")
-	return nil
+	return countSegments(segments), nil
 }
 
-func downloadAndDecryptFile(conn io.ReadWriter, in io.Reader, outfile string,
-	adamId string, playlistSegments []*m3u8.MediaSegment, totalLen int64, Config structs.ConfigSet) error {
+func downloadAndDecryptFile(ctx context.Context, conn io.ReadWriter, in io.Reader, outfile string,
+	adamId string, playlistSegments []*m3u8.MediaSegment, totalLen int64, Config structs.ConfigSet, bus *events.Bus) error {
 	var buffer bytes.Buffer
 	var outBuf *bufio.Writer
+	// 直接写入 outfile，失败时由调用方删除（通常传入 atomicfile.PartPath 生成的临时文件）
+	var ofh *os.File
 	MaxMemorySize := int64(Config.MaxMemoryLimit * 1024 * 1024)
 	inBuf := bufio.NewReader(in)
 	if totalLen <= MaxMemorySize {
 		outBuf = bufio.NewWriter(&buffer)
 	} else {
-		ofh, err := os.Create(outfile)
+		var err error
+		ofh, err = os.Create(outfile)
 		if err != nil {
 			return err
 		}
@@ -229,8 +234,13 @@ func downloadAndDecryptFile(conn io.ReadWriter, in io.Reader, outfile string,
 		}),
 	)
 	bar.Add64(int64(offset))
+	progress := events.NewProgress(bus, events.DecryptProgress, adamId, outfile, totalLen)
+	progress.Add(int64(offset))
 	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
 	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		var frag *mp4.Fragment
 		rawoffset := offset
 		frag, offset, err = ReadNextFragment(inBuf, offset)
@@ -239,7 +249,10 @@ func downloadAndDecryptFile(conn io.ReadWriter, in io.Reader, outfile string,
 			return err
 		}
 		if frag == nil {
-			// check offset against Content-Length?
+			// 流提前结束时分片数少于播放列表中的分段数
+			if i < len(playlistSegments) && playlistSegments[i] != nil {
+				return fmt.Errorf("incomplete stream: got %d fragments, playlist has more", i)
+			}
 			break
 		}
 		// print progress
@@ -273,6 +286,7 @@ func downloadAndDecryptFile(conn io.ReadWriter, in io.Reader, outfile string,
 			return err
 		}
 		bar.Add64(int64(rawoffset))
+		progress.Add(int64(rawoffset))
 	}
 	err = outBuf.Flush()
 	if err != nil {
@@ -280,18 +294,9 @@ func downloadAndDecryptFile(conn io.ReadWriter, in io.Reader, outfile string,
 	}
 	if totalLen <= MaxMemorySize {
 		// create output file
-		ofh, err := os.Create(outfile)
-		if err != nil {
-			return err
-		}
-		defer ofh.Close()
-
-		_, err = ofh.Write(buffer.Bytes())
-		if err != nil {
-			return err
-		}
+		return os.WriteFile(outfile, buffer.Bytes(), 0666)
 	}
-	return nil
+	return ofh.Close()
 }
 
 // Remove boxes in the init segment that are known to cause compatibility issues
@@ -348,6 +353,17 @@ func filterResponse(f io.Reader) (*bytes.Buffer, error) {
 	return buf, nil
 }
 
+// countSegments 播放列表中的分段数；m3u8 库预分配的切片尾部为 nil
+func countSegments(segments []*m3u8.MediaSegment) int {
+	n := 0
+	for _, seg := range segments {
+		if seg != nil {
+			n++
+		}
+	}
+	return n
+}
+
 func parseMediaPlaylist(r io.ReadCloser) ([]*m3u8.MediaSegment, error) {
 	defer r.Close()
 	playlistBuf, err := filterResponse(r)
@@ -9,6 +9,7 @@ import (
 	"github.com/go-resty/resty/v2"
 	"google.golang.org/protobuf/proto"
 
+	"main/utils/events"
 	cdm "main/utils/runv3/cdm"
 	key "main/utils/runv3/key"
 	"os"
@@ -103,7 +104,7 @@ func AfterRequest(response *resty.Response) ([]byte, error) {
 	return license, nil
 }
 
-func GetWebplayback(adamId string, authtoken string, mutoken string, mvmode bool) (string, string, string, error) {
+func GetWebplayback(ctx context.Context, adamId string, authtoken string, mutoken string, mvmode bool) (string, string, string, error) {
 	url := "https://play.music.apple.com/WebObjects/MZPlay.woa/wa/webPlayback"
 	postData := map[string]string{
 		"salableAdamId": adamId,
@@ -113,7 +114,7 @@ func GetWebplayback(adamId string, authtoken string, mutoken string, mvmode bool
 		fmt.Println("Error encoding JSON:", err)
 		return "", "", "", err
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(jsonData)))
 	if err != nil {
 		fmt.Println("Error creating request:", err)
 		return "", "", "", err
@@ -149,7 +150,7 @@ func GetWebplayback(adamId string, authtoken string, mutoken string, mvmode bool
 		// 遍历 Assets
 		for i := range obj.List[0].Assets {
 			if obj.List[0].Assets[i].Flavor == "28:ctrp256" {
-				kidBase64, fileurl, uriPrefix, err := extractKidBase64(obj.List[0].Assets[i].URL, false)
+				kidBase64, fileurl, uriPrefix, err := extractKidBase64(ctx, obj.List[0].Assets[i].URL, false)
 				if err != nil {
 					return "", "", "", err
 				}
@@ -173,9 +174,13 @@ type Songlist struct {
 	Status int `json:"status"`
 }
 
-func extractKidBase64(b string, mvmode bool) (string, string, string, error) {
+func extractKidBase64(ctx context.Context, b string, mvmode bool) (string, string, string, error) {
     client := &http.Client{Timeout: 30 * time.Second}
-    resp, err := client.Get(b)
+    req, err := http.NewRequestWithContext(ctx, "GET", b, nil)
+    if err != nil {
+        return "", "", "", err
+    }
+    resp, err := client.Do(req)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -228,9 +233,13 @@ func extractKidBase64(b string, mvmode bool) (string, string, string, error) {
 	}
 	return kidbase64, urlBuilder.String(), uriPrefix, nil
 }
-func extsong(b string) (*os.File, int64, error) {
+func extsong(ctx context.Context, adamId string, b string, bus *events.Bus) (*os.File, int64, error) {
     client := &http.Client{Timeout: 120 * time.Second}
-    resp, err := client.Get(b)
+    req, err := http.NewRequestWithContext(ctx, "GET", b, nil)
+    if err != nil {
+        return nil, 0, err
+    }
+    resp, err := client.Do(req)
     if err != nil {
         fmt.Printf("下载文件失败: %v\n", err)
         return nil, 0, err
@@ -258,7 +267,7 @@ func extsong(b string) (*os.File, int64, error) {
 			BarEnd:        "",
 		}),
 	)
-    _, err = io.Copy(io.MultiWriter(tmp, bar), resp.Body)
+    _, err = io.Copy(io.MultiWriter(tmp, bar, events.NewProgress(bus, events.DownloadProgress, adamId, tmp.Name(), resp.ContentLength)), resp.Body)
     if err != nil {
         tmp.Close()
         os.Remove(tmp.Name())
@@ -271,24 +280,24 @@ func extsong(b string) (*os.File, int64, error) {
     }
     return tmp, resp.ContentLength, nil
 }
-func Run(adamId string, trackpath string, authtoken string, mutoken string, mvmode bool, serverUrl string) (string, error) {
+// Run mvmode 时返回密钥与分段地址，否则下载并解密到 trackpath；下载进度发布到 bus
+func Run(ctx context.Context, adamId string, trackpath string, authtoken string, mutoken string, mvmode bool, serverUrl string, bus *events.Bus) (string, error) {
 	var keystr string //for mv key
 	var fileurl string
 	var kidBase64 string
 	var uriPrefix string
 	var err error
 	if mvmode {
-		kidBase64, fileurl, uriPrefix, err = extractKidBase64(trackpath, true)
+		kidBase64, fileurl, uriPrefix, err = extractKidBase64(ctx, trackpath, true)
 		if err != nil {
 			return "", err
 		}
 	} else {
-		fileurl, kidBase64, uriPrefix, err = GetWebplayback(adamId, authtoken, mutoken, false)
+		fileurl, kidBase64, uriPrefix, err = GetWebplayback(ctx, adamId, authtoken, mutoken, false)
 		if err != nil {
 			return "", err
 		}
 	}
-	ctx := context.Background()
 	ctx = context.WithValue(ctx, "pssh", kidBase64)
 	ctx = context.WithValue(ctx, "adamId", adamId)
 	ctx = context.WithValue(ctx, "uriPrefix", uriPrefix)
@@ -328,11 +337,12 @@ func Run(adamId string, trackpath string, authtoken string, mutoken string, mvmo
 		keyAndUrls := "1:" + keystr + ";" + fileurl
 		return keyAndUrls, nil
 	}
-    bodyFile, _, err := extsong(fileurl)
+    bodyFile, _, err := extsong(ctx, adamId, fileurl, bus)
     if err != nil {
         return "", err
     }
     fmt.Print("Downloaded\n")
+    // 直接解密到 trackpath，原子替换由调用方负责（通常传入 atomicfile.PartPath 生成的临时文件）
     ofh, err := os.Create(trackpath)
     if err != nil {
         bodyFile.Close()
@@ -340,18 +350,17 @@ func Run(adamId string, trackpath string, authtoken string, mutoken string, mvmo
         return "", err
     }
     err = DecryptMP4(bodyFile, keybt, ofh)
+    bodyFile.Close()
+    os.Remove(bodyFile.Name())
+    if cerr := ofh.Close(); err == nil {
+        err = cerr
+    }
     if err != nil {
         fmt.Print("Decryption failed\n")
-        ofh.Close()
-        bodyFile.Close()
-        os.Remove(bodyFile.Name())
+        os.Remove(trackpath)
         return "", err
-    } else {
-        fmt.Print("Decrypted\n")
     }
-    ofh.Close()
-    bodyFile.Close()
-    os.Remove(bodyFile.Name())
+    fmt.Print("Decrypted\n")
     return "", nil
 }
 
@@ -361,14 +370,14 @@ type Segment struct {
 	Data  []byte
 }
 
-func downloadSegment(url string, index int, wg *sync.WaitGroup, segmentsChan chan<- Segment, client *http.Client, limiter chan struct{}) {
+func downloadSegment(ctx context.Context, url string, index int, wg *sync.WaitGroup, segmentsChan chan<- Segment, client *http.Client, limiter chan struct{}) {
 	// 函数退出时，从 limiter 中接收一个值，释放一个并发槽位
 	defer func() {
 		<-limiter
 		wg.Done()
 	}()
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		fmt.Printf("错误(分段 %d): 创建请求失败: %v\n", index, err)
 		return
@@ -444,7 +453,7 @@ func fileWriter(wg *sync.WaitGroup, segmentsChan <-chan Segment, outputFile io.W
 	}
 }
 
-func ExtMvData(keyAndUrls string, savePath string, maxConcurrency int) error {
+func ExtMvData(ctx context.Context, keyAndUrls string, savePath string, maxConcurrency int, bus *events.Bus) error {
 	segments := strings.Split(keyAndUrls, ";")
 	key := segments[0]
 	//fmt.Println(key)
@@ -468,7 +477,7 @@ func ExtMvData(keyAndUrls string, savePath string, maxConcurrency int) error {
 
 	// 初始化进度条
 	bar := progressbar.DefaultBytes(-1, "Downloading...")
-	barWriter := io.MultiWriter(tempFile, bar)
+	barWriter := io.MultiWriter(tempFile, bar, events.NewProgress(bus, events.DownloadProgress, "", savePath, -1))
 
 	// 启动写入 Goroutine
 	writerWg.Add(1)
@@ -476,6 +485,9 @@ func ExtMvData(keyAndUrls string, savePath string, maxConcurrency int) error {
 
 	// 启动下载 Goroutines
 	for i, url := range urls {
+		if ctx.Err() != nil {
+			break
+		}
 		// 在启动 Goroutine 前，向 limiter 发送一个值来“获取”一个槽位
 		// 如果 limiter 已满 (达到10个)，这里会阻塞，直到有其他任务完成并释放槽位
 		//fmt.Printf("请求启动任务 %d...\n", i)
@@ -484,7 +496,7 @@ func ExtMvData(keyAndUrls string, savePath string, maxConcurrency int) error {
 
 		downloadWg.Add(1)
 		// 将 limiter 传递给下载函数
-		go downloadSegment(url, i, &downloadWg, segmentsChan, client, limiter)
+		go downloadSegment(ctx, url, i, &downloadWg, segmentsChan, client, limiter)
 	}
 
 	// 等待所有下载任务完成
@@ -500,14 +512,20 @@ func ExtMvData(keyAndUrls string, savePath string, maxConcurrency int) error {
 		fmt.Printf("关闭临时文件失败: %v\n", err)
 		return err
 	}
+	// 中断时不再解密，避免留下不完整的输出
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	fmt.Println("\nDownloaded.")
 
-	cmd1 := exec.Command("mp4decrypt", "--key", key, tempFile.Name(), filepath.Base(savePath))
+	// 直接解密到 savePath，原子替换由调用方负责
+	cmd1 := exec.CommandContext(ctx, "mp4decrypt", "--key", key, tempFile.Name(), filepath.Base(savePath))
 	cmd1.Dir = filepath.Dir(savePath) //设置mp4decrypt的工作目录以解决中文路径错误
 	outlog, err := cmd1.CombinedOutput()
 	if err != nil {
 		fmt.Printf("Decrypt failed: %v\n", err)
 		fmt.Printf("Output:\n%s\n", outlog)
+		os.Remove(savePath)
 		return err
 	} else {
 		fmt.Println("Decrypted.")
@@ -0,0 +1,202 @@
+package sanitize
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxComponent 单个目录名/文件名的最大长度：posix 按字节，Windows 系按 UTF-16 单元
+const MaxComponent = 255
+
+// minComponent 为满足路径长度而截断时名称至少保留的长度
+const minComponent = 16
+
+// Profile 目标文件系统的命名规则
+type Profile struct {
+	Name         string
+	Forbidden    string // 除控制字符外需要替换的字符
+	Reserved     bool   // 是否避开 Windows 保留设备名（CON、NUL、COM1 ...）
+	TrimTrailing bool   // 是否去掉末尾的点与空格
+	UTF16        bool   // 名称长度按 UTF-16 单元计算（NTFS、FAT、exFAT 与 SMB 服务器），否则按字节
+	MaxPath      int    // 完整路径的最大长度，0 表示不限制
+	PathUTF16    bool   // MaxPath 按 UTF-16 单元计算（Windows 的 MAX_PATH），否则按字节（本机的 PATH_MAX）
+	Form         string // Unicode 规范化：nfc、nfd 或 none
+}
+
+const windowsForbidden = `<>:"/\|?*`
+
+var profiles = map[string]Profile{
+	"posix":   {Name: "posix", Forbidden: "/", MaxPath: 4096, Form: "nfc"},
+	"windows": {Name: "windows", Forbidden: windowsForbidden, Reserved: true, TrimTrailing: true, UTF16: true, MaxPath: 260, PathUTF16: true, Form: "nfc"},
+	// FAT32 的长文件名与 Windows 规则相同，完整路径同样不超过 260 个 UTF-16 单元
+	"fat32": {Name: "fat32", Forbidden: windowsForbidden, Reserved: true, TrimTrailing: true, UTF16: true, MaxPath: 260, PathUTF16: true, Form: "nfc"},
+	// exFAT 的名称规则与 Windows 相同，但文件系统本身几乎不限制路径长度，只受挂载它的本机限制
+	"exfat": {Name: "exfat", Forbidden: windowsForbidden, Reserved: true, TrimTrailing: true, UTF16: true, MaxPath: 4096, Form: "nfc"},
+	// 挂载的 SMB 共享：服务器按 Windows 规则处理名称，路径长度受本机限制
+	"smb": {Name: "smb", Forbidden: windowsForbidden, Reserved: true, TrimTrailing: true, UTF16: true, MaxPath: 4096, Form: "nfc"},
+}
+
+// Names 可选的配置档名称
+func Names() []string {
+	return []string{"posix", "windows", "fat32", "exfat", "smb"}
+}
+
+// Lookup 返回名称对应的配置档，maxPath 大于 0 时覆盖路径长度上限，form 非空时覆盖规范化方式。
+// name 为空时使用默认规则：按 windows 处理字符，但不限制完整路径长度
+func Lookup(name string, maxPath int, form string) (*Profile, error) {
+	var p Profile
+	if strings.TrimSpace(name) == "" {
+		p = profiles["windows"]
+		p.MaxPath = 0
+	} else {
+		var ok bool
+		if p, ok = profiles[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("unknown filename profile %q (available: %s)", name, strings.Join(Names(), ", "))
+		}
+	}
+	if maxPath > 0 {
+		p.MaxPath = maxPath
+	}
+	switch form {
+	case "":
+	case "nfc", "nfd", "none":
+		p.Form = form
+	default:
+		return nil, fmt.Errorf("unknown normalization %q (available: nfc, nfd, none)", form)
+	}
+	return &p, nil
+}
+
+var reservedNames = map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true}
+
+func init() {
+	for i := 0; i <= 9; i++ {
+		reservedNames[fmt.Sprintf("COM%d", i)] = true
+		reservedNames[fmt.Sprintf("LPT%d", i)] = true
+	}
+}
+
+func (p *Profile) normalize(s string) string {
+	switch p.Form {
+	case "nfc":
+		return norm.NFC.String(s)
+	case "nfd":
+		return norm.NFD.String(s)
+	}
+	return s
+}
+
+func (p *Profile) trim(s string) string {
+	s = strings.TrimSpace(s)
+	if p.TrimTrailing {
+		s = strings.TrimRight(s, ". ")
+	}
+	return s
+}
+
+// utf16Len s 编码为 UTF-16 后的单元数
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// pathLength 路径按本配置档计算的长度
+func (p *Profile) pathLength(s string) int {
+	if p.PathUTF16 {
+		return utf16Len(s)
+	}
+	return len(s)
+}
+
+// truncate 截断到 max 字节以内，不拆开 UTF-8 字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// truncate 截断到 max 以内（UTF16 时按 UTF-16 单元，否则按字节），不拆开字符
+func (p *Profile) truncate(s string, max int) string {
+	if !p.UTF16 {
+		return truncate(s, max)
+	}
+	n := 0
+	for i, r := range s {
+		if n += utf16.RuneLen(r); n > max {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// Component 处理单个目录名/文件名：规范化、替换非法与控制字符、处理保留名与末尾的点和空格，
+// 并截断到 max 以内（max <= 0 时为 MaxComponent）。空名称原样返回
+func (p *Profile) Component(name string, max int) string {
+	if name == "" {
+		return ""
+	}
+	if max <= 0 || max > MaxComponent {
+		max = MaxComponent
+	}
+	name = strings.ToValidUTF8(p.normalize(name), "_")
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(p.Forbidden, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = p.trim(p.truncate(p.trim(name), max))
+	if p.Reserved {
+		stem := name
+		if i := strings.IndexByte(stem, '.'); i >= 0 {
+			stem = stem[:i]
+		}
+		if reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+			name = p.trim(p.truncate(stem+"_"+name[len(stem):], max))
+		}
+	}
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// Fit 处理 dir 下的名称 name，使 dir/name 再加上 reserve（之后追加的扩展名等，按 ASCII 计）不超过路径长度上限
+func (p *Profile) Fit(dir string, name string, reserve int) string {
+	max := MaxComponent - reserve
+	room := -1
+	if p.MaxPath > 0 {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		room = p.MaxPath - p.pathLength(dir) - 1 - reserve
+		if room < minComponent {
+			room = minComponent
+		}
+		if p.UTF16 == p.PathUTF16 && room < max {
+			max = room
+		}
+	}
+	if max < minComponent {
+		max = minComponent
+	}
+	name = p.Component(name, max)
+	// 名称按 UTF-16 单元、路径按字节计时（smb），再按字节截断一次
+	if room >= 0 && p.UTF16 && !p.PathUTF16 && len(name) > room {
+		name = p.Component(truncate(name, room), max)
+	}
+	return name
+}
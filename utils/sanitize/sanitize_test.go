@@ -0,0 +1,187 @@
+package sanitize
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func mustLookup(t *testing.T, name string, maxPath int, form string) *Profile {
+	t.Helper()
+	p, err := Lookup(name, maxPath, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   string
+		maxPath   int
+		form      string
+		wantMax   int
+		wantUTF16 bool
+		wantErr   bool
+	}{
+		{"default", "", 0, "", 0, true, false},
+		{"default with max path", "", 300, "", 300, true, false},
+		{"windows", "windows", 0, "", 260, true, false},
+		{"case insensitive", "Windows", 0, "", 260, true, false},
+		{"posix", "posix", 0, "", 4096, false, false},
+		{"fat32", "fat32", 0, "", 260, true, false},
+		{"exfat", "EXFAT", 0, "", 4096, true, false},
+		{"smb", "smb", 0, "", 4096, true, false},
+		{"override", "posix", 1024, "nfd", 1024, false, false},
+		{"unknown profile", "ntfs", 0, "", 0, false, true},
+		{"unknown form", "posix", 0, "nfkc", 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Lookup(tt.profile, tt.maxPath, tt.form)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.MaxPath != tt.wantMax || p.UTF16 != tt.wantUTF16 {
+				t.Errorf("MaxPath = %d, UTF16 = %v, want %d, %v", p.MaxPath, p.UTF16, tt.wantMax, tt.wantUTF16)
+			}
+			if tt.form != "" && p.Form != tt.form {
+				t.Errorf("Form = %q, want %q", p.Form, tt.form)
+			}
+		})
+	}
+}
+
+func TestComponent(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		form    string
+		in      string
+		max     int
+		want    string
+	}{
+		{"empty", "windows", "", "", 0, ""},
+		{"forbidden windows", "windows", "", `a<b>c:d"e/f\g|h?i*j`, 0, "a_b_c_d_e_f_g_h_i_j"},
+		{"forbidden posix", "posix", "", `a:b/c?`, 0, "a:b_c?"},
+		{"control characters", "posix", "", "a\tb\x7fc", 0, "a_b_c"},
+		{"trailing dots windows", "windows", "", "Vol. 1...  ", 0, "Vol. 1"},
+		{"trailing dots posix", "posix", "", "Vol. 1...", 0, "Vol. 1..."},
+		{"reserved name", "windows", "", "con", 0, "con_"},
+		{"reserved name with extension", "windows", "", "COM1.m4a", 0, "COM1_.m4a"},
+		{"reserved name posix", "posix", "", "CON", 0, "CON"},
+		{"dot names", "posix", "", "..", 0, "_"},
+		{"only forbidden trimmed", "windows", "", " . ", 0, "_"},
+		{"invalid utf8", "posix", "", "a\xffb", 0, "a_b"},
+		{"nfc", "posix", "", "Cafe\u0301", 0, "Caf\u00e9"},
+		{"nfd", "posix", "nfd", "Caf\u00e9", 0, "Cafe\u0301"},
+		{"no normalization", "posix", "none", "Cafe\u0301", 0, "Cafe\u0301"},
+		{"truncate bytes", "posix", "", "日本語", 7, "日本"},
+		{"truncate utf16", "windows", "", "日本語", 2, "日本"},
+		{"truncate surrogate pair", "windows", "", "a😀b", 2, "a"},
+		{"forbidden fat32", "fat32", "", `a:b?. `, 0, "a_b_"},
+		{"reserved name exfat", "exfat", "", "aux.lrc", 0, "aux_.lrc"},
+		{"truncate then trim", "windows", "", "ab. cd", 4, "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := mustLookup(t, tt.profile, 0, tt.form)
+			if got := p.Component(tt.in, tt.max); got != tt.want {
+				t.Errorf("Component(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComponentMaxLength(t *testing.T) {
+	long := strings.Repeat("日", 300)
+	tests := []struct {
+		profile string
+		measure func(string) int
+	}{
+		// posix 按字节：255 字节内最多 85 个三字节字符
+		{"posix", func(s string) int { return len(s) }},
+		// windows、fat32、exfat 按 UTF-16 单元：255 个字符
+		{"windows", utf8.RuneCountInString},
+		{"fat32", utf8.RuneCountInString},
+		{"exfat", utf8.RuneCountInString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			got := mustLookup(t, tt.profile, 0, "").Component(long, 0)
+			if n := tt.measure(got); n > MaxComponent || n < MaxComponent-2 {
+				t.Errorf("length = %d, want close to %d", n, MaxComponent)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	dir := t.TempDir()
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := strings.Repeat("a", 300)
+	tests := []struct {
+		name    string
+		profile string
+		maxPath int
+		dir     string
+		reserve int
+		want    int // 结果名称的字节数
+	}{
+		{"no path limit", "", 0, dir, 0, MaxComponent},
+		{"reserve", "", 0, dir, 16, MaxComponent - 16},
+		{"path limit", "windows", 0, dir, 0, 260 - len(abs) - 1},
+		{"path limit with reserve", "windows", 0, dir, 10, 260 - len(abs) - 1 - 10},
+		{"explicit max path", "", 100, dir, 0, 100 - len(abs) - 1},
+		{"min component", "posix", len(abs) + 5, dir, 0, minComponent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := mustLookup(t, tt.profile, tt.maxPath, "")
+			if got := p.Fit(tt.dir, name, tt.reserve); len(got) != tt.want {
+				t.Errorf("Fit returned %d bytes, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestFitUnits(t *testing.T) {
+	dir := t.TempDir()
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := strings.Repeat("日", 300)
+	tests := []struct {
+		name    string
+		profile string
+		maxPath int
+		want    int // 结果名称的字符数
+	}{
+		// windows 的路径按 UTF-16 单元计算，一个汉字占一个单元
+		{"windows", "windows", 0, 260 - len(abs) - 1},
+		{"fat32", "fat32", 0, 260 - len(abs) - 1},
+		// exfat 与 smb 相同：名称按 UTF-16 单元，路径按字节
+		{"exfat", "exfat", len(abs) + 1 + 30, 10},
+		// smb 的名称按 UTF-16 单元，路径按字节：一个汉字占三个字节
+		{"smb", "smb", len(abs) + 1 + 30, 10},
+		// posix 全部按字节
+		{"posix", "posix", len(abs) + 1 + 30, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustLookup(t, tt.profile, tt.maxPath, "").Fit(dir, name, 0)
+			if n := utf8.RuneCountInString(got); n != tt.want {
+				t.Errorf("Fit returned %d characters, want %d", n, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketLibrary      = []byte("library")       // 文件路径 -> LibraryItem
+	bucketLibraryIndex = []byte("library-index") // song/<id>/<path>、isrc/<isrc>/<path>
+)
+
+// LibraryItem 曲库中的一个本地文件，来源于下载记录或扫描输出目录时读取的内嵌标签
+type LibraryItem struct {
+	Path      string    `json:"path"`
+	SongID    string    `json:"songId,omitempty"`
+	ISRC      string    `json:"isrc,omitempty"`
+	Codec     string    `json:"codec,omitempty"` // AAC / ALAC / ATMOS
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func createLibraryBuckets(tx *bolt.Tx) error {
+	if _, err := tx.CreateBucketIfNotExists(bucketLibrary); err != nil {
+		return err
+	}
+	_, err := tx.CreateBucketIfNotExists(bucketLibraryIndex)
+	return err
+}
+
+func libraryIndexKeys(it LibraryItem) [][]byte {
+	var keys [][]byte
+	if it.SongID != "" {
+		keys = append(keys, []byte("song/"+it.SongID+"/"+it.Path))
+	}
+	if it.ISRC != "" {
+		keys = append(keys, []byte("isrc/"+strings.ToUpper(it.ISRC)+"/"+it.Path))
+	}
+	return keys
+}
+
+func deleteLibraryItem(tx *bolt.Tx, path string) error {
+	lib := tx.Bucket(bucketLibrary)
+	data := lib.Get([]byte(path))
+	if data == nil {
+		return nil
+	}
+	var old LibraryItem
+	if err := json.Unmarshal(data, &old); err == nil {
+		idx := tx.Bucket(bucketLibraryIndex)
+		for _, k := range libraryIndexKeys(old) {
+			if err := idx.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return lib.Delete([]byte(path))
+}
+
+func putLibraryItem(tx *bolt.Tx, it LibraryItem) error {
+	if it.Path == "" {
+		return errors.New("library item path is required")
+	}
+	if it.SongID == "" && it.ISRC == "" {
+		return errors.New("library item needs a song id or isrc")
+	}
+	if it.UpdatedAt.IsZero() {
+		it.UpdatedAt = time.Now()
+	}
+	data, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	if err := deleteLibraryItem(tx, it.Path); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketLibrary).Put([]byte(it.Path), data); err != nil {
+		return err
+	}
+	idx := tx.Bucket(bucketLibraryIndex)
+	for _, k := range libraryIndexKeys(it) {
+		if err := idx.Put(k, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PutLibraryItem 写入或覆盖某个路径的曲库记录，同时维护歌曲 ID / ISRC 索引
+func (d *DB) PutLibraryItem(it LibraryItem) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return putLibraryItem(tx, it)
+	})
+}
+
+// RemoveLibraryItem 删除某个路径的曲库记录（文件已不存在时使用）
+func (d *DB) RemoveLibraryItem(path string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return deleteLibraryItem(tx, path)
+	})
+}
+
+// FindLibraryItems 按歌曲 ID 或 ISRC 查找曲库中的文件，结果按路径去重；isrc 为空时只按歌曲 ID
+func (d *DB) FindLibraryItems(songID, isrc string) ([]LibraryItem, error) {
+	var prefixes []string
+	if songID != "" {
+		prefixes = append(prefixes, "song/"+songID+"/")
+	}
+	if isrc != "" {
+		prefixes = append(prefixes, "isrc/"+strings.ToUpper(isrc)+"/")
+	}
+	var out []LibraryItem
+	seen := make(map[string]struct{})
+	err := d.db.View(func(tx *bolt.Tx) error {
+		lib := tx.Bucket(bucketLibrary)
+		c := tx.Bucket(bucketLibraryIndex).Cursor()
+		for _, prefix := range prefixes {
+			for k, _ := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+				path := strings.TrimPrefix(string(k), prefix)
+				if _, ok := seen[path]; ok {
+					continue
+				}
+				seen[path] = struct{}{}
+				data := lib.Get([]byte(path))
+				if data == nil {
+					continue
+				}
+				var it LibraryItem
+				if err := json.Unmarshal(data, &it); err != nil {
+					return err
+				}
+				out = append(out, it)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// ReplaceLibrary 以 items 替换整个曲库索引，在同一个事务中完成：任一记录写入失败时保留原有索引
+func (d *DB) ReplaceLibrary(items []LibraryItem) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketLibrary, bucketLibraryIndex} {
+			if err := tx.DeleteBucket(name); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+		if err := createLibraryBuckets(tx); err != nil {
+			return err
+		}
+		for _, it := range items {
+			if err := putLibraryItem(tx, it); err != nil {
+				return fmt.Errorf("%s: %w", it.Path, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func libraryPaths(items []LibraryItem) []string {
+	paths := make([]string, 0, len(items))
+	for _, it := range items {
+		paths = append(paths, it.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestFindLibraryItems(t *testing.T) {
+	db := openTestDB(t)
+	for _, it := range []LibraryItem{
+		{Path: "/a/1.m4a", SongID: "12", ISRC: "usabc0000001", Codec: "ALAC"},
+		{Path: "/b/1.m4a", SongID: "123", ISRC: "USABC0000001", Codec: "AAC"},
+		{Path: "/c/2.m4a", SongID: "12"},
+		{Path: "/d/3.m4a", ISRC: "GBXYZ0000003"},
+	} {
+		if err := db.PutLibraryItem(it); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		songID string
+		isrc   string
+		want   []string
+	}{
+		{"song id", "12", "", []string{"/a/1.m4a", "/c/2.m4a"}},
+		{"song id is not a prefix match", "1", "", []string{}},
+		{"isrc is case insensitive", "", "UsAbC0000001", []string{"/a/1.m4a", "/b/1.m4a"}},
+		{"song id or isrc, deduplicated", "12", "USABC0000001", []string{"/a/1.m4a", "/b/1.m4a", "/c/2.m4a"}},
+		{"isrc only item", "999", "GBXYZ0000003", []string{"/d/3.m4a"}},
+		{"nothing", "", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := db.FindLibraryItems(tt.songID, tt.isrc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := libraryPaths(items)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPutLibraryItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    LibraryItem
+		wantErr bool
+	}{
+		{"song id", LibraryItem{Path: "/a.m4a", SongID: "1"}, false},
+		{"isrc", LibraryItem{Path: "/a.m4a", ISRC: "X"}, false},
+		{"no id", LibraryItem{Path: "/a.m4a"}, true},
+		{"no path", LibraryItem{SongID: "1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := openTestDB(t).PutLibraryItem(tt.item)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PutLibraryItem error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLibraryItemUpdateAndRemove(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.PutLibraryItem(LibraryItem{Path: "/a.m4a", SongID: "1", ISRC: "OLD"}); err != nil {
+		t.Fatal(err)
+	}
+	// 覆盖同一路径时旧的索引键一并删除
+	if err := db.PutLibraryItem(LibraryItem{Path: "/a.m4a", SongID: "2", Codec: "ALAC"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		songID, isrc string
+		want         int
+	}{
+		{"1", "", 0},
+		{"", "OLD", 0},
+		{"2", "", 1},
+	} {
+		items, err := db.FindLibraryItems(tt.songID, tt.isrc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != tt.want {
+			t.Errorf("FindLibraryItems(%q, %q) = %d item(s), want %d", tt.songID, tt.isrc, len(items), tt.want)
+		}
+	}
+	items, _ := db.FindLibraryItems("2", "")
+	if len(items) == 1 && (items[0].Codec != "ALAC" || items[0].UpdatedAt.IsZero()) {
+		t.Errorf("stored item = %+v", items[0])
+	}
+
+	if err := db.RemoveLibraryItem("/a.m4a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RemoveLibraryItem("/missing.m4a"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := db.FindLibraryItems("2", ""); len(items) != 0 {
+		t.Errorf("item still indexed after remove: %v", items)
+	}
+}
+
+func TestReplaceLibrary(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []LibraryItem
+		want    []string // 替换后歌曲 1 对应的文件
+		wantErr bool
+	}{
+		{"replace", []LibraryItem{{Path: "/b.m4a", SongID: "1"}, {Path: "/c.m4a", SongID: "2"}}, []string{"/b.m4a"}, false},
+		{"empty", nil, nil, false},
+		// 任一记录无效时整个替换回滚，原有索引保留
+		{"invalid item", []LibraryItem{{Path: "/b.m4a", SongID: "1"}, {Path: "/d.m4a"}}, []string{"/a.m4a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if err := db.PutLibraryItem(LibraryItem{Path: "/a.m4a", SongID: "1"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.ReplaceLibrary(tt.items); (err != nil) != tt.wantErr {
+				t.Fatalf("ReplaceLibrary error = %v, wantErr %v", err, tt.wantErr)
+			}
+			items, err := db.FindLibraryItems("1", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := libraryPaths(items); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("song 1 = %v, want %v", got, tt.want)
+			}
+			if err := db.PutLibraryItem(LibraryItem{Path: "/e.m4a", SongID: "3"}); err != nil {
+				t.Fatalf("put after replace: %v", err)
+			}
+		})
+	}
+}
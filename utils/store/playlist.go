@@ -0,0 +1,129 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketPlaylists     = []byte("playlists")
+	bucketPlaylistLinks = []byte("playlist-links")
+)
+
+// PlaylistTrack 同步歌单中的一首曲目
+type PlaylistTrack struct {
+	SongID   string `json:"songId"`
+	Position int    `json:"position"` // 从 1 开始
+	Name     string `json:"name"`
+	Path     string `json:"path,omitempty"`
+}
+
+// PlaylistManifest playlist sync 上次同步时歌单的曲目与位置
+type PlaylistManifest struct {
+	PlaylistID string          `json:"playlistId"`
+	Storefront string          `json:"storefront"`
+	Name       string          `json:"name"`
+	Folder     string          `json:"folder"`
+	Tracks     []PlaylistTrack `json:"tracks"`
+	SyncedAt   time.Time       `json:"syncedAt"`
+}
+
+// PutPlaylist 写入或覆盖歌单清单
+func (d *DB) PutPlaylist(m PlaylistManifest) error {
+	if m.PlaylistID == "" {
+		return errors.New("playlist id is required")
+	}
+	if m.SyncedAt.IsZero() {
+		m.SyncedAt = time.Now()
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketPlaylists)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(m.PlaylistID), data)
+	})
+}
+
+// GetPlaylist 查询歌单清单，不存在时返回 nil
+func (d *DB) GetPlaylist(playlistID string) (*PlaylistManifest, error) {
+	var m *PlaylistManifest
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPlaylists)
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(playlistID))
+		if data == nil {
+			return nil
+		}
+		m = new(PlaylistManifest)
+		return json.Unmarshal(data, m)
+	})
+	return m, err
+}
+
+// Playlists 返回全部同步过的歌单
+func (d *DB) Playlists() ([]PlaylistManifest, error) {
+	var out []PlaylistManifest
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPlaylists)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var m PlaylistManifest
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+			out = append(out, m)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// PutPlaylistLinks 记录 playlist-links 在歌单目录中建立的链接，links 为空时删除记录
+func (d *DB) PutPlaylistLinks(playlistID string, links []string) error {
+	if playlistID == "" {
+		return errors.New("playlist id is required")
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketPlaylistLinks)
+		if err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return b.Delete([]byte(playlistID))
+		}
+		data, err := json.Marshal(links)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(playlistID), data)
+	})
+}
+
+// PlaylistLinks 查询上次为歌单建立的链接
+func (d *DB) PlaylistLinks(playlistID string) ([]string, error) {
+	var links []string
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPlaylistLinks)
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(playlistID))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &links)
+	})
+	return links, err
+}
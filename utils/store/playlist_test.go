@@ -0,0 +1,116 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPlaylists(t *testing.T) {
+	db := openTestDB(t)
+	synced := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		put     PlaylistManifest
+		wantErr bool
+	}{
+		{"first", PlaylistManifest{PlaylistID: "pl.1", Name: "One", Tracks: []PlaylistTrack{{SongID: "10", Position: 1, Path: "/p/01.m4a"}, {SongID: "11", Position: 2}}}, false},
+		{"second", PlaylistManifest{PlaylistID: "pl.2", Name: "Two", SyncedAt: synced}, false},
+		{"overwrite", PlaylistManifest{PlaylistID: "pl.1", Name: "One", Tracks: []PlaylistTrack{{SongID: "11", Position: 1}}}, false},
+		{"no id", PlaylistManifest{Name: "None"}, true},
+	}
+	for _, tt := range tests {
+		if err := db.PutPlaylist(tt.put); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: PutPlaylist error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	got := []struct {
+		id         string
+		wantTracks int
+		wantSynced time.Time
+		wantNil    bool
+	}{
+		{"pl.1", 1, time.Time{}, false},
+		{"pl.2", 0, synced, false},
+		{"pl.3", 0, time.Time{}, true},
+	}
+	for _, tt := range got {
+		m, err := db.GetPlaylist(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (m == nil) != tt.wantNil {
+			t.Fatalf("GetPlaylist(%q) = %v, want nil %v", tt.id, m, tt.wantNil)
+		}
+		if m == nil {
+			continue
+		}
+		if len(m.Tracks) != tt.wantTracks {
+			t.Errorf("GetPlaylist(%q) has %d track(s), want %d", tt.id, len(m.Tracks), tt.wantTracks)
+		}
+		// 未设置同步时间时写入当前时间
+		if m.SyncedAt.IsZero() || !tt.wantSynced.IsZero() && !m.SyncedAt.Equal(tt.wantSynced) {
+			t.Errorf("GetPlaylist(%q).SyncedAt = %v, want %v", tt.id, m.SyncedAt, tt.wantSynced)
+		}
+	}
+
+	list, err := db.Playlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range list {
+		ids = append(ids, m.PlaylistID)
+	}
+	sort.Strings(ids)
+	if len(ids) != 2 || ids[0] != "pl.1" || ids[1] != "pl.2" {
+		t.Errorf("Playlists() = %v, want [pl.1 pl.2]", ids)
+	}
+}
+
+func TestPlaylistsEmpty(t *testing.T) {
+	db := openTestDB(t)
+	if m, err := db.GetPlaylist("pl.1"); err != nil || m != nil {
+		t.Errorf("GetPlaylist on empty db = %v, %v", m, err)
+	}
+	if list, err := db.Playlists(); err != nil || len(list) != 0 {
+		t.Errorf("Playlists on empty db = %v, %v", list, err)
+	}
+}
+
+func TestPlaylistLinks(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		name    string
+		id      string
+		links   []string
+		want    string
+		wantErr bool
+	}{
+		{"put", "pl.1", []string{"/p/01.m4a", "/p/02.m4a"}, "[/p/01.m4a /p/02.m4a]", false},
+		{"overwrite", "pl.1", []string{"/p/01.flac"}, "[/p/01.flac]", false},
+		{"clear", "pl.1", nil, "[]", false},
+		{"no id", "", []string{"/p/01.m4a"}, "", true},
+	}
+	for _, tt := range tests {
+		err := db.PutPlaylistLinks(tt.id, tt.links)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: PutPlaylistLinks error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		got, err := db.PlaylistLinks(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s: PlaylistLinks(%q) = %v, want %s", tt.name, tt.id, got, tt.want)
+		}
+	}
+	if got, err := db.PlaylistLinks("pl.2"); err != nil || len(got) != 0 {
+		t.Errorf("PlaylistLinks(unknown) = %v, %v", got, err)
+	}
+}
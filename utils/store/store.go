@@ -0,0 +1,132 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+var bucketTracks = []byte("tracks")
+
+// TrackRecord 单曲下载状态，按 上级实体ID/歌曲ID 存储
+type TrackRecord struct {
+	EntityID   string    `json:"entityId"`
+	EntityType string    `json:"entityType"` // albums / playlists / stations
+	Storefront string    `json:"storefront"`
+	SongID     string    `json:"songId"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Codec      string    `json:"codec,omitempty"`
+	Quality    string    `json:"quality,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Checksum   string    `json:"checksum,omitempty"` // sha256
+	Reason     string    `json:"reason,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// DB 持久化的下载数据库（BoltDB 单文件）
+type DB struct {
+	db *bolt.DB
+}
+
+// Open 打开或创建数据库文件，文件被其他进程占用时在 timeout 后返回错误
+func Open(path string, timeout time.Duration) (*DB, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketTracks); err != nil {
+			return err
+		}
+		return createLibraryBuckets(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+func trackKey(entityID, songID string) []byte {
+	return []byte(entityID + "/" + songID)
+}
+
+// PutTrack 写入或覆盖一条单曲记录
+func (d *DB) PutTrack(r TrackRecord) error {
+	if r.EntityID == "" || r.SongID == "" {
+		return errors.New("entity id and song id are required")
+	}
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = time.Now()
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketTracks).Put(trackKey(r.EntityID, r.SongID), data)
+	})
+}
+
+// GetTrack 查询单曲记录，不存在时返回 nil
+func (d *DB) GetTrack(entityID, songID string) (*TrackRecord, error) {
+	var rec *TrackRecord
+	err := d.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketTracks).Get(trackKey(entityID, songID))
+		if data == nil {
+			return nil
+		}
+		rec = new(TrackRecord)
+		return json.Unmarshal(data, rec)
+	})
+	return rec, err
+}
+
+// Tracks 返回某个实体下的全部记录
+func (d *DB) Tracks(entityID string) ([]TrackRecord, error) {
+	var out []TrackRecord
+	prefix := []byte(entityID + "/")
+	err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketTracks).Cursor()
+		for k, v := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = c.Next() {
+			var r TrackRecord
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			out = append(out, r)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// FailedTracks 返回所有状态为失败的记录
+func (d *DB) FailedTracks() ([]TrackRecord, error) {
+	var out []TrackRecord
+	err := d.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketTracks).ForEach(func(k, v []byte) error {
+			var r TrackRecord
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if r.Status == StatusFailed {
+				out = append(out, r)
+			}
+			return nil
+		})
+	})
+	return out, err
+}
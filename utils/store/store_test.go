@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestTracks(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		name    string
+		put     TrackRecord
+		wantErr bool
+	}{
+		{"ok", TrackRecord{EntityID: "1", EntityType: "albums", SongID: "10", Status: StatusOK}, false},
+		{"failed", TrackRecord{EntityID: "1", EntityType: "albums", SongID: "11", Status: StatusFailed, Reason: "timeout"}, false},
+		{"other entity", TrackRecord{EntityID: "12", EntityType: "albums", SongID: "20", Status: StatusFailed}, false},
+		{"overwrite", TrackRecord{EntityID: "12", EntityType: "albums", SongID: "20", Status: StatusOK}, false},
+		{"no entity", TrackRecord{SongID: "30", Status: StatusOK}, true},
+		{"no song", TrackRecord{EntityID: "1", Status: StatusOK}, true},
+	}
+	for _, tt := range tests {
+		if err := db.PutTrack(tt.put); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: PutTrack error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	rec, err := db.GetTrack("1", "11")
+	if err != nil || rec == nil {
+		t.Fatalf("GetTrack(1, 11) = %v, %v", rec, err)
+	}
+	if rec.Status != StatusFailed || rec.Reason != "timeout" || rec.UpdatedAt.IsZero() {
+		t.Errorf("GetTrack(1, 11) = %+v", rec)
+	}
+	if rec, err := db.GetTrack("1", "99"); err != nil || rec != nil {
+		t.Errorf("GetTrack(1, 99) = %v, %v, want nil", rec, err)
+	}
+
+	// 前缀查询不包含 ID 以 "1" 开头的其他实体
+	for _, tt := range []struct {
+		entity string
+		want   []string
+	}{
+		{"1", []string{"10", "11"}},
+		{"12", []string{"20"}},
+		{"2", nil},
+	} {
+		list, err := db.Tracks(tt.entity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var songs []string
+		for _, r := range list {
+			songs = append(songs, r.SongID)
+		}
+		sort.Strings(songs)
+		if fmt.Sprint(songs) != fmt.Sprint(tt.want) {
+			t.Errorf("Tracks(%q) = %v, want %v", tt.entity, songs, tt.want)
+		}
+	}
+
+	failed, err := db.FailedTracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].SongID != "11" {
+		t.Errorf("FailedTracks() = %+v, want only 1/11", failed)
+	}
+}
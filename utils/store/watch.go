@@ -0,0 +1,89 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketWatch = []byte("watch")
+
+// WatchedArtist 关注的艺术家及已见过的专辑 ID
+type WatchedArtist struct {
+	ArtistID   string          `json:"artistId"`
+	Storefront string          `json:"storefront"`
+	Name       string          `json:"name"`
+	URL        string          `json:"url"`
+	Seen       map[string]bool `json:"seen"`
+	AddedAt    time.Time       `json:"addedAt"`
+	LastSync   time.Time       `json:"lastSync,omitempty"`
+}
+
+// PutWatch 写入或覆盖关注记录
+func (d *DB) PutWatch(w WatchedArtist) error {
+	if w.ArtistID == "" {
+		return errors.New("artist id is required")
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketWatch)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(w.ArtistID), data)
+	})
+}
+
+// GetWatch 查询关注记录，不存在时返回 nil
+func (d *DB) GetWatch(artistID string) (*WatchedArtist, error) {
+	var w *WatchedArtist
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketWatch)
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(artistID))
+		if data == nil {
+			return nil
+		}
+		w = new(WatchedArtist)
+		return json.Unmarshal(data, w)
+	})
+	return w, err
+}
+
+// RemoveWatch 取消关注
+func (d *DB) RemoveWatch(artistID string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketWatch)
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(artistID))
+	})
+}
+
+// Watches 返回全部关注的艺术家
+func (d *DB) Watches() ([]WatchedArtist, error) {
+	var out []WatchedArtist
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketWatch)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var w WatchedArtist
+			if err := json.Unmarshal(v, &w); err != nil {
+				return err
+			}
+			out = append(out, w)
+			return nil
+		})
+	})
+	return out, err
+}
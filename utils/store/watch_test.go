@@ -0,0 +1,78 @@
+package store
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestWatches(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		name    string
+		put     WatchedArtist
+		wantErr bool
+	}{
+		{"first", WatchedArtist{ArtistID: "1", Name: "A", Seen: map[string]bool{"100": true}}, false},
+		{"second", WatchedArtist{ArtistID: "2", Name: "B"}, false},
+		{"overwrite", WatchedArtist{ArtistID: "1", Name: "A", Seen: map[string]bool{"100": true, "101": true}}, false},
+		{"no id", WatchedArtist{Name: "C"}, true},
+	}
+	for _, tt := range tests {
+		if err := db.PutWatch(tt.put); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: PutWatch error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	for _, tt := range []struct {
+		id       string
+		wantSeen int
+		wantNil  bool
+	}{
+		{"1", 2, false},
+		{"2", 0, false},
+		{"3", 0, true},
+	} {
+		w, err := db.GetWatch(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (w == nil) != tt.wantNil {
+			t.Fatalf("GetWatch(%q) = %v, want nil %v", tt.id, w, tt.wantNil)
+		}
+		if w != nil && len(w.Seen) != tt.wantSeen {
+			t.Errorf("GetWatch(%q) has %d seen release(s), want %d", tt.id, len(w.Seen), tt.wantSeen)
+		}
+	}
+
+	if err := db.RemoveWatch("2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RemoveWatch("missing"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := db.Watches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, w := range list {
+		ids = append(ids, w.ArtistID)
+	}
+	sort.Strings(ids)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("Watches() = %v, want [1]", ids)
+	}
+}
+
+func TestWatchesEmpty(t *testing.T) {
+	db := openTestDB(t)
+	if w, err := db.GetWatch("1"); err != nil || w != nil {
+		t.Errorf("GetWatch on empty db = %v, %v", w, err)
+	}
+	if err := db.RemoveWatch("1"); err != nil {
+		t.Errorf("RemoveWatch on empty db: %v", err)
+	}
+	if list, err := db.Watches(); err != nil || len(list) != 0 {
+		t.Errorf("Watches on empty db = %v, %v", list, err)
+	}
+}
@@ -19,6 +19,15 @@ type ConfigSet struct {
 	PlaylistFolderFormat       string   `yaml:"playlist-folder-format"`
 	ArtistFolderFormat         string   `yaml:"artist-folder-format"`
 	SongFileFormat             string   `yaml:"song-file-format"`
+	DiscFolderFormat           string   `yaml:"disc-folder-format"`
+	MVFileFormat               string   `yaml:"mv-file-format"`
+	FilenameProfile            string   `yaml:"filename-profile"`
+	FilenameNormalization      string   `yaml:"filename-normalization"`
+	MaxPathLength              int      `yaml:"max-path-length"`
+	PlaylistFiles              []string `yaml:"playlist-files"`
+	PlaylistFilesForAlbums     bool     `yaml:"playlist-files-for-albums"`
+	PlaylistLayout             string   `yaml:"playlist-layout"`
+	PlaylistLinks              string   `yaml:"playlist-links"`
 	ExplicitChoice             string   `yaml:"explicit-choice"`
 	CleanChoice                string   `yaml:"clean-choice"`
 	AppleMasterChoice          string   `yaml:"apple-master-choice"`
@@ -47,6 +56,9 @@ type ConfigSet struct {
     DownloadTimeoutSec         int      `yaml:"download-timeout-sec"`
     MVSegmentConcurrency       int      `yaml:"mv-segment-concurrency"`
     TaggingConcurrency         int      `yaml:"tagging-concurrency"`
+    LibrarySkipExisting        bool     `yaml:"library-skip-existing"`
+    LibraryMatchCodec          bool     `yaml:"library-match-codec"`
+    LibraryMatchISRC           bool     `yaml:"library-match-isrc"`
 }
 
 type Counter struct {
@@ -93,6 +105,9 @@ type AutoGeneratedArtist struct {
 			AudioTraits          []string `json:"audioTraits"`
 			HasLyrics            bool     `json:"hasLyrics"`
 			AlbumName            string   `json:"albumName"`
+			IsSingle             bool     `json:"isSingle"`
+			IsCompilation        bool     `json:"isCompilation"`
+			TrackCount           int      `json:"trackCount"`
 			PlayParams           struct {
 				ID   string `json:"id"`
 				Kind string `json:"kind"`
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"bufio"
 	"errors"
 	"fmt"
@@ -39,10 +40,10 @@ func NewAlbum(st string, id string) *Album {
 
 }
 
-func (a *Album) GetResp(token, l string) error {
+func (a *Album) GetResp(ctx context.Context, token, l string) error {
 	var err error
 	a.Language = l
-	resp, err := ampapi.GetAlbumResp(a.Storefront, a.ID, a.Language, token)
+	resp, err := ampapi.GetAlbumResp(ctx, a.Storefront, a.ID, a.Language, token)
 	if err != nil {
 		return errors.New("error getting album response")
 	}
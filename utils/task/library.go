@@ -0,0 +1,96 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"main/utils/ampapi"
+)
+
+// LibraryPlaylist 资料库中的歌单（p. 开头的 ID），曲目映射为目录中的歌曲/MV 后按普通歌单下载
+type LibraryPlaylist struct {
+	Playlist
+	// 没有目录 ID 的曲目（上传的音乐等），无法下载；它们在歌单中的位置保留，其余曲目的序号不变
+	Missing []string
+}
+
+func NewLibraryPlaylist(st string, id string) *LibraryPlaylist {
+	a := new(LibraryPlaylist)
+	a.Storefront = st
+	a.ID = id
+	return a
+}
+
+func (a *LibraryPlaylist) GetResp(ctx context.Context, mutoken, token, l string) error {
+	a.Language = l
+	item, err := ampapi.GetLibraryItem(ctx, "playlists", a.ID, mutoken, a.Language, token)
+	if err != nil {
+		return fmt.Errorf("error getting library playlist response: %w", err)
+	}
+	items, err := ampapi.GetLibraryPlaylistTracks(ctx, a.ID, mutoken, a.Language, token)
+	if err != nil {
+		return fmt.Errorf("error getting library playlist tracks: %w", err)
+	}
+
+	// 按类型批量获取目录中的曲目，再按歌单顺序排列
+	ids := map[string][]string{}
+	for i := range items {
+		kind := "songs"
+		if items[i].Type == "library-music-videos" {
+			kind = "music-videos"
+		}
+		if id := items[i].CatalogID(); id != "" {
+			ids[kind] = append(ids[kind], id)
+		}
+	}
+	catalog := map[string]ampapi.TrackRespData{}
+	for kind, list := range ids {
+		tracks, err := ampapi.GetCatalogTracks(ctx, a.Storefront, kind, list, a.Language, token)
+		if err != nil {
+			return fmt.Errorf("error getting catalog %s: %w", kind, err)
+		}
+		for _, t := range tracks {
+			catalog[t.ID] = t
+		}
+	}
+	return a.setResp(item, items, catalog)
+}
+
+// setResp 由资料库歌单、其曲目与按目录 ID 索引的目录曲目生成歌单响应与曲目列表
+func (a *LibraryPlaylist) setResp(item *ampapi.LibraryRespData, items []ampapi.LibraryRespData, catalog map[string]ampapi.TrackRespData) error {
+	var data ampapi.PlaylistRespData
+	data.ID = a.ID
+	data.Type = "playlists"
+	data.Attributes.Name = item.Attributes.Name
+	data.Attributes.Artwork.URL = item.Attributes.Artwork.URL
+	data.Attributes.Artwork.Width = item.Attributes.Artwork.Width
+	data.Attributes.Artwork.Height = item.Attributes.Artwork.Height
+	data.Attributes.PlayParams.ID = a.ID
+	data.Attributes.PlayParams.Kind = "playlist"
+	var positions []int
+	for i := range items {
+		t, ok := catalog[items[i].CatalogID()]
+		if !ok {
+			a.Missing = append(a.Missing, fmt.Sprintf("%d. %s - %s", i+1, items[i].Attributes.ArtistName, items[i].Attributes.Name))
+			continue
+		}
+		data.Relationships.Tracks.Data = append(data.Relationships.Tracks.Data, t)
+		positions = append(positions, i+1)
+	}
+	if len(data.Relationships.Tracks.Data) == 0 {
+		return errors.New("library playlist has no tracks available in the catalog")
+	}
+	// 自建歌单可能没有封面，使用第一首曲目的封面
+	if data.Attributes.Artwork.URL == "" {
+		data.Attributes.Artwork.URL = data.Relationships.Tracks.Data[0].Attributes.Artwork.URL
+	}
+	a.Resp = ampapi.PlaylistResp{Data: []ampapi.PlaylistRespData{data}}
+	a.setTracks()
+	// 序号按资料库歌单中的原始位置，跳过的曲目不会使后面的曲目前移
+	for k := range a.Tracks {
+		a.Tracks[k].TaskNum = positions[k]
+		a.Tracks[k].TaskTotal = len(items)
+	}
+	return nil
+}
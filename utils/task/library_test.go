@@ -0,0 +1,105 @@
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	"main/utils/ampapi"
+)
+
+func libraryItem(name string, catalogID string, mv bool) ampapi.LibraryRespData {
+	var it ampapi.LibraryRespData
+	it.Type = "library-songs"
+	if mv {
+		it.Type = "library-music-videos"
+	}
+	it.Attributes.Name = name
+	it.Attributes.ArtistName = "Artist"
+	it.Attributes.PlayParams.CatalogID = catalogID
+	return it
+}
+
+func catalogTrack(id string, kind string) ampapi.TrackRespData {
+	var t ampapi.TrackRespData
+	t.ID, t.Type = id, kind
+	t.Attributes.Name = "Song " + id
+	t.Attributes.Artwork.URL = "https://example.com/" + id + ".jpg"
+	return t
+}
+
+func TestLibraryPlaylistSetResp(t *testing.T) {
+	catalog := map[string]ampapi.TrackRespData{
+		"10": catalogTrack("10", "songs"),
+		"20": catalogTrack("20", "songs"),
+		"30": catalogTrack("30", "music-videos"),
+	}
+	tests := []struct {
+		name        string
+		items       []ampapi.LibraryRespData
+		artwork     string
+		wantIDs     string
+		wantNums    string
+		wantMissing int
+		wantArtwork string
+		wantErr     bool
+	}{
+		{
+			name:        "all in catalog",
+			items:       []ampapi.LibraryRespData{libraryItem("a", "10", false), libraryItem("b", "20", false)},
+			artwork:     "https://example.com/cover.jpg",
+			wantIDs:     "[10 20]",
+			wantNums:    "[1/2 2/2]",
+			wantArtwork: "https://example.com/cover.jpg",
+		},
+		{
+			// 上传的曲目没有目录 ID，其余曲目保留原来的序号
+			name: "missing keeps positions",
+			items: []ampapi.LibraryRespData{
+				libraryItem("uploaded", "", false), libraryItem("a", "10", false),
+				libraryItem("gone", "99", false), libraryItem("mv", "30", true),
+			},
+			wantIDs:     "[10 30]",
+			wantNums:    "[2/4 4/4]",
+			wantMissing: 2,
+			wantArtwork: "https://example.com/10.jpg",
+		},
+		{
+			name:    "nothing in catalog",
+			items:   []ampapi.LibraryRespData{libraryItem("uploaded", "", false)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewLibraryPlaylist("us", "p.test")
+			var item ampapi.LibraryRespData
+			item.Attributes.Name = "Mix"
+			item.Attributes.Artwork.URL = tt.artwork
+			err := a.setResp(&item, tt.items, catalog)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setResp error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var ids, nums []string
+			for _, tr := range a.Tracks {
+				ids = append(ids, tr.ID)
+				nums = append(nums, fmt.Sprintf("%d/%d", tr.TaskNum, tr.TaskTotal))
+				if tr.PreID != "p.test" || tr.PreType != "playlists" {
+					t.Errorf("track %s parent = %s %s", tr.ID, tr.PreType, tr.PreID)
+				}
+			}
+			if fmt.Sprint(ids) != tt.wantIDs || fmt.Sprint(nums) != tt.wantNums {
+				t.Errorf("tracks = %v %v, want %s %s", ids, nums, tt.wantIDs, tt.wantNums)
+			}
+			if len(a.Missing) != tt.wantMissing {
+				t.Errorf("Missing = %v, want %d item(s)", a.Missing, tt.wantMissing)
+			}
+			data := a.Resp.Data[0]
+			if a.Name != "Mix" || data.ID != "p.test" || data.Attributes.Artwork.URL != tt.wantArtwork {
+				t.Errorf("playlist = %q %q %q", a.Name, data.ID, data.Attributes.Artwork.URL)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"bufio"
 	"errors"
 	"fmt"
@@ -39,15 +40,20 @@ func NewPlaylist(st string, id string) *Playlist {
 
 }
 
-func (a *Playlist) GetResp(token, l string) error {
+func (a *Playlist) GetResp(ctx context.Context, token, l string) error {
 	var err error
 	a.Language = l
-	resp, err := ampapi.GetPlaylistResp(a.Storefront, a.ID, a.Language, token)
+	resp, err := ampapi.GetPlaylistResp(ctx, a.Storefront, a.ID, a.Language, token)
 	if err != nil {
 		return errors.New("error getting album response")
 	}
 	a.Resp = *resp
+	a.setTracks()
+	return nil
+}
 
+// setTracks 由 Resp 生成 Tracks
+func (a *Playlist) setTracks() {
 	a.Resp.Data[0].Attributes.ArtistName = "Apple Music"
 	//简化高频调用名称
 	a.Name = a.Resp.Data[0].Attributes.Name
@@ -77,7 +83,6 @@ func (a *Playlist) GetResp(token, l string) error {
 			PlaylistData: a.Resp.Data[0],
 		})
 	}
-	return nil
 }
 
 func (a *Playlist) GetArtwork() string {
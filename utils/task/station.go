@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	//"bufio"
 	"errors"
 	"fmt"
@@ -40,10 +41,10 @@ func NewStation(st string, id string) *Station {
 
 }
 
-func (a *Station) GetResp(mutoken, token, l string) error {
+func (a *Station) GetResp(ctx context.Context, mutoken, token, l string) error {
 	var err error
 	a.Language = l
-	resp, err := ampapi.GetStationResp(a.Storefront, a.ID, a.Language, token)
+	resp, err := ampapi.GetStationResp(ctx, a.Storefront, a.ID, a.Language, token)
 	if err != nil {
 		return errors.New("error getting station response")
 	}
@@ -54,14 +55,14 @@ func (a *Station) GetResp(mutoken, token, l string) error {
 	if a.Type != "tracks" {
 		return nil
 	}
-	tracksResp, err := ampapi.GetStationNextTracks(a.ID, mutoken, a.Language, token)
+	tracksResp, err := ampapi.GetStationNextTracks(ctx, a.ID, mutoken, a.Language, token)
 	if err != nil {
 		return errors.New("error getting station tracks response")
 	}
 	//fmt.Println("Getting album response")
 	//从resp中的Tracks数据中提取trackData信息到新的Track结构体中
 	for i, trackData := range tracksResp.Data {
-		albumResp, err := ampapi.GetAlbumRespByHref(trackData.Href, a.Language, token)
+		albumResp, err := ampapi.GetAlbumRespByHref(ctx, trackData.Href, a.Language, token)
 		if err != nil {
 			fmt.Println("Error getting album response:", err)
 			continue
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"main/utils/ampapi"
 )
 
@@ -31,9 +32,9 @@ type Track struct {
 	PlaylistData ampapi.PlaylistRespData
 }
 
-func (t *Track) GetAlbumData(token string) error {
+func (t *Track) GetAlbumData(ctx context.Context, token string) error {
 	var err error
-	resp, err := ampapi.GetAlbumRespByHref(t.Resp.Href, t.Language, token)
+	resp, err := ampapi.GetAlbumRespByHref(ctx, t.Resp.Href, t.Language, token)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,187 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/itouakirai/mp4ff/mp4"
+)
+
+// 时长允许的误差：取 2 秒与期望时长 1% 中的较大者
+const (
+	durationTolerance    = 2 * time.Second
+	durationTolerancePct = 1
+)
+
+// Expect 期望值，零值表示不检查
+type Expect struct {
+	Duration  time.Duration // 元数据中的时长（durationInMillis）
+	Fragments int           // 媒体播放列表中的分段数，每个分段对应一个 moof+mdat 分片
+}
+
+// Result 从文件中解析出的音轨信息
+type Result struct {
+	Codec      string // sample entry 类型：alac / mp4a / ec-3 / ac-3
+	Fragmented bool
+	Fragments  int
+	Samples    int
+	Duration   time.Duration
+}
+
+// File 解析 MP4 并检查：文件未截断、sample entry 完整且已解密、每个分片的数据都已写入、分片数/时长与期望一致
+func File(path string, want Expect) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse failed: %w", err)
+	}
+	// 惰性解析 mdat 时不会读到文件末尾，需按 box 大小判断是否截断
+	if size := parsed.Size(); size > uint64(st.Size()) {
+		return nil, fmt.Errorf("file is truncated (%d of %d bytes)", st.Size(), size)
+	}
+	if parsed.Moov == nil || parsed.Moov.Trak == nil || parsed.Moov.Trak.Mdia == nil ||
+		parsed.Moov.Trak.Mdia.Mdhd == nil || parsed.Moov.Trak.Mdia.Minf == nil ||
+		parsed.Moov.Trak.Mdia.Minf.Stbl == nil {
+		return nil, errors.New("no audio track")
+	}
+	trak := parsed.Moov.Trak
+	timescale := trak.Mdia.Mdhd.Timescale
+	if timescale == 0 {
+		return nil, errors.New("mdhd timescale is 0")
+	}
+	codec, err := checkSampleEntry(trak.Mdia.Minf.Stbl.Stsd)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Codec: codec, Fragmented: parsed.IsFragmented()}
+	var units uint64
+	if res.Fragmented {
+		var trex *mp4.TrexBox
+		if parsed.Moov.Mvex != nil && trak.Tkhd != nil {
+			trex, _ = parsed.Moov.Mvex.GetTrex(trak.Tkhd.TrackID)
+		}
+		for _, seg := range parsed.Segments {
+			for _, frag := range seg.Fragments {
+				res.Fragments++
+				if frag.Moof == nil || frag.Mdat == nil {
+					return nil, fmt.Errorf("fragment %d has no media data", res.Fragments)
+				}
+				var dataSize uint64
+				for _, traf := range frag.Moof.Trafs {
+					for _, trun := range traf.Truns {
+						units += trun.AddSampleDefaultValues(traf.Tfhd, trex)
+						dataSize += trun.SizeOfData()
+						res.Samples += int(trun.SampleCount())
+					}
+				}
+				if got := mdatSize(frag.Mdat); got < dataSize {
+					return nil, fmt.Errorf("fragment %d is incomplete (%d of %d bytes)", res.Fragments, got, dataSize)
+				}
+			}
+		}
+	} else {
+		stbl := trak.Mdia.Minf.Stbl
+		if stbl.Stsz == nil || stbl.Stts == nil {
+			return nil, errors.New("missing sample table")
+		}
+		res.Samples = int(stbl.Stsz.SampleNumber)
+		var dataSize uint64
+		if stbl.Stsz.SampleUniformSize > 0 {
+			dataSize = uint64(stbl.Stsz.SampleUniformSize) * uint64(stbl.Stsz.SampleNumber)
+		} else {
+			for _, s := range stbl.Stsz.SampleSize {
+				dataSize += uint64(s)
+			}
+		}
+		for i, n := range stbl.Stts.SampleCount {
+			if i < len(stbl.Stts.SampleTimeDelta) {
+				units += uint64(n) * uint64(stbl.Stts.SampleTimeDelta[i])
+			}
+		}
+		if parsed.Mdat == nil || mdatSize(parsed.Mdat) < dataSize {
+			return nil, fmt.Errorf("media data is incomplete (%d bytes expected)", dataSize)
+		}
+	}
+	if res.Samples == 0 {
+		return nil, errors.New("no samples")
+	}
+	if want.Fragments > 0 && res.Fragments != want.Fragments {
+		return res, fmt.Errorf("got %d fragments, playlist has %d", res.Fragments, want.Fragments)
+	}
+	res.Duration = time.Duration(units * uint64(time.Second) / uint64(timescale))
+
+	if want.Duration > 0 {
+		tolerance := want.Duration * durationTolerancePct / 100
+		if tolerance < durationTolerance {
+			tolerance = durationTolerance
+		}
+		diff := res.Duration - want.Duration
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > tolerance {
+			return res, fmt.Errorf("duration %s does not match expected %s", res.Duration.Round(time.Millisecond), want.Duration.Round(time.Millisecond))
+		}
+	}
+	return res, nil
+}
+
+// checkSampleEntry 检查 stsd：必须是已解密的音频 sample entry，且带有解码配置
+func checkSampleEntry(stsd *mp4.StsdBox) (string, error) {
+	if stsd == nil || len(stsd.Children) == 0 {
+		return "", errors.New("no sample description")
+	}
+	entry := stsd.Children[0]
+	switch t := entry.Type(); t {
+	case "alac":
+		// mp4ff 不解析 alac，按大小判断：音频 sample entry（36 字节）+ alac 配置（36 字节）
+		if entry.Size() < 72 {
+			return "", errors.New("alac sample entry has no decoder config")
+		}
+		return t, nil
+	case "mp4a", "ec-3", "ac-3":
+		ase, ok := entry.(*mp4.AudioSampleEntryBox)
+		if !ok {
+			return "", fmt.Errorf("unexpected %s sample entry", t)
+		}
+		if ase.ChannelCount == 0 {
+			return "", fmt.Errorf("%s sample entry has no channels", t)
+		}
+		if (t == "mp4a" && ase.Esds == nil) || (t == "ec-3" && ase.Dec3 == nil) || (t == "ac-3" && ase.Dac3 == nil) {
+			return "", fmt.Errorf("%s sample entry has no decoder config", t)
+		}
+		return t, nil
+	case "enca":
+		return "", errors.New("audio is still encrypted")
+	default:
+		return "", fmt.Errorf("unexpected sample entry %q", t)
+	}
+}
+
+// decode 惰性解析 mdat；mp4ff 遇到残缺的 moov 可能 panic，转为错误返回
+func decode(f *os.File) (parsed *mp4.File, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			parsed, err = nil, fmt.Errorf("malformed file: %v", r)
+		}
+	}()
+	return mp4.DecodeFile(f, mp4.WithDecodeMode(mp4.DecModeLazyMdat))
+}
+
+func mdatSize(m *mp4.MdatBox) uint64 {
+	if m.IsLazy() {
+		return m.GetLazyDataSize()
+	}
+	return m.DataLength()
+}
@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itouakirai/mp4ff/aac"
+	"github.com/itouakirai/mp4ff/mp4"
+)
+
+const (
+	testTimescale      = 44100
+	testSampleDur      = 1024
+	testSamplesPerFrag = 43
+)
+
+// fragmentedAAC 生成 fragments 个分片、每个分片 testSamplesPerFrag 个样本的 fMP4 音频
+func fragmentedAAC(t *testing.T, fragments int) []byte {
+	t.Helper()
+	init := mp4.CreateEmptyInit()
+	init.AddEmptyTrack(testTimescale, "audio", "en")
+	if err := init.Moov.Trak.SetAACDescriptor(aac.AAClc, testTimescale); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decodeTime uint64
+	for i := 0; i < fragments; i++ {
+		frag, err := mp4.CreateFragment(uint32(i+1), init.Moov.Trak.Tkhd.TrackID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < testSamplesPerFrag; j++ {
+			data := []byte{0x21, 0x10, 0x04, byte(j)}
+			frag.AddFullSample(mp4.FullSample{
+				Sample:     mp4.NewSample(mp4.SyncSampleFlags, testSampleDur, uint32(len(data)), 0),
+				DecodeTime: decodeTime,
+				Data:       data,
+			})
+			decodeTime += testSampleDur
+		}
+		if err := frag.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.m4a")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFile(t *testing.T) {
+	const fragments = 5
+	valid := fragmentedAAC(t, fragments)
+	duration := time.Duration(fragments*testSamplesPerFrag*testSampleDur) * time.Second / testTimescale
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Expect
+		wantErr string
+	}{
+		{"valid", valid, Expect{}, ""},
+		{"fragment count matches", valid, Expect{Fragments: fragments}, ""},
+		{"duration within tolerance", valid, Expect{Duration: duration + time.Second}, ""},
+		{"both match", valid, Expect{Duration: duration, Fragments: fragments}, ""},
+		{"missing fragments", valid, Expect{Fragments: fragments + 1}, "got 5 fragments, playlist has 6"},
+		{"duration mismatch", valid, Expect{Duration: duration + 10*time.Second}, "does not match expected"},
+		{"truncated", valid[:len(valid)-3], Expect{}, "file is truncated"},
+		{"encrypted", bytes.Replace(valid, []byte("mp4a"), []byte("enca"), 1), Expect{}, "still encrypted"},
+		{"not mp4", []byte("definitely not an mp4 file"), Expect{}, "parse failed"},
+		{"no samples", fragmentedAAC(t, 0), Expect{}, "no samples"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := File(writeTemp(t, tt.data), tt.want)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("File error = %v, want error containing %q", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("File error = %q, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if res.Codec != "mp4a" || !res.Fragmented || res.Fragments != fragments || res.Samples != fragments*testSamplesPerFrag {
+				t.Errorf("File result = %+v", res)
+			}
+			if res.Duration != duration {
+				t.Errorf("Duration = %s, want %s", res.Duration, duration)
+			}
+		})
+	}
+}
+
+func TestFileMissing(t *testing.T) {
+	if _, err := File(filepath.Join(t.TempDir(), "missing.m4a"), Expect{}); err == nil {
+		t.Error("File on a missing path succeeded, want error")
+	}
+}
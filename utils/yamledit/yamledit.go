@@ -0,0 +1,227 @@
+package yamledit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set 将顶层键 key 的值改为 value，只改写该键所在的行，其余内容（注释、顺序、空行）保持不变；
+// 键不存在时追加到文件末尾。value 支持 string、bool、整数、浮点数与 []string
+func Set(data []byte, key string, value interface{}) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var root *yaml.Node
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, errors.New("top level is not a mapping")
+		}
+	}
+
+	eol := "\n"
+	if strings.Contains(string(data), "\r\n") {
+		eol = "\r\n"
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	// 末尾换行产生的空元素在拼接时恢复
+	trailing := len(lines) > 0 && lines[len(lines)-1] == ""
+	if trailing {
+		lines = lines[:len(lines)-1]
+	}
+
+	var keyNode, valNode *yaml.Node
+	if root != nil {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == key {
+				keyNode, valNode = root.Content[i], root.Content[i+1]
+			}
+		}
+	}
+
+	if keyNode == nil {
+		block, err := render(key, value, nil, 0, 2, "")
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, block...)
+		return join(lines, eol, true), nil
+	}
+
+	start := keyNode.Line - 1
+	end := lastLine(valNode) - 1
+	if isBlockScalar(valNode) {
+		end = blockScalarEnd(lines, start, keyNode.Column-1)
+	}
+	if start < 0 || end >= len(lines) || end < start {
+		return nil, fmt.Errorf("cannot locate %s in file", key)
+	}
+
+	_, isList := value.([]string)
+	oldInline := valNode.Line == keyNode.Line && !isBlockScalar(valNode) && end == start &&
+		!(valNode.Tag == "!!null" && valNode.Value == "")
+	if !isList && oldInline {
+		// 单行替换：保留值前的内容与行尾注释（尽量保持注释列不变）
+		scalar, err := renderScalar(value, valNode.Style)
+		if err != nil {
+			return nil, err
+		}
+		lines[start] = spliceValue(lines[start], valNode, scalar)
+		return join(lines, eol, trailing), nil
+	}
+
+	itemIndent := keyNode.Column - 1 + 2
+	if valNode.Kind == yaml.SequenceNode && valNode.Style&yaml.FlowStyle == 0 && len(valNode.Content) > 0 {
+		// 沿用原列表的缩进（"- " 位于元素前两列）
+		if c := valNode.Content[0].Column - 3; c >= 0 {
+			itemIndent = c
+		}
+	}
+	block, err := render(key, value, valNode, keyNode.Column-1, itemIndent, lineComment(lines[start], keyNode, valNode))
+	if err != nil {
+		return nil, err
+	}
+	out := append([]string{}, lines[:start]...)
+	out = append(out, block...)
+	out = append(out, lines[end+1:]...)
+	return join(out, eol, trailing), nil
+}
+
+func join(lines []string, eol string, trailing bool) []byte {
+	s := strings.Join(lines, eol)
+	if trailing {
+		s += eol
+	}
+	return []byte(s)
+}
+
+// render 生成 "key: value" 或块列表的若干行
+func render(key string, value interface{}, old *yaml.Node, indent int, itemIndent int, comment string) ([]string, error) {
+	pad := strings.Repeat(" ", indent)
+	suffix := ""
+	if comment != "" {
+		suffix = " " + comment
+	}
+	if list, ok := value.([]string); ok {
+		if len(list) == 0 {
+			return []string{pad + key + ": []" + suffix}, nil
+		}
+		out := []string{pad + key + ":" + suffix}
+		var style yaml.Style
+		if old != nil && old.Kind == yaml.SequenceNode && len(old.Content) > 0 {
+			style = old.Content[0].Style
+		}
+		for _, item := range list {
+			s, err := renderScalar(item, style)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, strings.Repeat(" ", itemIndent)+"- "+s)
+		}
+		return out, nil
+	}
+	var style yaml.Style
+	if old != nil && old.Kind == yaml.ScalarNode {
+		style = old.Style
+	}
+	s, err := renderScalar(value, style)
+	if err != nil {
+		return nil, err
+	}
+	return []string{pad + key + ": " + s + suffix}, nil
+}
+
+// renderScalar 按 yaml 规则输出标量，字符串沿用原来的引号风格（不允许时由编码器自动加引号）
+func renderScalar(value interface{}, style yaml.Style) (string, error) {
+	n := &yaml.Node{Kind: yaml.ScalarNode}
+	switch v := value.(type) {
+	case string:
+		n.Tag, n.Value = "!!str", v
+		n.Style = style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle)
+	case bool:
+		n.Tag, n.Value = "!!bool", strconv.FormatBool(v)
+	case int:
+		n.Tag, n.Value = "!!int", strconv.Itoa(v)
+	case int64:
+		n.Tag, n.Value = "!!int", strconv.FormatInt(v, 10)
+	case float64:
+		n.Tag, n.Value = "!!float", strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// spliceValue 替换行内的值，保留行尾注释
+func spliceValue(line string, val *yaml.Node, scalar string) string {
+	runes := []rune(line)
+	start := val.Column - 1
+	if start > len(runes) {
+		start = len(runes)
+	}
+	rest := string(runes[start:])
+	commentAt := -1
+	if val.LineComment != "" {
+		commentAt = strings.LastIndex(rest, val.LineComment)
+	}
+	if commentAt < 0 {
+		return string(runes[:start]) + scalar
+	}
+	// 按新旧值的长度差调整空格，尽量保持注释对齐
+	gap := len([]rune(rest[:commentAt])) - len([]rune(scalar))
+	if gap < 1 {
+		gap = 1
+	}
+	return string(runes[:start]) + scalar + strings.Repeat(" ", gap) + rest[commentAt:]
+}
+
+// lineComment 返回键所在行的行尾注释
+func lineComment(line string, key, val *yaml.Node) string {
+	for _, c := range []string{key.LineComment, val.LineComment} {
+		if c != "" && strings.Contains(line, c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// lastLine 返回节点（含子节点）所在的最后一行
+func lastLine(n *yaml.Node) int {
+	line := n.Line
+	for _, c := range n.Content {
+		if l := lastLine(c); l > line {
+			line = l
+		}
+	}
+	return line
+}
+
+func isBlockScalar(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0
+}
+
+// blockScalarEnd 块标量（| 或 >）延续到下一个缩进不大于键的非空行之前
+func blockScalarEnd(lines []string, start int, indent int) int {
+	end := start
+	for i := start + 1; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if t == "" {
+			continue
+		}
+		if len(lines[i])-len(strings.TrimLeft(lines[i], " ")) <= indent {
+			break
+		}
+		end = i
+	}
+	return end
+}
@@ -0,0 +1,149 @@
+package yamledit
+
+import "testing"
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		key   string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "string keeps comments and order",
+			in:    "# header\nstorefront: us # two letters\n\nlanguage: en\n",
+			key:   "storefront",
+			value: "jp",
+			want:  "# header\nstorefront: jp # two letters\n\nlanguage: en\n",
+		},
+		{
+			name:  "keeps double quotes",
+			in:    "decrypt-m3u8-port: \"127.0.0.1:10020\"\n",
+			key:   "decrypt-m3u8-port",
+			value: "127.0.0.1:20020",
+			want:  "decrypt-m3u8-port: \"127.0.0.1:20020\"\n",
+		},
+		{
+			name:  "quotes when needed",
+			in:    "song-file-format: x\n",
+			key:   "song-file-format",
+			value: "{SongName}: live",
+			want:  "song-file-format: '{SongName}: live'\n",
+		},
+		{
+			name:  "bool",
+			in:    "embed-lrc: true\n",
+			key:   "embed-lrc",
+			value: false,
+			want:  "embed-lrc: false\n",
+		},
+		{
+			name:  "int keeps comment column",
+			in:    "alac-max: 192000  # Hz\n",
+			key:   "alac-max",
+			value: 48000,
+			want:  "alac-max: 48000   # Hz\n",
+		},
+		{
+			name:  "empty value",
+			in:    "media-user-token:\nlanguage: en\n",
+			key:   "media-user-token",
+			value: "abc",
+			want:  "media-user-token: abc\nlanguage: en\n",
+		},
+		{
+			name:  "block list",
+			in:    "codec-priority:\n  - alac\n  - aac # fallback\nlanguage: en\n",
+			key:   "codec-priority",
+			value: []string{"ec-3", "alac"},
+			want:  "codec-priority:\n  - ec-3\n  - alac\nlanguage: en\n",
+		},
+		{
+			name:  "flow list",
+			in:    "playlist-files: [m3u8]\nlanguage: en\n",
+			key:   "playlist-files",
+			value: []string{"m3u8", "xspf"},
+			want:  "playlist-files:\n  - m3u8\n  - xspf\nlanguage: en\n",
+		},
+		{
+			name:  "empty list",
+			in:    "playlist-files:\n  - m3u8\n",
+			key:   "playlist-files",
+			value: []string{},
+			want:  "playlist-files: []\n",
+		},
+		{
+			name:  "block scalar",
+			in:    "song-file-format: |\n  {SongName}\n  more\nlanguage: en\n",
+			key:   "song-file-format",
+			value: "{SongId}",
+			want:  "song-file-format: '{SongId}'\nlanguage: en\n",
+		},
+		{
+			name:  "missing key is appended",
+			in:    "language: en\n",
+			key:   "storefront",
+			value: "jp",
+			want:  "language: en\nstorefront: jp\n",
+		},
+		{
+			name:  "empty document",
+			in:    "",
+			key:   "storefront",
+			value: "jp",
+			want:  "storefront: jp\n",
+		},
+		{
+			name:  "crlf preserved",
+			in:    "# c\r\nstorefront: us # sf\r\nlanguage: en\r\n",
+			key:   "storefront",
+			value: "jp",
+			want:  "# c\r\nstorefront: jp # sf\r\nlanguage: en\r\n",
+		},
+		{
+			name:  "crlf list",
+			in:    "codec-priority:\r\n  - alac\r\nlanguage: en\r\n",
+			key:   "codec-priority",
+			value: []string{"aac"},
+			want:  "codec-priority:\r\n  - aac\r\nlanguage: en\r\n",
+		},
+		{
+			name:  "no trailing newline",
+			in:    "storefront: us",
+			key:   "storefront",
+			value: "jp",
+			want:  "storefront: jp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Set([]byte(tt.in), tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("Set error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Set(%q, %v)\n got: %q\nwant: %q", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		value interface{}
+	}{
+		{"not a mapping", "- a\n- b\n", "x"},
+		{"invalid yaml", "a: [\n", "x"},
+		{"unsupported type", "a: 1\n", struct{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Set([]byte(tt.in), "a", tt.value); err == nil {
+				t.Error("Set succeeded, want error")
+			}
+		})
+	}
+}